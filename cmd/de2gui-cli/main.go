@@ -0,0 +1,155 @@
+// Command de2gui-cli drives a simulated board without a display, reading
+// commands in the script format, see the script package, and printing the
+// state of the board as text after each one. It is meant for grading, and
+// for machines which can only be reached over SSH.
+//
+// Usage:
+//
+//	de2gui-cli [flags] [script...]
+//
+// Commands are read from each script in turn, or from standard input if no
+// scripts are given, in which case a prompt is shown if it is a terminal.
+// The design is either a simulator started with -run, one which connects to
+// the address given by -listen, using the protocol described in the
+// simproto package, or, by default, a demo which shows the tick number on
+// the red LEDs. For example:
+//
+//	go build ./cmd/de2gui-sim
+//	echo "sw 1
+//	tick 20
+//	expect hex 00000014" | de2gui-cli -run ./de2gui-sim
+//
+// The exit code is 0 if every command succeeded and every check passed, 1
+// if not, and 2 if the design could not be started.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/herclab/de2gui/cmd/internal/design"
+	"github.com/herclab/de2gui/de2gui"
+	"github.com/herclab/de2gui/de2gui/script"
+)
+
+// isTerminal returns true if f is a terminal
+func isTerminal(f *os.File) bool {
+	fi, err := f.Stat()
+	return err == nil && fi.Mode()&os.ModeCharDevice != 0
+}
+
+// writeReport writes the report to path using the given function, unless
+// path is empty
+func writeReport(path string, write func(f *os.File) error) error {
+	if path == "" {
+		return nil
+	}
+
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+
+	if err := write(f); err != nil {
+		f.Close()
+		return err
+	}
+
+	return f.Close()
+}
+
+func usage() {
+	fmt.Fprintf(os.Stderr, "usage: de2gui-cli [flags] [script...]\n\nflags:\n")
+	flag.PrintDefaults()
+}
+
+func main() {
+	boardName := flag.String("board", "DE2-115", "the board to simulate")
+	run := flag.String("run", "", "start this simulator, rather than using the demo design")
+	listen := flag.String("listen", "", "wait for a simulator to connect to this address, rather than using the demo design")
+	keepGoing := flag.Bool("keep-going", false, "carry on after a command fails")
+	quiet := flag.Bool("q", false, "only print the outcome of each command, and not the board")
+	jsonPath := flag.String("json", "", "write a report of the checks to this file as JSON")
+	junitPath := flag.String("junit", "", "write a report of the checks to this file as JUnit XML")
+	flag.Usage = usage
+	flag.Parse()
+
+	board, err := de2gui.LookupProfile(*boardName)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		os.Exit(2)
+	}
+
+	s := de2gui.NewHeadlessUIStateFor(board)
+
+	disconnect, err := design.Connect(s, design.Options{Run: *run, Listen: *listen})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		os.Exit(2)
+	}
+
+	opts := de2gui.CommandOptions{KeepGoing: *keepGoing, Quiet: *quiet}
+	report := &script.Report{Name: "stdin", Results: []script.Result{}}
+
+	if flag.NArg() == 0 {
+		if isTerminal(os.Stdin) {
+			opts.Prompt = "> "
+			opts.KeepGoing = true
+		}
+
+		if !opts.Quiet {
+			s.WriteState(os.Stdout)
+			fmt.Println()
+		}
+
+		opts.Name = report.Name
+		report = s.RunCommands(os.Stdin, os.Stdout, opts)
+	}
+
+	if flag.NArg() > 0 {
+		report.Name = strings.Join(flag.Args(), ", ")
+	}
+
+	for _, path := range flag.Args() {
+		f, err := os.Open(path)
+		if err != nil {
+			report.Error = err.Error()
+			break
+		}
+
+		opts.Name = path
+		r := s.RunCommands(f, os.Stdout, opts)
+		f.Close()
+
+		report.Results = append(report.Results, r.Results...)
+		if r.Error != "" && report.Error == "" {
+			report.Error = fmt.Sprintf("%s: %s", path, r.Error)
+		}
+		if r.Error != "" && !opts.KeepGoing {
+			break
+		}
+	}
+
+	disconnect()
+
+	fmt.Printf("%d passed, %d failed\n", report.Passed(), report.Failed())
+	if report.Error != "" {
+		fmt.Printf("ERROR %s\n", report.Error)
+	}
+
+	if err := writeReport(*jsonPath, func(f *os.File) error { return report.WriteJSON(f) }); err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		os.Exit(2)
+	}
+
+	if err := writeReport(*junitPath, func(f *os.File) error { return report.WriteJUnit(f) }); err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		os.Exit(2)
+	}
+
+	if !report.OK() {
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,238 @@
+// Command de2gui-gen generates a de2gui harness for a Verilog design, which
+// is simulated using Verilator.
+//
+// Usage:
+//
+//	de2gui-gen [-top module] [-o dir] [-board name] top.v
+//
+// The ports of the top level module are bound to the board by name, see the
+// verilog package, and any ports of the wrong width or direction, or which
+// are not recognized, are reported. Unrecognized inputs are left at 0.
+//
+// The harness is written to the directory given by -o, which is created if
+// needed, and consists of:
+//
+//	shim.h, shim.cpp  a C interface to the Verilated model
+//	model.go          Go bindings for the C interface
+//	main.go           a program which shows the board, and connects it to
+//	                  the model, or runs commands without a window with
+//	                  -cli, or shows the board in the terminal with -tui
+//	Makefile          runs Verilator, and builds the program
+//
+// Existing files with these names are overwritten. In the program, each
+// tick is one cycle of CLOCK_50, and resetting the board creates a new
+// instance of the model. As on the physical board, KEY is active-low, so a
+// KEY reads as 0 while it is pressed, unless -active-high-keys is given.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"text/template"
+
+	"github.com/herclab/de2gui/de2gui"
+	"github.com/herclab/de2gui/de2gui/verilog"
+)
+
+// port is a bound port of the design
+type port struct {
+	// Name is the name of the port in the design.
+	Name string
+
+	// Signal is the name of the board signal it is bound to.
+	Signal string
+
+	// Mask contains the bits of the port.
+	Mask uint32
+
+	// Expr is the Go statement which copies an output to the board, or
+	// the expression giving the value of an input. It is empty for
+	// CLOCK_50, which is driven by ticks.
+	Expr string
+}
+
+// harness is the data used by the templates
+type harness struct {
+	// Top is the name of the top level module.
+	Top string
+
+	// Source is the path of the Verilog file, relative to the harness.
+	Source string
+
+	// Board is the name of the board profile.
+	Board string
+
+	Inputs  []port
+	Outputs []port
+
+	// Clock is true if the design has a CLOCK_50 input.
+	Clock bool
+}
+
+// newHarness describes the harness for the bound ports of a module
+func newHarness(m *verilog.Module, b *verilog.Bindings, activeHighKeys bool) *harness {
+	h := &harness{Top: m.Name}
+
+	for _, v := range b.Bound {
+		p := port{
+			Name:   v.Port.Name,
+			Signal: v.Signal.Name,
+			Mask:   uint32((uint64(1) << uint(v.Port.Width)) - 1),
+		}
+
+		switch {
+		case v.Signal.Name == "CLOCK_50":
+			h.Clock = true
+		case v.Signal.Name == "KEY" && !activeHighKeys:
+			p.Expr = "^s.KEY()"
+		case v.Signal.Dir == verilog.Input:
+			p.Expr = fmt.Sprintf("s.%s()", v.Signal.Name)
+		case v.Signal.Name == "LEDR" || v.Signal.Name == "LEDG":
+			p.Expr = fmt.Sprintf("s.Set%s(m.read%s())", v.Signal.Name, v.Signal.Name)
+		default:
+			p.Expr = fmt.Sprintf("s.SetHEX(%s, uint8(m.read%s()))", v.Signal.Name[3:], v.Signal.Name)
+		}
+
+		if v.Signal.Dir == verilog.Input {
+			h.Inputs = append(h.Inputs, p)
+		} else {
+			h.Outputs = append(h.Outputs, p)
+		}
+	}
+
+	return h
+}
+
+// bind binds the ports of m to the board, printing any problems to w, and
+// returns the ports which can be used in the harness
+func bind(w io.Writer, m *verilog.Module, board de2gui.BoardProfile) *verilog.Bindings {
+	b := verilog.Bind(m, verilog.BoardSignals(board.HEX, board.LEDR, board.LEDG, board.SW, board.KEY))
+
+	for _, p := range b.Problems {
+		fmt.Fprintf(w, "warning: %s\n", p)
+	}
+
+	for _, p := range b.Unrecognized {
+		if p.Dir == verilog.Input {
+			fmt.Fprintf(w, "warning: line %d: %s: not a %s signal, will be 0\n", p.Line, p.Name, board.Name)
+		} else {
+			fmt.Fprintf(w, "warning: line %d: %s: not a %s signal, will not be shown\n", p.Line, p.Name, board.Name)
+		}
+	}
+
+	// ports whose width is unknown cannot be masked, and are dropped
+	bound := []verilog.Binding{}
+	for _, v := range b.Bound {
+		switch {
+		case v.Port.Width == 0:
+			fmt.Fprintf(w, "warning: line %d: %s: not connected, since its width is unknown\n", v.Port.Line, v.Port.Name)
+		case v.Port.Width > 32:
+			fmt.Fprintf(w, "warning: line %d: %s: not connected, since it is wider than 32 bits\n", v.Port.Line, v.Port.Name)
+		default:
+			bound = append(bound, v)
+		}
+	}
+	b.Bound = bound
+
+	return b
+}
+
+// files contains the files of the harness, and their templates
+var files = []struct {
+	name string
+	t    *template.Template
+}{
+	{"shim.h", shimHeader},
+	{"shim.cpp", shimSource},
+	{"model.go", modelSource},
+	{"main.go", mainSource},
+	{"Makefile", makefile},
+}
+
+// writeFile executes the template t into the file at path
+func writeFile(path string, t *template.Template, h *harness) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+
+	if err := t.Execute(f, h); err != nil {
+		f.Close()
+		return err
+	}
+
+	return f.Close()
+}
+
+func usage(w io.Writer) {
+	fmt.Fprintf(w, "usage: de2gui-gen [flags] top.v\n\nflags:\n")
+	flag.CommandLine.SetOutput(w)
+	flag.PrintDefaults()
+}
+
+func main() {
+	top := flag.String("top", "", "the top level module, if the file declares several")
+	out := flag.String("o", "harness", "the directory to write the harness to")
+	boardName := flag.String("board", "DE2-115", "the board the design is written for")
+	activeHighKeys := flag.Bool("active-high-keys", false, "pass KEY to the design as 1 while pressed")
+	flag.Usage = func() { usage(os.Stderr) }
+	flag.Parse()
+
+	if flag.NArg() != 1 {
+		usage(os.Stderr)
+		os.Exit(2)
+	}
+	source := flag.Arg(0)
+
+	fail := func(err error) {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		os.Exit(1)
+	}
+
+	board, err := de2gui.LookupProfile(*boardName)
+	if err != nil {
+		fail(err)
+	}
+
+	modules, err := verilog.ParseFile(source)
+	if err != nil {
+		fail(err)
+	}
+
+	m, err := verilog.Top(modules, *top)
+	if err != nil {
+		fail(err)
+	}
+
+	h := newHarness(m, bind(os.Stderr, m, board), *activeHighKeys)
+	h.Board = board.Name
+
+	if err := os.MkdirAll(*out, 0755); err != nil {
+		fail(err)
+	}
+
+	// the Makefile runs Verilator from the harness directory
+	absOut, err := filepath.Abs(*out)
+	if err != nil {
+		fail(err)
+	}
+	absSource, err := filepath.Abs(source)
+	if err != nil {
+		fail(err)
+	}
+	h.Source, err = filepath.Rel(absOut, absSource)
+	if err != nil {
+		h.Source = absSource
+	}
+
+	for _, f := range files {
+		if err := writeFile(filepath.Join(*out, f.name), f.t, h); err != nil {
+			fail(err)
+		}
+	}
+
+	fmt.Printf("wrote harness for %s to %s, build it with: make -C %s\n", m.Name, *out, *out)
+}
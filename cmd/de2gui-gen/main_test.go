@@ -0,0 +1,183 @@
+package main
+
+import (
+	"bytes"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/herclab/de2gui/de2gui"
+	"github.com/herclab/de2gui/de2gui/verilog"
+)
+
+// counterSource is a design with every kind of port
+const counterSource = `
+module counter (
+	input         CLOCK_50,
+	input  [17:0] SW,
+	input  [3:0]  KEY,
+	output [17:0] LEDR,
+	output [8:0]  LEDG,
+	output [6:0]  HEX0,
+	output [39:0] HEX1,
+	output [N:0]  HEX2,
+	input         enable
+);
+endmodule
+`
+
+// newCounterHarness returns the harness for counterSource, and the warnings
+// printed while binding its ports
+func newCounterHarness(t *testing.T, activeHighKeys bool) (*harness, string) {
+	t.Helper()
+
+	modules, err := verilog.Parse("counter.v", strings.NewReader(counterSource))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var warnings bytes.Buffer
+	h := newHarness(modules[0], bind(&warnings, modules[0], de2gui.ProfileDE2115), activeHighKeys)
+	h.Board = "DE2-115"
+	h.Source = "../counter.v"
+	return h, warnings.String()
+}
+
+func TestNewHarness(t *testing.T) {
+	h, warnings := newCounterHarness(t, false)
+	if h.Top != "counter" || !h.Clock {
+		t.Errorf("the harness is for %s, with Clock %v", h.Top, h.Clock)
+	}
+
+	// HEX1 is too wide and the width of HEX2 is unknown, so they are left
+	// out, as is enable, which is not a board signal
+	inputs := []port{
+		{Name: "CLOCK_50", Signal: "CLOCK_50", Mask: 0x1},
+		{Name: "SW", Signal: "SW", Mask: 0x3ffff, Expr: "s.SW()"},
+		{Name: "KEY", Signal: "KEY", Mask: 0xf, Expr: "^s.KEY()"},
+	}
+	outputs := []port{
+		{Name: "LEDR", Signal: "LEDR", Mask: 0x3ffff, Expr: "s.SetLEDR(m.readLEDR())"},
+		{Name: "LEDG", Signal: "LEDG", Mask: 0x1ff, Expr: "s.SetLEDG(m.readLEDG())"},
+		{Name: "HEX0", Signal: "HEX0", Mask: 0x7f, Expr: "s.SetHEX(0, uint8(m.readHEX0()))"},
+	}
+	if !reflect.DeepEqual(h.Inputs, inputs) {
+		t.Errorf("the inputs are %+v, expected %+v", h.Inputs, inputs)
+	}
+	if !reflect.DeepEqual(h.Outputs, outputs) {
+		t.Errorf("the outputs are %+v, expected %+v", h.Outputs, outputs)
+	}
+
+	want := []string{
+		"warning: line 9: HEX1: is 40 bits wide, but HEX1 has 7",
+		"warning: line 10: HEX2: width could not be determined, expected 7 bits",
+		"warning: line 11: enable: not a DE2-115 signal, will be 0",
+		"warning: line 9: HEX1: not connected, since it is wider than 32 bits",
+		"warning: line 10: HEX2: not connected, since its width is unknown",
+	}
+	if got := strings.Split(strings.TrimSpace(warnings), "\n"); !reflect.DeepEqual(got, want) {
+		t.Errorf("printed the warnings %q, expected %q", got, want)
+	}
+
+	h, _ = newCounterHarness(t, true)
+	if h.Inputs[2].Expr != "s.KEY()" {
+		t.Errorf("with active-high KEYs, KEY is %q", h.Inputs[2].Expr)
+	}
+}
+
+// fakeModel stands in for the header Verilator generates for counter, so
+// that the shim can be compiled without Verilator
+const fakeModel = `
+#include <stdint.h>
+
+struct Vcounter {
+	uint8_t CLOCK_50;
+	uint32_t SW;
+	uint8_t KEY;
+	uint32_t LEDR;
+	uint16_t LEDG;
+	uint8_t HEX0;
+	void eval() {}
+	void final() {}
+};
+`
+
+func TestTemplates(t *testing.T) {
+	dir, err := ioutil.TempDir("", "de2gui-gen")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	h, _ := newCounterHarness(t, false)
+	for _, f := range files {
+		if err := writeFile(filepath.Join(dir, f.name), f.t, h); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	read := func(name string) string {
+		b, err := ioutil.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(b)
+	}
+
+	// the Go files are valid, and formatted as gofmt would
+	for _, name := range []string{"model.go", "main.go"} {
+		src := read(name)
+		if _, err := parser.ParseFile(token.NewFileSet(), name, src, 0); err != nil {
+			t.Errorf("%s: %v\n%s", name, err, src)
+			continue
+		}
+
+		formatted, err := format.Source([]byte(src))
+		if err != nil || !bytes.Equal(formatted, []byte(src)) {
+			t.Errorf("%s is not formatted:\n%s", name, src)
+		}
+	}
+
+	contains := []struct {
+		name, text string
+	}{
+		{"main.go", "m.setKEY(^s.KEY())"},
+		{"main.go", "s.SetHEX(0, uint8(m.readHEX0()))"},
+		{"main.go", `de2gui.LookupProfile("DE2-115")`},
+		{"model.go", "func (m *model) readLEDG() uint32 {"},
+		{"shim.cpp", "s->model->SW = v & 0x3ffffu;"},
+		{"shim.h", "uint32_t de2sim_get_HEX0(de2sim *s);"},
+		{"Makefile", "SOURCES = ../counter.v"},
+	}
+	for _, c := range contains {
+		if !strings.Contains(read(c.name), c.text) {
+			t.Errorf("%s does not contain %q:\n%s", c.name, c.text, read(c.name))
+		}
+	}
+
+	// the shim compiles against a model with the same ports
+	cxx, err := exec.LookPath("c++")
+	if err != nil {
+		t.Log("c++ not found, the shim is not compiled")
+		return
+	}
+	fakes := map[string]string{"Vcounter.h": fakeModel, "verilated.h": ""}
+	for name, text := range fakes {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(text), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	cmd := exec.Command(cxx, "-fsyntax-only", "-Wall", "-Werror", "shim.cpp")
+	cmd.Dir = dir
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Errorf("compiling the shim: %v\n%s", err, out)
+	}
+}
@@ -0,0 +1,268 @@
+package main
+
+import (
+	"text/template"
+)
+
+// The templates are executed with a harness as their data.
+
+var shimHeader = template.Must(template.New("shim.h").Parse(`// Code generated by de2gui-gen from {{.Source}}. DO NOT EDIT.
+
+// C interface to the Verilated {{.Top}} module, for use by Cgo.
+
+#ifndef DE2SIM_SHIM_H
+#define DE2SIM_SHIM_H
+
+#include <stdint.h>
+
+#ifdef __cplusplus
+extern "C" {
+#endif
+
+typedef struct de2sim de2sim;
+
+de2sim *de2sim_new(void);
+void de2sim_free(de2sim *s);
+void de2sim_eval(de2sim *s);
+{{range .Inputs}}
+void de2sim_set_{{.Name}}(de2sim *s, uint32_t v);
+{{- end}}
+{{range .Outputs}}
+uint32_t de2sim_get_{{.Name}}(de2sim *s);
+{{- end}}
+
+#ifdef __cplusplus
+}
+#endif
+
+#endif
+`))
+
+var shimSource = template.Must(template.New("shim.cpp").Parse(`// Code generated by de2gui-gen from {{.Source}}. DO NOT EDIT.
+
+#include "V{{.Top}}.h"
+#include "verilated.h"
+
+#include "shim.h"
+
+struct de2sim {
+	V{{.Top}} *model;
+};
+
+// required by older versions of Verilator
+double sc_time_stamp() {
+	return 0;
+}
+
+extern "C" de2sim *de2sim_new(void) {
+	de2sim *s = new de2sim;
+	s->model = new V{{.Top}};
+	return s;
+}
+
+extern "C" void de2sim_free(de2sim *s) {
+	s->model->final();
+	delete s->model;
+	delete s;
+}
+
+extern "C" void de2sim_eval(de2sim *s) {
+	s->model->eval();
+}
+{{range .Inputs}}
+extern "C" void de2sim_set_{{.Name}}(de2sim *s, uint32_t v) {
+	s->model->{{.Name}} = v & {{printf "0x%x" .Mask}}u;
+}
+{{end}}{{range .Outputs}}
+extern "C" uint32_t de2sim_get_{{.Name}}(de2sim *s) {
+	return s->model->{{.Name}};
+}
+{{end}}`))
+
+var modelSource = template.Must(template.New("model.go").Parse(`// Code generated by de2gui-gen from {{.Source}}. DO NOT EDIT.
+
+package main
+
+// #cgo CXXFLAGS: -std=c++17 -I${SRCDIR}/obj_dir
+// #cgo LDFLAGS: ${SRCDIR}/obj_dir/V{{.Top}}__ALL.a ${SRCDIR}/obj_dir/libverilated.a -lstdc++ -pthread
+// #include "shim.h"
+import "C"
+
+// model is an instance of the Verilated {{.Top}} module
+type model struct {
+	sim *C.de2sim
+}
+
+func newModel() *model {
+	return &model{sim: C.de2sim_new()}
+}
+
+// free runs the model's final blocks, and frees it
+func (m *model) free() {
+	C.de2sim_free(m.sim)
+}
+
+func (m *model) eval() {
+	C.de2sim_eval(m.sim)
+}
+{{range .Inputs}}
+// set{{.Signal}} sets the {{.Name}} input
+func (m *model) set{{.Signal}}(v uint32) {
+	C.de2sim_set_{{.Name}}(m.sim, C.uint32_t(v))
+}
+{{end}}{{range .Outputs}}
+// read{{.Signal}} returns the value of the {{.Name}} output
+func (m *model) read{{.Signal}}() uint32 {
+	return uint32(C.de2sim_get_{{.Name}}(m.sim))
+}
+{{end}}`))
+
+var mainSource = template.Must(template.New("main.go").Parse(`// Code generated by de2gui-gen from {{.Source}}. DO NOT EDIT.
+
+// Command {{.Top}} simulates the {{.Top}} module on a virtual {{.Board}},
+// using de2gui. Build it with make. With -cli, no window is shown, and
+// commands in the de2gui script format are read from standard input
+// instead, printing the state of the board after each one. With -tui, the
+// board is shown in the terminal, which is quicker than a window over SSH.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/app"
+
+	"github.com/herclab/de2gui/de2gui"
+	"github.com/herclab/de2gui/de2gui/tui"
+)
+
+func main() {
+	cli := flag.Bool("cli", false, "read commands from standard input rather than showing a window")
+	term := flag.Bool("tui", false, "show the board in the terminal rather than in a window")
+	flag.Parse()
+
+	board, err := de2gui.LookupProfile({{printf "%q" .Board}})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		os.Exit(2)
+	}
+
+	m := newModel()
+
+	// the widgets cannot be created before the app
+	var s *de2gui.UIState
+	var w fyne.Window
+	if *cli || *term {
+		s = de2gui.NewHeadlessUIStateFor(board)
+	} else {
+		a := app.New()
+		w = a.NewWindow({{printf "%q" .Top}})
+		w.SetMaster()
+		s = de2gui.NewUIStateFor(board)
+	}
+
+	// inputs copies the board's inputs to the model
+	inputs := func() {
+{{- range .Inputs}}{{if .Expr}}
+		m.set{{.Signal}}({{.Expr}})
+{{- end}}{{end}}
+	}
+
+	// outputs copies the model's outputs to the board
+	outputs := func() {
+{{- range .Outputs}}
+		{{.Expr}}
+{{- end}}
+	}
+
+	// each tick is one cycle of CLOCK_50
+	s.OnTick = func(s *de2gui.UIState, final bool) {
+		s.Tick++
+{{- if .Clock}}
+		m.setCLOCK_50(1)
+		m.eval()
+		m.setCLOCK_50(0)
+{{- end}}
+		m.eval()
+
+		if final {
+			outputs()
+		}
+	}
+
+	s.OnSW = func(s *de2gui.UIState) {
+		inputs()
+		m.eval()
+		outputs()
+	}
+	s.OnKEY = s.OnSW
+
+	// reset starts again with a new instance of the model
+	s.OnReset = func(s *de2gui.UIState) {
+		m.free()
+		m = newModel()
+
+		s.Tick = 0
+		s.ClearFutures()
+		inputs()
+		m.eval()
+		outputs()
+	}
+
+	inputs()
+	m.eval()
+	outputs()
+
+	if *cli {
+		r := s.RunCommands(os.Stdin, os.Stdout, de2gui.CommandOptions{Name: {{printf "%q" .Top}}})
+		m.free()
+		if !r.OK() {
+			os.Exit(1)
+		}
+		return
+	}
+
+	if *term {
+		err := tui.Run(s, os.Stdin, os.Stdout, tui.Options{})
+		m.free()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%v\n", err)
+			os.Exit(2)
+		}
+		return
+	}
+
+	w.SetContent(s.FyneObject())
+	w.ShowAndRun()
+
+	m.free()
+}
+`))
+
+var makefile = template.Must(template.New("Makefile").Parse(`# Code generated by de2gui-gen from {{.Source}}. DO NOT EDIT.
+#
+# Builds the de2gui harness for {{.Top}}. This needs a version of Verilator
+# whose generated makefiles can build libverilated.a.
+
+VERILATOR ?= verilator
+VERILATOR_ROOT ?= $(shell $(VERILATOR) --getenv VERILATOR_ROOT)
+SOURCES = {{.Source}}
+
+{{.Top}}: main.go model.go shim.cpp shim.h obj_dir/V{{.Top}}__ALL.a go.mod
+	CGO_CXXFLAGS="-I$(VERILATOR_ROOT)/include -I$(VERILATOR_ROOT)/include/vltstd" go build -o $@ .
+
+obj_dir/V{{.Top}}__ALL.a: $(SOURCES)
+	$(VERILATOR) --cc $(SOURCES) --top-module {{.Top}} -Mdir obj_dir
+	$(MAKE) -C obj_dir -f V{{.Top}}.mk V{{.Top}}__ALL.a libverilated.a
+
+go.mod:
+	go mod init {{.Top}}
+	go get github.com/herclab/de2gui/de2gui
+
+clean:
+	rm -rf obj_dir {{.Top}}
+
+.PHONY: clean
+`))
@@ -0,0 +1,83 @@
+// Command de2gui-server shows a board, and waits for a simulator to connect
+// to it, using the protocol described in the simproto package. Since the
+// simulator runs in its own process, it can be restarted, or crash, without
+// closing the window.
+//
+// Usage:
+//
+//	de2gui-server [-listen address] [-board name]
+//	de2gui-server -run "command args..." [-board name]
+//
+// The address is either unix:/path/to/socket, or a TCP address such as
+// localhost:6110, which is the default. With -run, the command is started
+// instead, and the protocol is spoken over its standard input and output.
+// If it exits, the end of its standard error is shown, and it can be
+// restarted. For example, to try it out with the stand-in simulator:
+//
+//	go build ./cmd/de2gui-sim
+//	de2gui-server -run "./de2gui-sim -crash-after 1000"
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+
+	"fyne.io/fyne/v2/app"
+
+	"github.com/herclab/de2gui/de2gui"
+	"github.com/herclab/de2gui/de2gui/simproto"
+)
+
+func main() {
+	address := flag.String("listen", "localhost:6110", "the address to listen for simulators on")
+	run := flag.String("run", "", "start this simulator, rather than listening for one")
+	boardName := flag.String("board", "DE2-115", "the board to show")
+	flag.Parse()
+
+	board, err := de2gui.LookupProfile(*boardName)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		os.Exit(2)
+	}
+
+	a := app.New()
+	w := a.NewWindow("de2gui - " + *address)
+	w.SetMaster()
+
+	s := de2gui.NewUIStateFor(board)
+
+	if *run != "" {
+		args := strings.Fields(*run)
+		if len(args) == 0 {
+			fmt.Fprintf(os.Stderr, "-run: no command given\n")
+			os.Exit(2)
+		}
+
+		sup := s.NewSupervisor(func() *exec.Cmd {
+			return exec.Command(args[0], args[1:]...)
+		})
+		if err := sup.Start(); err != nil {
+			fmt.Fprintf(os.Stderr, "%v\n", err)
+			os.Exit(1)
+		}
+		defer sup.Stop()
+
+		w.SetTitle("de2gui - " + args[0])
+	} else {
+		l, err := simproto.Listen(*address)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%v\n", err)
+			os.Exit(1)
+		}
+		defer l.Close()
+
+		go s.ServeSimulators(l)
+		fmt.Printf("waiting for a simulator on %s\n", *address)
+	}
+
+	w.SetContent(s.FyneObject())
+	w.ShowAndRun()
+}
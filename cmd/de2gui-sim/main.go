@@ -0,0 +1,100 @@
+// Command de2gui-sim is a stand-in for a simulator, which speaks the
+// protocol described in the simproto package, for trying out and testing
+// de2gui's support for out-of-process simulators without a Verilog design.
+//
+// Usage:
+//
+//	de2gui-sim [-connect address]
+//
+// By default, it speaks the protocol over its standard input and output, as
+// for a simulator started with de2gui-server -run. With -connect, it
+// connects to de2gui-server at the given address instead.
+//
+// The simulated design is a counter, incremented on each tick while SW0 is
+// on, and shown in hexadecimal on the HEX displays. The red LEDs follow the
+// switches, and the green LEDs follow the KEYs. Pressing KEY0 clears the
+// counter.
+//
+// To try out de2gui's handling of simulators which crash, -crash-after makes
+// it panic once the counter reaches the given value.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/herclab/de2gui/de2gui"
+	"github.com/herclab/de2gui/de2gui/simproto"
+)
+
+// numHex is the number of HEX displays the counter is shown on
+const numHex = 8
+
+// counter is the simulated design
+type counter struct {
+	sw, key uint32
+	count   uint32
+
+	// crashAfter is the count at which to crash, or 0 to never crash
+	crashAfter uint32
+}
+
+func (c *counter) SetInputs(sw, key uint32) {
+	c.sw = sw
+	c.key = key
+	if key&1 != 0 {
+		c.count = 0
+	}
+}
+
+func (c *counter) Tick() {
+	if c.sw&1 != 0 && c.key&1 == 0 {
+		c.count++
+	}
+
+	if c.crashAfter != 0 && c.count >= c.crashAfter {
+		fmt.Fprintf(os.Stderr, "counter reached %d, crashing\n", c.count)
+		var p *counter
+		p.count++
+	}
+}
+
+func (c *counter) Reset() {
+	c.count = 0
+}
+
+func (c *counter) Outputs() simproto.Outputs {
+	out := simproto.Outputs{LEDR: c.sw, LEDG: c.key, HEX: make([]uint8, numHex)}
+	for i := range out.HEX {
+		out.HEX[i] = de2gui.HexSegments(uint8(c.count >> uint(4*i)))
+	}
+	return out
+}
+
+func main() {
+	address := flag.String("connect", "", "connect to de2gui at this address, rather than using standard input and output")
+	crashAfter := flag.Uint("crash-after", 0, "crash when the counter reaches this value")
+	flag.Parse()
+
+	var rw io.ReadWriter = struct {
+		io.Reader
+		io.Writer
+	}{os.Stdin, os.Stdout}
+
+	if *address != "" {
+		c, err := simproto.Dial(*address)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%v\n", err)
+			os.Exit(1)
+		}
+		defer c.Close()
+		rw = c
+	}
+
+	if err := simproto.Serve(rw, &counter{crashAfter: uint32(*crashAfter)}); err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,74 @@
+// Command de2gui-tui shows a simulated board in a terminal, rather than in a
+// window, for use over SSH, where forwarding a window is too slow. See the
+// tui package for the keys which work the board.
+//
+// Usage:
+//
+//	de2gui-tui [flags]
+//
+// As with de2gui-cli, the design is either a simulator started with -run,
+// one which connects to the address given by -listen, using the protocol
+// described in the simproto package, or, by default, a demo which shows the
+// tick number on the red LEDs. For example:
+//
+//	go build ./cmd/de2gui-sim
+//	de2gui-tui -run ./de2gui-sim
+//
+// Anything the simulator writes to its standard error is shown in the log
+// below the board.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/herclab/de2gui/cmd/internal/design"
+	"github.com/herclab/de2gui/de2gui"
+	"github.com/herclab/de2gui/de2gui/tui"
+)
+
+func usage() {
+	fmt.Fprintf(os.Stderr, "usage: de2gui-tui [flags]\n\nflags:\n")
+	flag.PrintDefaults()
+}
+
+func main() {
+	boardName := flag.String("board", "DE2-115", "the board to simulate")
+	run := flag.String("run", "", "start this simulator, rather than using the demo design")
+	listen := flag.String("listen", "", "wait for a simulator to connect to this address, rather than using the demo design")
+	noColor := flag.Bool("no-color", false, "draw the board without colors")
+	flag.Usage = usage
+	flag.Parse()
+
+	if flag.NArg() > 0 {
+		usage()
+		os.Exit(2)
+	}
+
+	board, err := de2gui.LookupProfile(*boardName)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		os.Exit(2)
+	}
+
+	s := de2gui.NewHeadlessUIStateFor(board)
+
+	// the simulator's messages would spoil the screen
+	stderr := s.LogWriter(de2gui.LevelInfo)
+
+	disconnect, err := design.Connect(s, design.Options{Run: *run, Listen: *listen, Stderr: stderr})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		os.Exit(2)
+	}
+
+	err = tui.Run(s, os.Stdin, os.Stdout, tui.Options{NoColor: *noColor})
+	disconnect()
+	stderr.Close()
+
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		os.Exit(2)
+	}
+}
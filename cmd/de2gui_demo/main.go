@@ -1,23 +1,30 @@
 // This example application creates a window with a DE2GUI instance int it.
 // The red LEDs are used to show the tick number.
+//
+// The -board flag selects the board to show, for example DE10-Lite, see
+// de2gui.Profiles. Alternatively, -layout shows a custom board described by a
+// layout file, such as lab_board.json, see the layout package.
+//
+// If the -script flag is given, no window is created. Instead the script is
+// run against the demo, and a report is written to standard out, and
+// optionally to the files named by -json and -junit. The exit code is
+// non-zero if any check failed.
 package main
 
 import (
+	"flag"
 	"fmt"
+	"os"
 
 	"fyne.io/fyne/v2/app"
 
 	"github.com/herclab/de2gui/de2gui"
+	"github.com/herclab/de2gui/de2gui/layout"
+	"github.com/herclab/de2gui/de2gui/script"
 )
 
-func main() {
-	app := app.New()
-	w := app.NewWindow("de2gui demo")
-
-	w.SetMaster()
-
-	s := de2gui.NewUIState()
-
+// setup installs the demo's callbacks into s
+func setup(s *de2gui.UIState) {
 	s.OnKEY = func(s *de2gui.UIState) {
 		fmt.Printf("KEY pressed, key state is: 0x%x\n", s.KEY())
 	}
@@ -48,10 +55,133 @@ func main() {
 		s.SetLEDG(0)
 		s.ClearFutures()
 		s.ClearSW()
-		for i := 0; i < 8; i++ {
+		for i := 0; i < s.Profile().HEX; i++ {
 			s.SetHEX(i, 0xff)
 		}
 	}
+}
+
+// writeReport writes the report to path using the given function, unless
+// path is empty
+func writeReport(path string, write func(f *os.File) error) error {
+	if path == "" {
+		return nil
+	}
+
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+
+	if err := write(f); err != nil {
+		f.Close()
+		return err
+	}
+
+	return f.Close()
+}
+
+// grade runs the script at scriptPath without a GUI and returns the exit
+// code for the program
+func grade(board de2gui.BoardProfile, scriptPath, jsonPath, junitPath string) int {
+	sc, err := script.ParseFile(scriptPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		return 2
+	}
+
+	s := de2gui.NewHeadlessUIStateFor(board)
+	setup(s)
+
+	r := s.RunScript(sc)
+
+	for _, v := range r.Results {
+		status := "PASS"
+		if !v.Pass {
+			status = "FAIL"
+		}
+		fmt.Printf("%s line %d tick %d: %s (observed %s)\n", status, v.Line, v.Tick, v.Check, v.Observed)
+	}
+
+	if r.Error != "" {
+		fmt.Printf("ERROR %s\n", r.Error)
+	}
+
+	fmt.Printf("%d passed, %d failed\n", r.Passed(), r.Failed())
+
+	if err := writeReport(jsonPath, func(f *os.File) error { return r.WriteJSON(f) }); err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		return 2
+	}
+
+	if err := writeReport(junitPath, func(f *os.File) error { return r.WriteJUnit(f) }); err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		return 2
+	}
+
+	if !r.OK() {
+		return 1
+	}
+
+	return 0
+}
+
+func main() {
+	scriptPath := flag.String("script", "", "run this test script without a GUI")
+	jsonPath := flag.String("json", "", "write the script report to this file as JSON")
+	junitPath := flag.String("junit", "", "write the script report to this file as JUnit XML")
+	boardName := flag.String("board", "DE2-115", "the board to show")
+	layoutPath := flag.String("layout", "", "show the board described by this layout file instead of -board")
+	skin := flag.Bool("skin", false, "show the DE2-115 board skin instead of -board")
+	background := flag.String("background", "", "draw this image behind the board skin")
+	flag.Parse()
+
+	board, err := de2gui.LookupProfile(*boardName)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		os.Exit(2)
+	}
+
+	var l *layout.Layout
+	if *layoutPath != "" {
+		l, err = layout.ParseFile(*layoutPath)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%v\n", err)
+			os.Exit(2)
+		}
+		board = de2gui.LayoutProfile(l)
+	} else if *skin {
+		l = layout.SkinDE2115()
+		if *background != "" {
+			l.Background = *background
+			l.RemoveOutlines()
+		}
+		board = de2gui.LayoutProfile(l)
+	}
+
+	if *scriptPath != "" {
+		os.Exit(grade(board, *scriptPath, *jsonPath, *junitPath))
+	}
+
+	app := app.New()
+	w := app.NewWindow("de2gui demo")
+
+	w.SetMaster()
+
+	var s *de2gui.UIState
+	if l != nil {
+		s, err = de2gui.NewUIStateFromLayout(l)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%v\n", err)
+			os.Exit(2)
+		}
+		if l.Name != "" {
+			w.SetTitle(l.Name)
+		}
+	} else {
+		s = de2gui.NewUIStateFor(board)
+	}
+	setup(s)
 
 	w.SetContent(s.FyneObject())
 
@@ -0,0 +1,95 @@
+// Package design connects the board of a command line frontend, such as
+// de2gui-cli or de2gui-tui, to the design chosen by its flags: a simulator
+// started as a child process, one which connects over the network, or a demo
+// which needs neither.
+package design
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/herclab/de2gui/de2gui"
+	"github.com/herclab/de2gui/de2gui/simproto"
+)
+
+// Options chooses the design to connect to.
+type Options struct {
+	// Run is the command line of a simulator to start, split into
+	// arguments at spaces.
+	Run string
+
+	// Listen is an address to wait for a simulator to connect to, using
+	// the protocol described in the simproto package. It is ignored if
+	// Run is set.
+	Listen string
+
+	// Stderr receives the standard error of a simulator started with
+	// Run. If it is nil, it is passed through to os.Stderr.
+	Stderr io.Writer
+}
+
+// Demo installs the callbacks of a demo design into s, which shows the tick
+// number on the red LEDs.
+func Demo(s *de2gui.UIState) {
+	s.OnTick = func(s *de2gui.UIState, final bool) {
+		s.Tick++
+		if final {
+			s.SetLEDR(uint32(s.Tick))
+		}
+	}
+
+	s.OnReset = func(s *de2gui.UIState) {
+		s.Tick = 0
+		s.SetLEDR(0)
+		s.ClearFutures()
+	}
+}
+
+// Connect connects s to the design chosen by opts, or to the demo design if
+// neither Run nor Listen is set, returning a function which disconnects from
+// it.
+func Connect(s *de2gui.UIState, opts Options) (func(), error) {
+	switch {
+	case opts.Run != "":
+		args := strings.Fields(opts.Run)
+		if len(args) == 0 {
+			return nil, fmt.Errorf("-run: no command given")
+		}
+
+		cmd := exec.Command(args[0], args[1:]...)
+		cmd.Stderr = opts.Stderr
+
+		sim, err := s.StartSimulator(cmd)
+		if err != nil {
+			return nil, err
+		}
+		return func() { sim.Close(); sim.Wait() }, nil
+
+	case opts.Listen != "":
+		l, err := simproto.Listen(opts.Listen)
+		if err != nil {
+			return nil, err
+		}
+		defer l.Close()
+
+		fmt.Fprintf(os.Stderr, "waiting for a simulator on %s\n", opts.Listen)
+		var c net.Conn
+		if c, err = l.Accept(); err != nil {
+			return nil, err
+		}
+
+		sim, err := s.ConnectSimulator(c)
+		if err != nil {
+			return nil, err
+		}
+		return func() { sim.Close(); sim.Wait() }, nil
+
+	default:
+		Demo(s)
+		return func() {}, nil
+	}
+}
@@ -0,0 +1,80 @@
+// The public interface in de2gui.h, implemented using the functions exported
+// from Go. Keeping them separate means that the header does not change with
+// the way cgo declares exported functions.
+
+#include <stddef.h>
+
+#include "de2gui.h"
+#include "_cgo_export.h"
+
+int de2gui_api_version(void) {
+	return DE2GUI_API_VERSION;
+}
+
+de2gui_handle de2gui_new(const char *board) {
+	return goNew((char *)board);
+}
+
+void de2gui_free(de2gui_handle gui) {
+	goFree(gui);
+}
+
+void de2gui_set_ledr(de2gui_handle gui, uint32_t state) {
+	goSetLEDR(gui, state);
+}
+
+void de2gui_set_ledg(de2gui_handle gui, uint32_t state) {
+	goSetLEDG(gui, state);
+}
+
+void de2gui_set_hex(de2gui_handle gui, int i, uint8_t segments) {
+	goSetHEX(gui, i, segments);
+}
+
+void de2gui_set_lcd(de2gui_handle gui, int line, const char *text) {
+	goSetLCD(gui, line, (char *)text);
+}
+
+void de2gui_log(de2gui_handle gui, int level, const char *text) {
+	goLog(gui, level, (char *)text);
+}
+
+uint32_t de2gui_sw(de2gui_handle gui) {
+	return goSW(gui);
+}
+
+uint32_t de2gui_key(de2gui_handle gui) {
+	return goKEY(gui);
+}
+
+uint64_t de2gui_tick(de2gui_handle gui) {
+	return goTick(gui);
+}
+
+void de2gui_on_tick(de2gui_handle gui, de2gui_tick_func f, void *user) {
+	goOnTick(gui, f, user);
+}
+
+void de2gui_on_sw(de2gui_handle gui, de2gui_event_func f, void *user) {
+	goOnEvent(gui, EVENT_SW, f, user);
+}
+
+void de2gui_on_key(de2gui_handle gui, de2gui_event_func f, void *user) {
+	goOnEvent(gui, EVENT_KEY, f, user);
+}
+
+void de2gui_on_reset(de2gui_handle gui, de2gui_event_func f, void *user) {
+	goOnEvent(gui, EVENT_RESET, f, user);
+}
+
+int de2gui_run(de2gui_handle gui, const char *title) {
+	return goRun(gui, (char *)title);
+}
+
+void call_tick(de2gui_tick_func f, de2gui_handle gui, int final, void *user) {
+	f(gui, final, user);
+}
+
+void call_event(de2gui_event_func f, de2gui_handle gui, void *user) {
+	f(gui, user);
+}
@@ -0,0 +1,126 @@
+/*
+ * de2gui.h - C interface to de2gui
+ *
+ * This is the interface of libde2gui, a build of de2gui as a shared library,
+ * which lets C and C++ simulation harnesses, such as a Verilator sim_main.cpp,
+ * drive the GUI directly. Build the library with:
+ *
+ *	go build -buildmode=c-shared -o libde2gui.so ./cmd/libde2gui
+ *
+ * and link against it with -lde2gui. A minimal harness looks like:
+ *
+ *	static void tick(de2gui_handle gui, int final, void *user) {
+ *		Vtop *top = (Vtop *)user;
+ *		top->SW = de2gui_sw(gui);
+ *		top->KEY = ~de2gui_key(gui);
+ *		top->CLOCK_50 = 1; top->eval();
+ *		top->CLOCK_50 = 0; top->eval();
+ *		if (final) {
+ *			de2gui_set_ledr(gui, top->LEDR);
+ *			de2gui_set_hex(gui, 0, top->HEX0);
+ *		}
+ *	}
+ *
+ *	int main(int argc, char **argv) {
+ *		Vtop *top = new Vtop;
+ *		de2gui_handle gui = de2gui_new(NULL);
+ *		de2gui_on_tick(gui, tick, top);
+ *		return de2gui_run(gui, "top");
+ *	}
+ *
+ * The GUI toolkit requires its windows to be created and its main loop to be
+ * run on the main thread, the one which runs main(), so de2gui_new() and
+ * de2gui_run() must be called from it. The other functions may be called from
+ * any thread, including from the callbacks, which are called from threads
+ * created by the library.
+ *
+ * Functions which are given a handle which is not valid do nothing, and
+ * return 0. Changes to this interface which are not backwards compatible
+ * increase DE2GUI_API_VERSION.
+ */
+
+#ifndef DE2GUI_H
+#define DE2GUI_H
+
+#include <stdint.h>
+
+#ifdef __cplusplus
+extern "C" {
+#endif
+
+#define DE2GUI_API_VERSION 1
+
+/* de2gui_handle identifies a board created by de2gui_new(). Valid handles
+ * are never 0. */
+typedef int de2gui_handle;
+
+/* de2gui_tick_func is called for each tick. final is non-zero if this is the
+ * last of a group of ticks run at once, in which case the outputs should be
+ * updated; updating them on every tick is slow. */
+typedef void (*de2gui_tick_func)(de2gui_handle gui, int final, void *user);
+
+/* de2gui_event_func is called when the switches or KEYs change, or the board
+ * is reset. */
+typedef void (*de2gui_event_func)(de2gui_handle gui, void *user);
+
+/* de2gui_api_version returns the DE2GUI_API_VERSION the library was built
+ * with. */
+int de2gui_api_version(void);
+
+/* de2gui_new creates a board. board is the name of a board profile, such as
+ * "DE10-Lite", or NULL for the DE2-115. It returns 0, and prints a message,
+ * if the board is not known. It must be called from the main thread. */
+de2gui_handle de2gui_new(const char *board);
+
+/* de2gui_free releases a board which is not running, and stops it ticking,
+ * so its callbacks are not called after it returns. */
+void de2gui_free(de2gui_handle gui);
+
+/* Outputs. HEX segments are active-low, as on the physical board, with
+ * segment 0 in bit 0. */
+void de2gui_set_ledr(de2gui_handle gui, uint32_t state);
+void de2gui_set_ledg(de2gui_handle gui, uint32_t state);
+void de2gui_set_hex(de2gui_handle gui, int i, uint8_t segments);
+void de2gui_set_lcd(de2gui_handle gui, int line, const char *text);
+
+/* Log levels, see de2gui_log(). */
+enum {
+	DE2GUI_LOG_DEBUG,
+	DE2GUI_LOG_INFO,
+	DE2GUI_LOG_WARNING,
+	DE2GUI_LOG_ERROR
+};
+
+/* de2gui_log adds text to the board's log panel at the given level, stamped
+ * with the current tick. Text containing several lines is split into one
+ * message per line. */
+void de2gui_log(de2gui_handle gui, int level, const char *text);
+
+/* Inputs. A KEY's bit is set while it is pressed, so it must be inverted to
+ * drive a design written for the physical board, whose KEYs are active-low. */
+uint32_t de2gui_sw(de2gui_handle gui);
+uint32_t de2gui_key(de2gui_handle gui);
+
+/* de2gui_tick returns the number of ticks since the board was created or
+ * last reset. It is incremented before the tick function is called. */
+uint64_t de2gui_tick(de2gui_handle gui);
+
+/* Callbacks. Each replaces the previous function of its kind; NULL removes
+ * it. user is passed to the function unchanged. The tick count is reset to 0
+ * before the reset function is called. */
+void de2gui_on_tick(de2gui_handle gui, de2gui_tick_func f, void *user);
+void de2gui_on_sw(de2gui_handle gui, de2gui_event_func f, void *user);
+void de2gui_on_key(de2gui_handle gui, de2gui_event_func f, void *user);
+void de2gui_on_reset(de2gui_handle gui, de2gui_event_func f, void *user);
+
+/* de2gui_run shows the board in a window with the given title, or "de2gui"
+ * if it is NULL, and runs the GUI's main loop until the window is closed.
+ * It must be called from the program's main thread, and only once. It
+ * returns 0 on success. */
+int de2gui_run(de2gui_handle gui, const char *title);
+
+#ifdef __cplusplus
+}
+#endif
+
+#endif
@@ -0,0 +1,282 @@
+// Command libde2gui builds de2gui as a shared library with a C interface,
+// so that C and C++ simulation harnesses can drive the GUI without any Go
+// glue. Build it with
+//
+//	go build -buildmode=c-shared -o libde2gui.so ./cmd/libde2gui
+//
+// and include de2gui.h, from this directory, which documents the interface.
+// The header which go build writes alongside the library declares the
+// functions used internally, and should not be used.
+//
+// Fyne's drivers require the window to be created and the main loop to be
+// run on the process's main thread, so de2gui_new() and de2gui_run() must
+// be called from the thread which runs the C program's main(). goRun locks
+// the calling thread for the duration of the main loop; the library cannot
+// check that it is the main thread, and on some platforms, such as macOS,
+// calling it from another thread crashes.
+package main
+
+// #include <stdlib.h>
+// #include "de2gui.h"
+//
+// enum { EVENT_SW, EVENT_KEY, EVENT_RESET };
+//
+// void call_tick(de2gui_tick_func f, de2gui_handle gui, int final, void *user);
+// void call_event(de2gui_event_func f, de2gui_handle gui, void *user);
+import "C"
+
+import (
+	"fmt"
+	"os"
+	"runtime"
+	"sync"
+	"unsafe"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/app"
+
+	"github.com/herclab/de2gui/de2gui"
+)
+
+// callback is a C function registered for an event, and its user data
+type callback struct {
+	event C.de2gui_event_func
+	tick  C.de2gui_tick_func
+	user  unsafe.Pointer
+}
+
+// board is a board created through the C interface
+type board struct {
+	s *de2gui.UIState
+
+	// callbacks contains the tick callback, and the event callbacks,
+	// indexed by EVENT_SW etc.
+	tick      callback
+	callbacks [3]callback
+}
+
+var (
+	// boardsMutex guards the variables below, and the callbacks of each
+	// board, which may be changed from any thread
+	boardsMutex sync.Mutex
+	boards                      = map[C.de2gui_handle]*board{}
+	nextHandle  C.de2gui_handle = 1
+	running     bool
+
+	// fyneApp is created along with the first board, since the widgets
+	// cannot be created without it
+	fyneApp fyne.App
+)
+
+// lookup returns the board with the given handle, or nil
+func lookup(h C.de2gui_handle) *board {
+	boardsMutex.Lock()
+	defer boardsMutex.Unlock()
+	return boards[h]
+}
+
+// callEvent calls the C function registered for an event, if any
+func callEvent(h C.de2gui_handle, event int) {
+	boardsMutex.Lock()
+	cb := callback{}
+	if b := boards[h]; b != nil {
+		cb = b.callbacks[event]
+	}
+	boardsMutex.Unlock()
+
+	if cb.event != nil {
+		C.call_event(cb.event, h, cb.user)
+	}
+}
+
+//export goNew
+func goNew(name *C.char) C.de2gui_handle {
+	p := de2gui.ProfileDE2115
+	if name != nil {
+		var err error
+		p, err = de2gui.LookupProfile(C.GoString(name))
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "de2gui: %v\n", err)
+			return 0
+		}
+	}
+
+	boardsMutex.Lock()
+	if fyneApp == nil {
+		fyneApp = app.New()
+	}
+	boardsMutex.Unlock()
+
+	s := de2gui.NewUIStateFor(p)
+
+	boardsMutex.Lock()
+	h := nextHandle
+	nextHandle++
+	boards[h] = &board{s: s}
+	boardsMutex.Unlock()
+
+	s.OnTick = func(s *de2gui.UIState, final bool) {
+		s.Tick++
+
+		boardsMutex.Lock()
+		cb := callback{}
+		if b := boards[h]; b != nil {
+			cb = b.tick
+		}
+		boardsMutex.Unlock()
+
+		if cb.tick != nil {
+			f := C.int(0)
+			if final {
+				f = 1
+			}
+			C.call_tick(cb.tick, h, f, cb.user)
+		}
+	}
+
+	s.OnSW = func(s *de2gui.UIState) {
+		callEvent(h, C.EVENT_SW)
+	}
+
+	s.OnKEY = func(s *de2gui.UIState) {
+		callEvent(h, C.EVENT_KEY)
+	}
+
+	s.OnReset = func(s *de2gui.UIState) {
+		s.Tick = 0
+		s.ClearFutures()
+		callEvent(h, C.EVENT_RESET)
+	}
+
+	return h
+}
+
+//export goFree
+func goFree(h C.de2gui_handle) {
+	boardsMutex.Lock()
+	b := boards[h]
+	delete(boards, h)
+	boardsMutex.Unlock()
+
+	// stop auto-ticking, so that the callbacks are not called after the
+	// board is freed
+	if b != nil {
+		b.s.Close()
+	}
+}
+
+//export goSetLEDR
+func goSetLEDR(h C.de2gui_handle, state C.uint32_t) {
+	if b := lookup(h); b != nil {
+		b.s.SetLEDR(uint32(state))
+	}
+}
+
+//export goSetLEDG
+func goSetLEDG(h C.de2gui_handle, state C.uint32_t) {
+	if b := lookup(h); b != nil {
+		b.s.SetLEDG(uint32(state))
+	}
+}
+
+//export goSetHEX
+func goSetHEX(h C.de2gui_handle, i C.int, segments C.uint8_t) {
+	if b := lookup(h); b != nil {
+		b.s.SetHEX(int(i), uint8(segments))
+	}
+}
+
+//export goSetLCD
+func goSetLCD(h C.de2gui_handle, line C.int, text *C.char) {
+	if b := lookup(h); b != nil && text != nil {
+		b.s.SetLCD(int(line), C.GoString(text))
+	}
+}
+
+//export goLog
+func goLog(h C.de2gui_handle, level C.int, text *C.char) {
+	if b := lookup(h); b != nil && text != nil {
+		b.s.Logf(de2gui.Level(level), "%s", C.GoString(text))
+	}
+}
+
+//export goSW
+func goSW(h C.de2gui_handle) C.uint32_t {
+	if b := lookup(h); b != nil {
+		return C.uint32_t(b.s.SW())
+	}
+	return 0
+}
+
+//export goKEY
+func goKEY(h C.de2gui_handle) C.uint32_t {
+	if b := lookup(h); b != nil {
+		return C.uint32_t(b.s.KEY())
+	}
+	return 0
+}
+
+//export goTick
+func goTick(h C.de2gui_handle) C.uint64_t {
+	if b := lookup(h); b != nil {
+		return C.uint64_t(b.s.Tick)
+	}
+	return 0
+}
+
+//export goOnTick
+func goOnTick(h C.de2gui_handle, f C.de2gui_tick_func, user unsafe.Pointer) {
+	boardsMutex.Lock()
+	defer boardsMutex.Unlock()
+
+	if b := boards[h]; b != nil {
+		b.tick = callback{tick: f, user: user}
+	}
+}
+
+//export goOnEvent
+func goOnEvent(h C.de2gui_handle, event C.int, f C.de2gui_event_func, user unsafe.Pointer) {
+	boardsMutex.Lock()
+	defer boardsMutex.Unlock()
+
+	if b := boards[h]; b != nil && event >= 0 && int(event) < len(b.callbacks) {
+		b.callbacks[event] = callback{event: f, user: user}
+	}
+}
+
+//export goRun
+func goRun(h C.de2gui_handle, title *C.char) C.int {
+	b := lookup(h)
+	if b == nil {
+		return 0
+	}
+
+	boardsMutex.Lock()
+	if running {
+		boardsMutex.Unlock()
+		fmt.Fprintf(os.Stderr, "de2gui: de2gui_run() may only be called once\n")
+		return -1
+	}
+	running = true
+	boardsMutex.Unlock()
+
+	// the GUI's main loop must stay on this thread, which the caller
+	// promises is the main thread
+	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
+
+	t := "de2gui"
+	if title != nil {
+		t = C.GoString(title)
+	}
+
+	w := fyneApp.NewWindow(t)
+	w.SetMaster()
+	w.SetContent(b.s.FyneObject())
+	w.ShowAndRun()
+
+	return 0
+}
+
+// main is required by -buildmode=c-shared, but is not run
+func main() {}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+// TestCInterface builds the library, and compiles and runs
+// testdata/harness.c against it, as C and then as C++, to check de2gui.h
+// and the functions which the library exports. The test is skipped if
+// there is no C compiler.
+func TestCInterface(t *testing.T) {
+	if testing.Short() {
+		t.Skip("building libde2gui is slow")
+	}
+	if runtime.GOOS == "windows" {
+		t.Skip("the harness is built for Unix")
+	}
+	goCmd, err := exec.LookPath("go")
+	if err != nil {
+		t.Skip("the go command is needed to build libde2gui")
+	}
+	if _, err := exec.LookPath("cc"); err != nil {
+		t.Skip("a C compiler is needed to build the harness")
+	}
+
+	dir, err := ioutil.TempDir("", "libde2gui")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	lib := filepath.Join(dir, "libde2gui.so")
+	out, err := exec.Command(goCmd, "build", "-buildmode=c-shared", "-o", lib, ".").CombinedOutput()
+	if err != nil {
+		t.Fatalf("building libde2gui: %v\n%s", err, out)
+	}
+
+	compilers := []struct {
+		cc, lang string
+	}{
+		{"cc", "c"},
+		{"c++", "c++"},
+	}
+
+	for _, c := range compilers {
+		if _, err := exec.LookPath(c.cc); err != nil {
+			t.Logf("%s not found, the harness is not built as %s", c.cc, c.lang)
+			continue
+		}
+
+		harness := filepath.Join(dir, "harness-"+c.lang)
+		out, err := exec.Command(c.cc, "-Wall", "-Werror", "-x", c.lang, "testdata/harness.c",
+			"-x", "none", lib, "-o", harness).CombinedOutput()
+		if err != nil {
+			t.Errorf("compiling the harness as %s: %v\n%s", c.lang, err, out)
+			continue
+		}
+
+		// the unknown board is reported on stderr, which is not
+		// checked
+		cmd := exec.Command(harness)
+		cmd.Env = append(os.Environ(), "LD_LIBRARY_PATH="+dir, "DYLD_LIBRARY_PATH="+dir)
+		out, err = cmd.Output()
+		if err != nil || strings.TrimSpace(string(out)) != "ok" {
+			t.Errorf("the harness built as %s failed: %v\n%s", c.lang, err, out)
+		}
+	}
+}
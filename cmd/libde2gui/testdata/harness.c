@@ -0,0 +1,59 @@
+/*
+ * harness.c - a harness which uses every function in de2gui.h, built by
+ * main_test.go as both C and C++ to check the header, and that the library
+ * exports the functions it declares. It does not open a window, so it only
+ * uses handles which are not valid, and checks that they are ignored.
+ */
+
+#include <stdio.h>
+#include "../de2gui.h"
+
+static void tick(de2gui_handle gui, int final, void *user) {
+	(void)gui;
+	(void)final;
+	(void)user;
+}
+
+static void event(de2gui_handle gui, void *user) {
+	(void)gui;
+	(void)user;
+}
+
+int main(void) {
+	de2gui_handle gui = 0;
+
+	if (de2gui_api_version() != DE2GUI_API_VERSION) {
+		printf("de2gui_api_version() = %d, expected %d\n", de2gui_api_version(), DE2GUI_API_VERSION);
+		return 1;
+	}
+
+	if (de2gui_new("no such board") != 0) {
+		printf("de2gui_new() created an unknown board\n");
+		return 1;
+	}
+
+	de2gui_set_ledr(gui, 1);
+	de2gui_set_ledg(gui, 1);
+	de2gui_set_hex(gui, 0, 0x40);
+	de2gui_set_lcd(gui, 0, "hello");
+	de2gui_log(gui, DE2GUI_LOG_INFO, "hello");
+	de2gui_on_tick(gui, tick, NULL);
+	de2gui_on_sw(gui, event, NULL);
+	de2gui_on_key(gui, event, NULL);
+	de2gui_on_reset(gui, event, NULL);
+
+	if (de2gui_sw(gui) != 0 || de2gui_key(gui) != 0 || de2gui_tick(gui) != 0) {
+		printf("an invalid handle has inputs\n");
+		return 1;
+	}
+
+	if (de2gui_run(gui, "harness") != 0) {
+		printf("de2gui_run() failed with an invalid handle\n");
+		return 1;
+	}
+
+	de2gui_free(gui);
+
+	printf("ok\n");
+	return 0;
+}
@@ -0,0 +1,166 @@
+package de2gui
+
+import (
+	"fmt"
+	"math/rand"
+	"strconv"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/widget"
+)
+
+// bouncer tracks contact bounce on a group of inputs, such as the switches.
+// While an input is bouncing, the value seen by the design is the settled
+// value with some of the bouncing bits inverted.
+type bouncer struct {
+	// last is the most recent value of the inputs, which is where they
+	// will eventually settle
+	last uint32
+
+	// mask contains the bits which are currently inverted from last
+	mask uint32
+
+	// bouncing contains the bits which have not yet settled
+	bouncing uint32
+
+	// gen is incremented whenever a bounce starts or is canceled, so that
+	// stale futures can tell that they should do nothing
+	gen uint64
+}
+
+// cancel stops any bounce in progress, and settles the inputs at val
+func (b *bouncer) cancel(val uint32) {
+	b.gen++
+	b.last = val
+	b.mask = 0
+	b.bouncing = 0
+}
+
+// MaxBounce is the largest number of ticks for which inputs may bounce, see
+// SetBounce().
+const MaxBounce = 1000000
+
+// SetBounce enables contact bounce emulation for the switches and KEYs.
+// Whenever a SW or KEY changes, each input which changed will toggle
+// pseudo-randomly on every tick for the given number of ticks, before
+// settling at its new value. The bouncing is visible to the design through
+// SW() and KEY(), and OnSW and OnKEY are run whenever the value seen by the
+// design changes. The switch widgets always show the position the user
+// chose, while the KEY widgets show KEY(), including any bouncing.
+//
+// Passing 0 disables bounce emulation, which is the default. This only
+// affects subsequent changes, any bounce already in progress continues. An
+// error is returned, and the setting is left unchanged, if ticks is more
+// than MaxBounce.
+func (s *UIState) SetBounce(ticks uint64) error {
+	if ticks > MaxBounce {
+		return fmt.Errorf("inputs cannot bounce for more than %d ticks", MaxBounce)
+	}
+
+	s.bounceTicks = ticks
+
+	if !s.headless {
+		text := strconv.FormatUint(ticks, 10)
+		if s.bounceEntry.Text != text {
+			s.bounceEntry.SetText(text)
+		}
+	}
+
+	return nil
+}
+
+// Bounce returns the number of ticks for which inputs bounce, see
+// SetBounce().
+func (s *UIState) Bounce() uint64 {
+	return s.bounceTicks
+}
+
+// SetBounceSeed seeds the pseudo-random number generator used to emulate
+// contact bounce. The generator is seeded with 1 by default, so that
+// scripted simulations always bounce in the same way.
+func (s *UIState) SetBounceSeed(seed int64) {
+	s.bounceRand = rand.New(rand.NewSource(seed))
+}
+
+// Internal function which is called with the new value of a group of
+// inputs whenever they change. If bounce emulation is enabled, a future is
+// scheduled which makes the changed bits bounce on each tick, rescheduling
+// itself until they settle, and runs update whenever the value seen by the
+// design changes.
+func (s *UIState) startBounce(b *bouncer, val uint32, update func()) {
+	changed := b.last ^ val
+	b.last = val
+	if changed == 0 || s.bounceTicks == 0 {
+		return
+	}
+
+	if s.bounceRand == nil {
+		s.SetBounceSeed(1)
+	}
+
+	// the contacts which just changed start out closed, and bounce on
+	// the following ticks
+	b.bouncing |= changed
+	b.mask &^= changed
+	b.gen++
+	gen := b.gen
+	settle := s.Tick + s.bounceTicks
+
+	var bounce func(*UIState)
+	bounce = func(*UIState) {
+		if b.gen != gen {
+			return
+		}
+
+		if s.Tick >= settle {
+			b.bouncing = 0
+			if b.mask != 0 {
+				b.mask = 0
+				update()
+			}
+			return
+		}
+
+		mask := uint32(s.bounceRand.Int63()) & b.bouncing
+		if mask != b.mask {
+			b.mask = mask
+			update()
+		}
+		s.ScheduleFuture(s.Tick+1, bounce)
+	}
+
+	s.ScheduleFuture(s.Tick+1, bounce)
+}
+
+// Internal function which creates the bounce emulation controls
+func (s *UIState) newBounceControls() fyne.CanvasObject {
+	s.bounceEntry.SetText("0")
+	s.bounceEntry.Validator = func(str string) error {
+		_, err := parseBounce(str)
+		return err
+	}
+	s.bounceEntry.OnChanged = func(str string) {
+		if n, err := parseBounce(str); err == nil {
+			s.bounceTicks = n
+		}
+	}
+
+	return container.NewHBox(
+		widget.NewLabel("Bounce:"),
+		s.bounceEntry,
+		widget.NewLabel("ticks"),
+	)
+}
+
+// parseBounce parses the number of ticks in the Bounce entry
+func parseBounce(str string) (uint64, error) {
+	n, err := strconv.ParseUint(str, 10, 64)
+	if err != nil {
+		return 0, err
+	}
+	if n > MaxBounce {
+		return 0, fmt.Errorf("inputs cannot bounce for more than %d ticks", MaxBounce)
+	}
+	return n, nil
+}
@@ -0,0 +1,87 @@
+package de2gui
+
+import (
+	"sync"
+	"testing"
+)
+
+// newBounceBoard returns a headless board with a design which counts ticks,
+// and the number of times OnSW has run
+func newBounceBoard() (*UIState, *int) {
+	s := NewHeadlessUIState()
+	s.OnTick = func(s *UIState, final bool) { s.Tick++ }
+
+	calls := 0
+	s.OnSW = func(s *UIState) { calls++ }
+
+	return s, &calls
+}
+
+func TestBounceSettles(t *testing.T) {
+	s, calls := newBounceBoard()
+	if err := s.SetBounce(20); err != nil {
+		t.Fatal(err)
+	}
+
+	s.SetSW(1)
+	s.RunTicks(10)
+	bouncing := *calls
+
+	s.RunTicks(15)
+	if s.SW() != 1 {
+		t.Errorf("SW() = %d after the bounce, expected 1", s.SW())
+	}
+	if *calls < 2 {
+		t.Errorf("OnSW ran %d times, expected the switch to bounce", *calls)
+	}
+
+	// nothing changes once the switch has settled
+	settled := *calls
+	s.RunTicks(100)
+	if *calls != settled {
+		t.Errorf("OnSW ran %d more times after the switch settled", *calls-settled)
+	}
+	if bouncing == settled {
+		t.Errorf("OnSW did not run after tick 10, expected bouncing to continue until tick 20")
+	}
+}
+
+func TestBounceLimit(t *testing.T) {
+	s, _ := newBounceBoard()
+
+	if err := s.SetBounce(MaxBounce + 1); err == nil {
+		t.Errorf("SetBounce(%d) succeeded, expected an error", MaxBounce+1)
+	}
+	if s.Bounce() != 0 {
+		t.Errorf("Bounce() = %d after a rejected SetBounce(), expected 0", s.Bounce())
+	}
+
+	// a long bounce costs nothing until it is ticked
+	if err := s.SetBounce(MaxBounce); err != nil {
+		t.Fatal(err)
+	}
+	s.SetSW(1)
+	if n := len(s.futures); n != 1 {
+		t.Errorf("%d futures scheduled, expected 1", n)
+	}
+
+	if _, err := s.ExecLine("bounce 2000000"); err == nil {
+		t.Errorf("bounce 2000000 succeeded, expected an error")
+	}
+}
+
+func TestScheduleFutureWhileTicking(t *testing.T) {
+	s, _ := newBounceBoard()
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 1000; i++ {
+			s.ScheduleFuture(uint64(i%10), func(*UIState) {})
+		}
+	}()
+
+	s.RunTicks(1000)
+	wg.Wait()
+}
@@ -0,0 +1,230 @@
+package de2gui
+
+import (
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/widget"
+)
+
+// history holds the commands which have been run successfully from the
+// command line, and the position of the one being shown in the entry
+type history struct {
+	// mutex guards the fields below, since commands are added by the
+	// goroutine which runs them
+	mutex sync.Mutex
+	lines []string
+
+	// pos is the position in lines of the command being shown, or
+	// len(lines) for a new command
+	pos int
+}
+
+// add adds a command to the end of the history, and moves to a new command
+func (h *history) add(line string) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	h.lines = append(h.lines, line)
+	h.pos = len(h.lines)
+}
+
+// older moves to the command before the one being shown, and returns it. It
+// returns false if there is none.
+func (h *history) older() (string, bool) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	if h.pos == 0 {
+		return "", false
+	}
+
+	h.pos--
+	return h.lines[h.pos], true
+}
+
+// newer moves to the command after the one being shown, and returns it, or
+// an empty string for a new command. It returns false if a new command is
+// already being shown.
+func (h *history) newer() (string, bool) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	if h.pos == len(h.lines) {
+		return "", false
+	}
+
+	h.pos++
+	if h.pos == len(h.lines) {
+		return "", true
+	}
+	return h.lines[h.pos], true
+}
+
+// all returns the commands in the history, oldest first
+func (h *history) all() []string {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	return append([]string{}, h.lines...)
+}
+
+// commandEntry is the entry of the command line, which steps through the
+// command history with the up and down arrow keys
+type commandEntry struct {
+	widget.Entry
+
+	s *UIState
+}
+
+// newCommandEntry creates the entry of the command line
+func newCommandEntry(s *UIState) *commandEntry {
+	e := &commandEntry{s: s}
+	e.ExtendBaseWidget(e)
+	e.SetPlaceHolder("tick 100, sw 0x2a, key 0 hold 30, run until ledr!=0, expect hex0 3...")
+	return e
+}
+
+// TypedKey implements fyne.Focusable
+func (e *commandEntry) TypedKey(key *fyne.KeyEvent) {
+	var text string
+	var ok bool
+
+	switch key.Name {
+	case fyne.KeyUp:
+		text, ok = e.s.commandHistory.older()
+	case fyne.KeyDown:
+		text, ok = e.s.commandHistory.newer()
+	default:
+		e.Entry.TypedKey(key)
+		return
+	}
+
+	if ok {
+		e.SetText(text)
+		e.CursorColumn = len(e.Text)
+	}
+}
+
+// Internal function which creates the command line, where the commands of
+// the script format can be typed, see the script package
+func (s *UIState) newCommandLine() fyne.CanvasObject {
+	s.commandEntry = newCommandEntry(s)
+	s.commandEntry.OnSubmitted = func(line string) { s.runCommand(line) }
+
+	s.commandRun = widget.NewButton("Run", func() { s.runCommand(s.commandEntry.Text) })
+	s.commandCancel = widget.NewButton("Cancel", func() { s.CancelCommand() })
+	s.commandCancel.Disable()
+
+	return container.NewBorder(nil, nil,
+		widget.NewLabel("Command:"),
+		container.NewHBox(
+			s.commandRun,
+			s.commandCancel,
+			widget.NewButton("Copy as Script", func() { s.copyCommands() }),
+		),
+		s.commandEntry,
+	)
+}
+
+// Internal function wired into the command line, which runs a command in
+// another goroutine, so that the window keeps responding while a long
+// command such as "run until" runs. The command line is disabled until the
+// command finishes, and the Cancel button stops it, see CancelCommand(). A
+// command which succeeds is cleared from the entry.
+func (s *UIState) runCommand(line string) {
+	line = strings.TrimSpace(line)
+	if line == "" || !atomic.CompareAndSwapInt32(&s.commandRunning, 0, 1) {
+		return
+	}
+
+	s.showCommandRunning(true)
+
+	go func() {
+		ok := s.execCommand(line)
+		atomic.StoreInt32(&s.commandRunning, 0)
+
+		s.showCommandRunning(false)
+		if ok {
+			s.commandEntry.SetText("")
+		}
+	}()
+}
+
+// Internal function which runs a command typed into the command line,
+// returning false if it failed. The command and its outcome are logged, and
+// commands which succeed are added to the history, see CommandHistory().
+func (s *UIState) execCommand(line string) bool {
+	// show the outcome of the command
+	s.log.show()
+	s.Logf(LevelInfo, "> %s", line)
+
+	count := s.Errors()
+	res, err := s.ExecLine(line)
+	if err == nil {
+		err = s.errorSince(count)
+	}
+
+	if err != nil {
+		// leave the command to be corrected
+		s.Logf(LevelError, "%v", err)
+		return false
+	}
+
+	s.commandHistory.add(line)
+
+	switch {
+	case res == nil:
+	case res.Pass:
+		s.Logf(LevelInfo, "pass: %s is %s", res.Target, res.Observed)
+	default:
+		s.Logf(LevelWarning, "FAIL: expected %s to be %s, observed %s", res.Target, res.Expected, res.Observed)
+	}
+
+	return true
+}
+
+// Internal function which disables the command line while a command runs,
+// leaving only the Cancel button enabled
+func (s *UIState) showCommandRunning(running bool) {
+	if s.headless {
+		return
+	}
+
+	if running {
+		s.commandEntry.Disable()
+		s.commandRun.Disable()
+		s.commandCancel.Enable()
+	} else {
+		s.commandEntry.Enable()
+		s.commandRun.Enable()
+		s.commandCancel.Disable()
+	}
+}
+
+// CommandHistory returns the commands which have been run successfully from
+// the command line, oldest first. Since they use the script format, they can
+// be saved as a script, see the script package.
+func (s *UIState) CommandHistory() []string {
+	return s.commandHistory.all()
+}
+
+// Internal function wired into the "Copy as Script" button, which copies the
+// command history to the clipboard
+func (s *UIState) copyCommands() {
+	w := s.window()
+	if w == nil {
+		return
+	}
+
+	commands := s.CommandHistory()
+	text := strings.Join(commands, "\n")
+	if text != "" {
+		text += "\n"
+	}
+	w.Clipboard().SetContent(text)
+	s.Logf(LevelInfo, "copied %d commands to the clipboard", len(commands))
+}
@@ -0,0 +1,197 @@
+package de2gui
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestHistory(t *testing.T) {
+	h := &history{}
+	if _, ok := h.older(); ok {
+		t.Errorf("older() succeeded with no history")
+	}
+
+	h.add("tick 1")
+	h.add("tick 2")
+
+	steps := []struct {
+		older bool
+		text  string
+		ok    bool
+	}{
+		{true, "tick 2", true},
+		{true, "tick 1", true},
+		{true, "", false},
+		{false, "tick 2", true},
+		{false, "", true},
+		{false, "", false},
+		{true, "tick 2", true},
+	}
+
+	for i, step := range steps {
+		var text string
+		var ok bool
+		if step.older {
+			text, ok = h.older()
+		} else {
+			text, ok = h.newer()
+		}
+
+		if text != step.text || ok != step.ok {
+			t.Errorf("step %d: got %q, %v, expected %q, %v", i, text, ok, step.text, step.ok)
+		}
+	}
+
+	// adding a command moves back to a new command
+	h.add("tick 3")
+	if text, _ := h.older(); text != "tick 3" {
+		t.Errorf("older() = %q after add(), expected the new command", text)
+	}
+}
+
+func TestExecCommand(t *testing.T) {
+	s := newCounterBoard()
+
+	tests := []struct {
+		line string
+		ok   bool
+		log  string
+	}{
+		{"tick 5", true, "[0] info: > tick 5"},
+		{"expect ledr 5", true, "[5] info: pass: ledr is 0x00005"},
+		{"expect ledr 4", true, "[5] warning: FAIL: expected ledr to be 0x00004, observed 0x00005"},
+		{"tick x", false, "[5] error: tick: invalid number 'x'"},
+		{"sw 17 on", true, "[5] info: > sw 17 on"},
+		{"tick 1", false, "[5] error: OnTick panicked at tick 5: SW17 is on"},
+	}
+
+	for _, test := range tests {
+		if ok := s.execCommand(test.line); ok != test.ok {
+			t.Errorf("execCommand(%q) = %v, expected %v", test.line, ok, test.ok)
+		}
+
+		log := s.Log()
+		if last := log[len(log)-1].String(); last != test.log {
+			t.Errorf("execCommand(%q) logged %q, expected %q", test.line, last, test.log)
+		}
+	}
+
+	// only the commands which succeeded are kept
+	want := []string{"tick 5", "expect ledr 5", "expect ledr 4", "sw 17 on"}
+	if got := s.CommandHistory(); !reflect.DeepEqual(got, want) {
+		t.Errorf("CommandHistory() = %q, expected %q", got, want)
+	}
+}
+
+func TestCancelCommand(t *testing.T) {
+	s := newCounterBoard()
+
+	// the design stops at tick 5 until the command has been canceled
+	started := make(chan bool)
+	release := make(chan bool)
+	onTick := s.OnTick
+	s.OnTick = func(s *UIState, final bool) {
+		if s.Tick == 5 {
+			close(started)
+			<-release
+		}
+		onTick(s, final)
+	}
+
+	done := make(chan error)
+	go func() {
+		_, err := s.ExecLine("tick 1000000")
+		done <- err
+	}()
+
+	<-started
+	s.CancelCommand()
+	close(release)
+
+	if err := <-done; err != errCanceled {
+		t.Fatalf("ExecLine() = %v, expected it to be canceled", err)
+	}
+
+	// the group of ticks being run is finished
+	if s.Tick != CommandTickGroup {
+		t.Errorf("Tick = %d after canceling, expected %d", s.Tick, CommandTickGroup)
+	}
+
+	// the next command is not canceled
+	if _, err := s.ExecLine("run until ledr == 0 limit 100"); err == nil || !strings.Contains(err.Error(), "within 100 ticks") {
+		t.Errorf("ExecLine() = %v, expected the condition not to hold", err)
+	}
+	if s.Tick != CommandTickGroup+100 {
+		t.Errorf("Tick = %d, expected %d", s.Tick, CommandTickGroup+100)
+	}
+}
+
+// TestRunUntilWhileTicking runs a command while another goroutine ticks, as
+// auto-ticking does. Run with -race.
+func TestRunUntilWhileTicking(t *testing.T) {
+	s := newCounterBoard()
+
+	stop := make(chan bool)
+	stopped := make(chan bool)
+	go func() {
+		defer close(stopped)
+		for {
+			select {
+			case <-stop:
+				return
+			default:
+				s.RunTicks(1)
+			}
+		}
+	}()
+
+	_, err := s.ExecLine("run until ledr >= 500")
+	close(stop)
+	<-stopped
+
+	if err != nil {
+		t.Errorf("ExecLine() = %v", err)
+	}
+}
+
+func TestCommandLine(t *testing.T) {
+	s := newWindowedUIState()
+	defer s.Close()
+
+	// the design stops at tick 5 until it is released
+	started := make(chan bool)
+	release := make(chan bool)
+	s.OnTick = func(s *UIState, final bool) {
+		if s.Tick == 5 {
+			close(started)
+			<-release
+		}
+		s.Tick++
+	}
+
+	// the command line is disabled while the command runs, leaving the
+	// Cancel button
+	s.runCommand("tick 1000000")
+	<-started
+	if !s.commandEntry.Disabled() || !s.commandRun.Disabled() || s.commandCancel.Disabled() {
+		t.Errorf("while running, the entry is disabled: %v, Run is disabled: %v, and Cancel is disabled: %v",
+			s.commandEntry.Disabled(), s.commandRun.Disabled(), s.commandCancel.Disabled())
+	}
+
+	// a second command is not run at the same time
+	s.runCommand("tick 1")
+
+	s.CancelCommand()
+	close(release)
+	// the Cancel button is disabled after the rest of the command line
+	// is enabled
+	waitFor(t, "the Cancel button to be disabled", s.commandCancel.Disabled)
+	if s.commandEntry.Disabled() || s.commandRun.Disabled() {
+		t.Errorf("after the command, the entry is disabled: %v, and Run is disabled: %v", s.commandEntry.Disabled(), s.commandRun.Disabled())
+	}
+
+	if s.currentTick() != CommandTickGroup || len(s.CommandHistory()) != 0 {
+		t.Errorf("Tick = %d and the history is %q after canceling", s.currentTick(), s.CommandHistory())
+	}
+}
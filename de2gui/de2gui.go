@@ -1,22 +1,33 @@
 // Package de2gui contains code for providing a graphical facsimile of the
-// Terasic DE2-115 development board.
+// Terasic DE2-115 development board, and of similar boards, see
+// BoardProfile.
 package de2gui
 
 import (
 	"fmt"
 	"image/color"
+	"io"
 	"math/rand"
-	"os"
+	"sort"
 	"strconv"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/canvas"
 	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/theme"
 	"fyne.io/fyne/v2/widget"
 
+	"github.com/herclab/de2gui/de2gui/layout"
+	"github.com/herclab/de2gui/de2gui/script"
+	"github.com/herclab/de2gui/de2gui/session"
 	"github.com/herclab/de2gui/de2gui/widgets/hexwidget"
+	"github.com/herclab/de2gui/de2gui/widgets/keywidget"
+	"github.com/herclab/de2gui/de2gui/widgets/lcdwidget"
 	"github.com/herclab/de2gui/de2gui/widgets/ledwidget"
+	"github.com/herclab/de2gui/de2gui/widgets/switchwidget"
 )
 
 // UIState contains all of the GUI widgets, and the data needed to interact
@@ -36,10 +47,54 @@ import (
 // scheduled time to run and a Tick occurs. Futures run before OnTick is
 // called.
 type UIState struct {
+	// loggedTick is the value of Tick used to stamp log messages, see
+	// Logf(). It is accessed atomically, and is first so that it is
+	// aligned for atomic access on 32-bit platforms.
+	loggedTick uint64
+
 	// state storage
 	key     uint32
+	keyGen  []uint64
+	sw      uint32
+	ledr    uint32
+	ledg    uint32
+	hex     []uint8
+	lcd     [lcdwidget.Rows]string
 	futures map[uint64][]func(*UIState)
 
+	// futureMutex guards futures, since futures may be scheduled by
+	// widgets while the auto tick goroutine is running them
+	futureMutex sync.Mutex
+
+	// the board being shown, see NewUIStateFor()
+	profile BoardProfile
+
+	// headless is true if this UIState was created without any widgets,
+	// see NewHeadlessUIState()
+	headless bool
+
+	// session recording, see StartRecording(), guarded by recordMutex
+	// since changes may be recorded by several goroutines
+	session     *session.Session
+	recording   bool
+	recordMutex sync.Mutex
+
+	// contact bounce emulation, see SetBounce()
+	bounceTicks uint64
+	bounceRand  *rand.Rand
+	swBounce    bouncer
+	keyBounce   bouncer
+
+	// injected faults, see SetFaults() and GlitchSW(). glitchEnding is
+	// true while the end of the glitches is scheduled.
+	faults       Faults
+	swGlitch     uint32
+	keyGlitch    uint32
+	glitchEnding bool
+
+	// results of the most recently loaded test vectors
+	vectorReport *script.Report
+
 	// widgets
 	ledrWidget   *ledwidget.LedWidget
 	ledrLabel    *widget.Label
@@ -48,11 +103,60 @@ type UIState struct {
 	hexWidgets   []*hexwidget.HexWidget
 	regLabels    []*widget.Label
 	cycleLabel   *widget.Label
-	switchChecks []*widget.Check
+	switchWidget *switchwidget.SwitchWidget
 	switchLabel  *widget.Label
 	tickEntry    *widget.Entry
 	tickEntryVal int
 
+	vectorPeriodEntry *widget.Entry
+	vectorStatus      *canvas.Text
+
+	bounceEntry *widget.Entry
+	faultLabel  *widget.Label
+
+	lcdWidget *lcdwidget.LcdWidget
+
+	autoTickCheck *widget.Check
+
+	// messages shown in the log panel, see Logf()
+	log boardLog
+
+	// snapshots saved with SaveSnapshot()
+	snapshots map[string]*Snapshot
+
+	// the command line, see newCommandLine()
+	commandEntry   *commandEntry
+	commandRun     *widget.Button
+	commandCancel  *widget.Button
+	commandHistory history
+
+	// commandRunning is 1 while a command typed into the command line is
+	// running, and execRunning counts the commands being run by Exec() and
+	// the like, which are stopped by CancelCommand() setting execCanceled.
+	// They are accessed atomically.
+	commandRunning int32
+	execRunning    int32
+	execCanceled   int32
+
+	// callback panics, see OnError, guarded by errorMutex since callbacks
+	// run in several goroutines
+	errorCount int
+	lastError  *CallbackError
+	errorMutex sync.Mutex
+	errorPanel *fyne.Container
+	errorLabel *widget.Label
+	errorStack *widget.Label
+
+	keyWidgets     []*keywidget.KeyWidget
+	keyLatchChecks []*widget.Check
+	keyHoldCheck   *widget.Check
+
+	// KEY press-and-hold mode, see SetKeyHoldMode() and SetKeyLatch().
+	// holdTicking is true while ticking because a KEY is held down.
+	keyHoldMode bool
+	keyLatch    []bool
+	holdTicking bool
+
 	// We may want to have multiple goroutines calling tick(), for example
 	// when we are auto-ticking.
 	tickMutex sync.Mutex
@@ -62,6 +166,12 @@ type UIState struct {
 	// from ticking at all.
 	tickChannel chan uint
 
+	// closeChannel is closed by Close() to stop the auto-ticking
+	// goroutine, which closes tickDone when it returns
+	closeChannel chan struct{}
+	tickDone     chan struct{}
+	closeOnce    sync.Once
+
 	widgetTree fyne.CanvasObject
 
 	// The Tick value is displayed to the user as the current tick #, and
@@ -85,12 +195,55 @@ type UIState struct {
 
 	// OnReset is run when the reset button is used
 	OnReset func(*UIState)
+
+	// OnSnapshot is run when a snapshot is saved, see SaveSnapshot(), and
+	// returns the state of the design, which must not change as the
+	// simulation continues. It may be nil if the design cannot be saved.
+	OnSnapshot func(*UIState) interface{}
+
+	// OnRestore is run when a snapshot is loaded, see LoadSnapshot(), with
+	// the state of the design returned by OnSnapshot. It must not cause
+	// ticks.
+	OnRestore func(*UIState, interface{})
+
+	// OnError is run when one of the callbacks above, or a function
+	// scheduled with ScheduleFuture(), panics, with a *CallbackError
+	// describing the panic. The panic is recovered, auto-ticking is
+	// stopped, and any remaining ticks in the group being run are skipped.
+	// If OnError is nil, the panic is shown in an error panel at the top
+	// of the window. Either way, the panic is logged, see ErrorLog, and
+	// counted, see Errors(). Since ticking carries on from where it
+	// stopped, code which drives a headless UIState, such as a test,
+	// should check Errors() or set OnError, rather than assume that every
+	// tick ran. The boards of the de2guitest package do this for you.
+	OnError func(*UIState, error)
+
+	// TopModule is the name of the design's top level Verilog module. It
+	// is used when exporting a recorded session as a testbench, and
+	// defaults to "top" if empty.
+	TopModule string
+
+	// VectorLog is where mismatches found while applying test vectors are
+	// logged, see LoadVectors(). If it is nil, they are added to the log,
+	// see Logf().
+	VectorLog io.Writer
+
+	// SimulatorLog is where messages from a simulator connected with
+	// ConnectSimulator() are logged. If it is nil, they are added to the
+	// log, see Logf().
+	SimulatorLog io.Writer
+
+	// ErrorLog is where panics in callbacks are logged, see OnError, as a
+	// line of key=value pairs followed by the stack trace. If it is nil,
+	// they are added to the log, see Logf().
+	ErrorLog io.Writer
+
+	// LogOutput is where messages added to the log are also written, see
+	// Logf(). If it is nil, they are only shown in the log panel, or
+	// written to os.Stderr for a headless UIState.
+	LogOutput io.Writer
 }
 
-const numHex int = 8
-const numRedLeds int = 18
-const numGreenLeds int = 9
-const numSwitches int = 18
 const tickChannelBufsz int = 10
 const autoTickInterval uint = 200 // 5Hz
 
@@ -118,124 +271,191 @@ var KeyPushMinTime uint64 = 10
 // it is pushed
 var KeyPushMaxTime uint64 = 250
 
+// NewHeadlessUIState initializes a new instance of the DE2GUI's state object
+// without creating any widgets. This is useful for running simulations and
+// test scripts (see RunScript()) on machines with no display. The callbacks
+// behave exactly as they do for a UIState created with NewUIState(), however
+// there is no auto-ticking, and FyneObject() will return nil.
+func NewHeadlessUIState() *UIState {
+	return NewHeadlessUIStateFor(ProfileDE2115)
+}
+
+// NewHeadlessUIStateFor is like NewHeadlessUIState(), but for the board
+// described by the given profile. It panics if the profile is not valid.
+func NewHeadlessUIStateFor(p BoardProfile) *UIState {
+	if err := p.Validate(); err != nil {
+		panic(err)
+	}
+
+	s := &UIState{
+		futures:  make(map[uint64][]func(*UIState)),
+		headless: true,
+		profile:  p,
+		keyGen:   make([]uint64, p.KEY),
+		keyLatch: make([]bool, p.KEY),
+		hex:      make([]uint8, p.HEX),
+	}
+
+	for i := range s.hex {
+		s.hex[i] = 0xff // remember they are active low
+	}
+
+	return s
+}
+
 // NewUIState initializes a new instance of the DE2GUI's state object along
-// with all of the needed widgets. After calling this, FyneObject() can
-// safely be called.
-//
-// EtraWidgets, if non-nil, will be inserted into the left panel of the
-// created GUI elements.
+// with all of the needed widgets, showing a DE2-115. After calling this,
+// FyneObject() can safely be called.
 func NewUIState() *UIState {
-	s := &UIState{
-		futures:      make(map[uint64][]func(*UIState)),
-		ledrWidget:   ledwidget.NewLedWidget(numRedLeds, ColorRedActive, ColorRedInactive),
-		ledrLabel:    widget.NewLabelWithStyle("(0x00000)", fyne.TextAlignLeading, fyne.TextStyle{false, false, true}),
-		ledgWidget:   ledwidget.NewLedWidget(numGreenLeds, ColorGreenActive, ColorGreenInactive),
-		ledgLabel:    widget.NewLabelWithStyle("(0x000)", fyne.TextAlignLeading, fyne.TextStyle{false, false, true}),
-		hexWidgets:   make([]*hexwidget.HexWidget, numHex),
-		cycleLabel:   widget.NewLabel("cycle# --"),
-		switchChecks: make([]*widget.Check, numSwitches),
-		switchLabel:  widget.NewLabelWithStyle("(0x00000)", fyne.TextAlignLeading, fyne.TextStyle{false, false, true}),
-		tickEntry:    widget.NewEntry(),
-		tickChannel:  make(chan uint, tickChannelBufsz),
-	}
-
-	// Create the HEX widgets and initialize them to completely off.
-	for i := 0; i < numHex; i++ {
-		s.hexWidgets[i] = hexwidget.NewHexWidget()
-		s.hexWidgets[i].Update(0xff) // remember they are active low
+	return NewUIStateFor(ProfileDE2115)
+}
+
+// NewUIStateFor is like NewUIState(), but shows the board described by the
+// given profile, see Profiles for the built-in ones. Groups of LEDs which
+// the board does not have are left out. It panics if the profile is not
+// valid.
+func NewUIStateFor(p BoardProfile) *UIState {
+	return newUIState(p, defaultLayout(p))
+}
+
+// Internal function which creates a UIState for the board described by p,
+// with its widgets arranged according to l
+func newUIState(p BoardProfile, l *layout.Layout) *UIState {
+	s := NewHeadlessUIStateFor(p)
+	s.headless = false
+
+	ledrActive, ledrInactive := ledColors(l, "ledr", ColorRedActive, ColorRedInactive)
+	ledgActive, ledgInactive := ledColors(l, "ledg", ColorGreenActive, ColorGreenInactive)
+
+	s.ledrWidget = ledwidget.NewLedWidget(p.LEDR, ledrActive, ledrInactive)
+	s.ledrLabel = widget.NewLabelWithStyle("("+formatBits(0, p.LEDR)+")", fyne.TextAlignLeading, fyne.TextStyle{false, false, true})
+	s.ledgWidget = ledwidget.NewLedWidget(p.LEDG, ledgActive, ledgInactive)
+	s.ledgLabel = widget.NewLabelWithStyle("("+formatBits(0, p.LEDG)+")", fyne.TextAlignLeading, fyne.TextStyle{false, false, true})
+	s.hexWidgets = make([]*hexwidget.HexWidget, p.HEX)
+	s.cycleLabel = widget.NewLabel("cycle# --")
+	s.switchWidget = switchwidget.NewSwitchWidget(p.SW)
+	s.switchLabel = widget.NewLabelWithStyle("("+formatBits(0, p.SW)+")", fyne.TextAlignLeading, fyne.TextStyle{false, false, true})
+	s.lcdWidget = lcdwidget.NewLcdWidget()
+
+	if pitch := layoutPitch(l, "ledr"); pitch != 0 {
+		s.ledrWidget.SetPitch(pitch)
+	}
+	if pitch := layoutPitch(l, "ledg"); pitch != 0 {
+		s.ledgWidget.SetPitch(pitch)
+	}
+	if pitch := layoutPitch(l, "sw"); pitch != 0 {
+		s.switchWidget.SetPitch(pitch)
 	}
+	s.tickEntry = widget.NewEntry()
+	s.tickChannel = make(chan uint, tickChannelBufsz)
+	s.closeChannel = make(chan struct{})
+	s.tickDone = make(chan struct{})
+
+	s.vectorPeriodEntry = widget.NewEntry()
+	s.vectorStatus = canvas.NewText("", theme.ForegroundColor())
+
+	s.bounceEntry = widget.NewEntry()
+	s.faultLabel = widget.NewLabel("")
 
-	// now we will set up a container to store the checkboxes used
-	// as switches, and initialize the checks themselves
-	checkcontainer := container.NewHBox(widget.NewLabel("SW:"))
-	for i := 0; i < numSwitches; i++ {
-		s.switchChecks[i] = widget.NewCheck("", func(dummy bool) { s.switchUpdate() })
-		checkcontainer.Objects = append(checkcontainer.Objects, s.switchChecks[i])
+	s.keyWidgets = make([]*keywidget.KeyWidget, p.KEY)
+	s.keyLatchChecks = make([]*widget.Check, p.KEY)
+
+	s.vectorPeriodEntry.SetText(strconv.FormatUint(DefaultVectorPeriod, 10))
+
+	// Create the HEX widgets and initialize them to completely off. HEX0
+	// is the rightmost.
+	hexRow := container.NewHBox()
+	for i := p.HEX - 1; i >= 0; i-- {
+		s.hexWidgets[i] = hexwidget.NewHexWidget()
+		s.hexWidgets[i].Update(s.hex[i])
+		hexRow.Objects = append(hexRow.Objects, s.hexWidgets[i])
 	}
 
-	// setup s.tickEntryVal to update when the entry is changed
+	s.switchWidget.OnChanged = func(uint32) { s.switchUpdate() }
+
+	// setup s.tickEntryVal to update when the entry is changed, and mark
+	// the entry as invalid if it does not contain a number of ticks
+	s.tickEntry.Validator = func(str string) error {
+		_, err := parseTickCount(str)
+		return err
+	}
 	s.tickEntry.OnChanged = func(str string) {
-		n, err := strconv.Atoi(str)
+		n, err := parseTickCount(str)
 		if err != nil {
-			fmt.Fprintf(os.Stderr, "Invalid tick entry value '%s': %v\n", str, err)
+			s.Logf(LevelWarning, "invalid tick entry value '%s': %v", str, err)
 			s.tickEntryVal = 0
 		} else {
 			s.tickEntryVal = n
 		}
 	}
 
-	// now we create the structure of the window in proper
-	s.widgetTree = container.NewVBox(
-		container.NewHBox(
-			s.hexWidgets[0],
-			s.hexWidgets[1],
-			s.hexWidgets[2],
-			s.hexWidgets[3],
-			s.hexWidgets[4],
-			s.hexWidgets[5],
-			s.hexWidgets[6],
-			s.hexWidgets[7],
-		),
-		container.NewHBox(
-			widget.NewLabel("LEDR:"),
+	// Every part of the board is created, even if the layout does not
+	// show it, so that the rest of the UIState need not care which parts
+	// are shown.
+	parts := map[string]fyne.CanvasObject{
+		"hex": hexRow,
+		"ledr": container.NewHBox(
+			widget.NewLabel(layoutLabel(l, "ledr", "LEDR:")),
 			s.ledrWidget,
 			s.ledrLabel,
 		),
-		container.NewHBox(
-			widget.NewLabel("LEDG:"),
+		"ledg": container.NewHBox(
+			widget.NewLabel(layoutLabel(l, "ledg", "LEDG:")),
 			s.ledgWidget,
 			s.ledgLabel,
 		),
-		checkcontainer,
-		container.NewHBox(
-			widget.NewButton("KEY3", func() { s.pushKey(3) }),
-			widget.NewButton("KEY2", func() { s.pushKey(2) }),
-			widget.NewButton("KEY1", func() { s.pushKey(1) }),
-			widget.NewButton("KEY0", func() { s.pushKey(0) }),
-		),
-		container.NewHBox(
-			s.cycleLabel,
-			widget.NewButton("Tick 1", func() { s.tick(1) }),
-			widget.NewButton("Tick 10", func() { s.tick(10) }),
-			widget.NewButton("Tick 100", func() { s.tick(100) }),
-			widget.NewLabel("n="),
-			s.tickEntry,
-			widget.NewButton("Tick N", func() { s.tick(s.tickEntryVal) }),
-			widget.NewCheck("Auto Tick", func(c bool) {
-				if c {
-					s.tickChannel <- autoTickInterval
-				} else {
-					// stop ticking
-					s.tickChannel <- 0
-				}
-			}),
-			widget.NewButton("Reset", func() {
-				if s.OnReset != nil {
-					s.OnReset(s)
-				}
-			}),
+		"sw": container.NewHBox(
+			widget.NewLabel(layoutLabel(l, "sw", "SW:")),
+			s.switchWidget,
+			s.switchLabel,
 		),
+		"key":     s.newKeyControls(layoutLabel(l, "key", "")),
+		"lcd":     s.lcdWidget,
+		"ticks":   s.newTickControls(),
+		"session": s.newSessionControls(),
+		"vectors": s.newVectorControls(),
+		"bounce":  s.newBounceControls(),
+		"faults":  s.newFaultControls(),
+	}
+
+	s.widgetTree = container.NewBorder(
+		s.newErrorPanel(),
+		container.NewVBox(s.newCommandLine(), s.newLogPanel()),
+		nil, nil,
+		arrange(l, parts),
 	)
 
 	// now we set up a goroutine to handle auto-ticking
 	tickfunc := func() {
+		defer close(s.tickDone)
+
 		interval := uint(0)
 		for {
 			select {
 			case n := <-s.tickChannel:
 				interval = n
+			case <-s.closeChannel:
+				return
 			default:
 
 			}
 
-			if interval == 0 {
-				// let the CPU idle before we poll again
-				time.Sleep(50 * time.Millisecond)
-				continue
+			// if we are not ticking, let the CPU idle before we poll
+			// again
+			delay := 50 * time.Millisecond
+			if interval != 0 {
+				delay = time.Duration(interval) * time.Millisecond
+			}
+
+			select {
+			case <-time.After(delay):
+			case <-s.closeChannel:
+				return
 			}
 
-			time.Sleep(time.Duration(interval) * time.Millisecond)
-			s.tick(1)
+			if interval != 0 {
+				s.tick(1)
+			}
 		}
 	}
 
@@ -244,34 +464,194 @@ func NewUIState() *UIState {
 	return s
 }
 
-// Internal function wired into key presses
-func (s *UIState) pushKey(i int) {
+// Close stops the goroutine which ticks the board while Auto Tick is
+// checked, and waits for it to return, so that no more ticks are run. The
+// widgets must not be used afterwards. A program which exits when its
+// window is closed need not call Close(), but one which creates and
+// discards several boards should. Close does nothing to a headless
+// UIState, and may be called more than once.
+func (s *UIState) Close() {
+	if s.headless {
+		return
+	}
+
+	s.closeOnce.Do(func() { close(s.closeChannel) })
+	<-s.tickDone
+}
+
+// parseTickCount parses the number of ticks in the Tick N entry
+func parseTickCount(str string) (int, error) {
+	n, err := strconv.Atoi(str)
+	if err != nil {
+		return 0, err
+	}
+	if n < 0 {
+		return 0, fmt.Errorf("the number of ticks cannot be negative")
+	}
+	return n, nil
+}
+
+// Internal function which creates the tick and reset controls
+func (s *UIState) newTickControls() fyne.CanvasObject {
+	s.autoTickCheck = widget.NewCheck("Auto Tick", func(c bool) {
+		if c {
+			s.tickChannel <- autoTickInterval
+		} else {
+			// stop ticking
+			s.tickChannel <- 0
+		}
+	})
+
+	return container.NewHBox(
+		s.cycleLabel,
+		widget.NewButton("Tick 1", func() { s.tick(1) }),
+		widget.NewButton("Tick 10", func() { s.tick(10) }),
+		widget.NewButton("Tick 100", func() { s.tick(100) }),
+		widget.NewLabel("n="),
+		s.tickEntry,
+		widget.NewButton("Tick N", func() { s.tick(s.tickEntryVal) }),
+		s.autoTickCheck,
+		widget.NewButton("Reset", func() { s.reset() }),
+	)
+}
+
+// PushKEY presses the i-th KEY for a random number of ticks, see
+// KeyPushMinTime and KeyPushMaxTime, as when the user clicks its button.
+func (s *UIState) PushKEY(i int) {
 	r := uint64(rand.Float64()*float64(KeyPushMaxTime) + float64(KeyPushMinTime))
-	release := s.Tick + r
+	s.PressKEY(i, r)
+}
+
+// PressKEY presses the i-th KEY, and schedules it to be released after the
+// given number of ticks, as if the user had pushed the KEY's button. OnKEY
+// is run both when the KEY is pressed and when it is released. KEYs which
+// do not exist are ignored.
+func (s *UIState) PressKEY(i int, ticks uint64) {
+	if i < 0 || i >= s.profile.KEY {
+		return
+	}
+
+	// if the key is pressed again before it is released, only the most
+	// recent press determines when it is released
+	s.keyGen[i]++
+	gen := s.keyGen[i]
+
+	release := s.Tick + ticks
 	s.ScheduleFuture(release, func(uistate *UIState) {
-		s.releaseKey(i)
+		if s.keyGen[i] == gen {
+			s.ReleaseKEY(i)
+		}
 	})
 
 	s.key |= (1 << i)
+	s.keyUpdate()
+}
 
-	if s.OnKEY != nil {
-		s.OnKEY(s)
+// HoldKEY presses the i-th KEY, and leaves it pressed until ReleaseKEY() is
+// called. Any pending release from an earlier PressKEY() is canceled. OnKEY
+// is run if the KEY was not already pressed. KEYs which do not exist are
+// ignored.
+func (s *UIState) HoldKEY(i int) {
+	if i < 0 || i >= s.profile.KEY {
+		return
 	}
+
+	s.keyGen[i]++
+	if s.key&(1<<i) != 0 {
+		return
+	}
+
+	s.key |= (1 << i)
+	s.keyUpdate()
 }
 
-// Internal function to handle key releases
-func (s *UIState) releaseKey(i int) {
-	s.key &= ^(1 << i)
-	if s.OnKEY != nil {
-		s.OnKEY(s)
+// ReleaseKEY releases the i-th KEY, canceling any pending release from an
+// earlier PressKEY(). OnKEY is run if the KEY was pressed. KEYs which do
+// not exist are ignored.
+func (s *UIState) ReleaseKEY(i int) {
+	if i < 0 || i >= s.profile.KEY {
+		return
+	}
+
+	s.keyGen[i]++
+	if s.key&(1<<i) == 0 {
+		return
 	}
+
+	s.key &= ^(1 << i)
+	s.keyUpdate()
 }
 
 // Internal function wired into switch change callbacks
 func (s *UIState) switchUpdate() {
+	s.sw = s.switchWidget.State()
+	s.switchLabel.SetText("(" + formatBits(s.sw, s.profile.SW) + ")")
+	s.swUpdate()
+}
+
+// Internal function called whenever the state of the switches changes,
+// which starts any bounce, records the change, and runs OnSW
+func (s *UIState) swUpdate() {
+	s.startBounce(&s.swBounce, s.sw, s.swUpdate)
+	s.record("SW", s.SW())
+
 	if s.OnSW != nil {
-		s.OnSW(s)
+		s.call("OnSW", func() { s.OnSW(s) })
+	}
+}
+
+// SetSW changes the switches to the given value, as if the user had flipped
+// them all at once, and runs OnSW. The bits are in the same order as for
+// SW(), and unused higher order bits are ignored.
+func (s *UIState) SetSW(val uint32) {
+	s.sw = val & bitmask(s.profile.SW)
+
+	if !s.headless {
+		s.switchWidget.Update(s.sw)
+		s.switchLabel.SetText("(" + formatBits(s.sw, s.profile.SW) + ")")
+	}
+
+	s.swUpdate()
+}
+
+// SetSWBit turns the i-th switch on or off, and runs OnSW. Switch 0 is the
+// rightmost switch, and corresponds to the least significant bit of SW().
+// Switches which do not exist are ignored.
+func (s *UIState) SetSWBit(i int, on bool) {
+	if i < 0 || i >= s.profile.SW {
+		return
+	}
+
+	if on {
+		s.SetSW(s.sw | (1 << i))
+	} else {
+		s.SetSW(s.sw &^ (1 << i))
+	}
+}
+
+// ToggleSWBit flips the i-th switch, and runs OnSW. Switches which do not
+// exist are ignored.
+func (s *UIState) ToggleSWBit(i int) {
+	if i < 0 || i >= s.profile.SW {
+		return
 	}
+
+	s.SetSW(s.sw ^ (1 << i))
+}
+
+// Internal function wired into the reset button
+func (s *UIState) reset() {
+	s.record("RESET", 0)
+	if s.OnReset != nil {
+		s.call("OnReset", func() { s.OnReset(s) })
+	}
+	s.publishTick()
+}
+
+// RunTicks causes count ticks to occur, exactly as if the user had used the
+// Tick N control.
+func (s *UIState) RunTicks(count int) {
+	s.tick(count)
 }
 
 // Internal function which handles tick events
@@ -284,39 +664,95 @@ func (s *UIState) tick(count int) {
 
 	s.tickMutex.Lock()
 
-	for i := 0; i < count; i++ {
+	// a panic in a callback skips the rest of the ticks
+	ok := true
+
+	for i := 0; i < count && ok; i++ {
+		s.publishTick()
+
 		// handle future that need to run on this tick
-		for k, futurelist := range s.futures {
-			if s.Tick >= k {
-				for _, future := range futurelist {
-					future(s)
-				}
-				delete(s.futures, k)
+		for _, future := range s.dueFutures() {
+			if ok = s.call("future", func() { future(s) }); !ok {
+				break
 			}
 		}
 
+		if !ok {
+			break
+		}
+
+		s.recordTick()
+
 		if s.OnTick != nil {
-			s.OnTick(s, (i+1) >= (count))
+			ok = s.call("OnTick", func() { s.OnTick(s, (i+1) >= (count)) })
 		}
 	}
 
-	s.cycleLabel.SetText(fmt.Sprintf("cycle# %d", s.Tick))
+	s.publishTick()
+	if !s.headless {
+		s.cycleLabel.SetText(fmt.Sprintf("cycle# %d", s.Tick))
+	}
 	s.tickMutex.Unlock()
 }
 
+// Internal function which makes the current value of Tick the one used to
+// stamp log messages. It is called whenever Tick may have changed, by the
+// goroutine which changed it, so that Logf() need not read Tick while
+// another goroutine is ticking.
+func (s *UIState) publishTick() {
+	atomic.StoreUint64(&s.loggedTick, s.Tick)
+}
+
+// Internal function which removes the futures which are due to run on the
+// current tick from the futures map, and returns them, earliest first
+func (s *UIState) dueFutures() []func(*UIState) {
+	s.futureMutex.Lock()
+	defer s.futureMutex.Unlock()
+
+	due := []uint64{}
+	for k := range s.futures {
+		if s.Tick >= k {
+			due = append(due, k)
+		}
+	}
+	sort.Slice(due, func(i, j int) bool { return due[i] < due[j] })
+
+	futures := []func(*UIState){}
+	for _, k := range due {
+		futures = append(futures, s.futures[k]...)
+		delete(s.futures, k)
+	}
+
+	return futures
+}
+
 // ClearFutures removes all functions scheduled to run in the future.  You
 // almost certainly want to call this in your OnRest() method.
 func (s *UIState) ClearFutures() {
+	s.futureMutex.Lock()
 	s.futures = make(map[uint64][]func(*UIState))
+	s.futureMutex.Unlock()
+
+	// any bounce or glitch in progress can no longer settle
+	s.swBounce.cancel(s.sw)
+	s.keyBounce.cancel(s.key)
+	s.swGlitch = 0
+	s.keyGlitch = 0
+	s.glitchEnding = false
 }
 
 // ClearSW resets all switches to the "off" state. You might want to call
 // this in your OnRest() method.
 func (s *UIState) ClearSW() {
-	for i := 0; i < numSwitches; i++ {
-		s.switchChecks[i].Checked = false
-		s.switchChecks[i].Refresh()
+	s.sw = 0
+	s.swBounce.cancel(s.sw)
+	s.record("SW", s.sw)
+	if s.headless {
+		return
 	}
+
+	s.switchWidget.Update(s.sw)
+	s.switchLabel.SetText("(" + formatBits(s.sw, s.profile.SW) + ")")
 }
 
 // ClearKEY "un-presses" all KEYs. If you have called ClearFutures, you
@@ -324,20 +760,29 @@ func (s *UIState) ClearSW() {
 // any pressed keys will now be deleted.
 func (s *UIState) ClearKEY() {
 	s.key = 0
+	s.keyBounce.cancel(s.key)
+	s.refreshKeys()
+	s.record("KEY", s.key)
 }
 
 // FyneObject will return a Fyne canvas object which contains all of the
 // widgets and such relating to this instance of the UIState. This should be
 // suitable for use with Window.SetContent. However for more advanced use
 // cases, it can be embedded in a container as needed.
+//
+// If this UIState was created with NewHeadlessUIState(), nil is returned.
 func (s *UIState) FyneObject() fyne.CanvasObject {
 
 	return s.widgetTree
 }
 
 // ScheduleFuture will cause the provided callback to be executed whenever
-// a tick occurs and s.Tick is at least equal to `when`.
+// a tick occurs and s.Tick is at least equal to `when`. It may be called
+// from any goroutine, including from futures and other callbacks.
 func (s *UIState) ScheduleFuture(when uint64, f func(*UIState)) {
+	s.futureMutex.Lock()
+	defer s.futureMutex.Unlock()
+
 	_, ok := s.futures[when]
 	if !ok {
 		s.futures[when] = make([]func(*UIState), 0)
@@ -361,43 +806,140 @@ func (s *UIState) ScheduleFuture(when uint64, f func(*UIState)) {
 //     -----
 //
 // Segments are packed into a uint8 as shown in the above diagram. Segments
-// are active-low.
+// are active-low. Displays which the board does not have are ignored.
 func (s *UIState) SetHEX(i int, state uint8) {
-	s.hexWidgets[i%numHex].Update(state)
+	if i < 0 || i >= s.profile.HEX {
+		return
+	}
+
+	if s.hex[i] != state {
+		s.record(fmt.Sprintf("HEX%d", i), uint32(state))
+	}
+	s.hex[i] = state
+	s.showHEX(i)
 }
 
-// SetLEDR sets the LEDR display. There are 18 red LEDs. The least significant
-// bit codes for the rightmost LED. LEDs are active-high. Unused higher order
-// bits are ignored.
+// Internal function which updates the i-th HEX widget
+func (s *UIState) showHEX(i int) {
+	if !s.headless {
+		s.hexWidgets[i].Update(s.HEX(i))
+	}
+}
+
+// HEX returns the segments most recently set on the i-th HEX display with
+// SetHEX(), using the same encoding. Any faults injected into the display
+// are included, see SetFaults(). Displays which the board does not have are
+// always blank.
+func (s *UIState) HEX(i int) uint8 {
+	if i < 0 || i >= s.profile.HEX {
+		return 0xff
+	}
+
+	if i < len(s.faults.HEX) {
+		return uint8(s.faults.HEX[i].Apply(uint32(s.hex[i])))
+	}
+	return s.hex[i]
+}
+
+// SetLEDR sets the LEDR display. There are 18 red LEDs on a DE2-115, see
+// BoardProfile for other boards. The least significant bit codes for the
+// rightmost LED. LEDs are active-high. Unused higher order bits are ignored.
 func (s *UIState) SetLEDR(state uint32) {
-	s.ledrWidget.Update(state)
-	s.ledrLabel.SetText(fmt.Sprintf("(0x%05x)", s.ledrWidget.State()))
+	state &= bitmask(s.profile.LEDR)
+	if s.ledr != state {
+		s.record("LEDR", state)
+	}
+	s.ledr = state
+	s.showLEDR()
+}
+
+// Internal function which updates the LEDR widget and label
+func (s *UIState) showLEDR() {
+	if !s.headless {
+		s.ledrWidget.Update(s.LEDR())
+		s.ledrLabel.SetText("(" + formatBits(s.LEDR(), s.profile.LEDR) + ")")
+	}
+}
+
+// LEDR returns the current state of the LEDR display, as set by SetLEDR().
+// Any faults injected into the LEDs are included, see SetFaults().
+func (s *UIState) LEDR() uint32 {
+	return s.faults.LEDR.Apply(s.ledr) & bitmask(s.profile.LEDR)
 }
 
-// SetLEDG sets the LEDG display. There are 9 green LEDs. the least significant
-// bit codes for the rightmost LED. LEDs are active-high. Unused higher order
-// bits are ignored.
+// SetLEDG sets the LEDG display. There are 9 green LEDs on a DE2-115, see
+// BoardProfile for other boards. The least significant bit codes for the
+// rightmost LED. LEDs are active-high. Unused higher order bits are ignored.
 func (s *UIState) SetLEDG(state uint32) {
-	s.ledgWidget.Update(state)
-	s.ledgLabel.SetText(fmt.Sprintf("(0x%03x)", s.ledgWidget.State()))
+	state &= bitmask(s.profile.LEDG)
+	if s.ledg != state {
+		s.record("LEDG", state)
+	}
+	s.ledg = state
+	s.showLEDG()
+}
+
+// Internal function which updates the LEDG widget and label
+func (s *UIState) showLEDG() {
+	if !s.headless {
+		s.ledgWidget.Update(s.LEDG())
+		s.ledgLabel.SetText("(" + formatBits(s.LEDG(), s.profile.LEDG) + ")")
+	}
+}
+
+// LEDG returns the current state of the LEDG display, as set by SetLEDG().
+// Any faults injected into the LEDs are included, see SetFaults().
+func (s *UIState) LEDG() uint32 {
+	return s.faults.LEDG.Apply(s.ledg) & bitmask(s.profile.LEDG)
+}
+
+// SetLCD changes the text shown on the i-th line of the character LCD, the
+// top line being 0. Text which does not fit is not shown. The LCD is only
+// shown if the board's layout includes it, see NewUIStateFromLayout(), but
+// LCD() works regardless.
+func (s *UIState) SetLCD(i int, text string) {
+	if i < 0 || i >= lcdwidget.Rows {
+		return
+	}
+
+	s.lcd[i] = text
+	if !s.headless {
+		s.lcdWidget.SetLine(i, text)
+	}
+}
+
+// LCD returns the text most recently set on the i-th line of the character
+// LCD with SetLCD().
+func (s *UIState) LCD(i int) string {
+	if i < 0 || i >= lcdwidget.Rows {
+		return ""
+	}
+	return s.lcd[i]
 }
 
 // SW gets the current value of the SW(itch) controls. There are 18
-// switches. The rightmost switch is assigned to the least-significant bit.
-// Unused higher order bits are left as zero.
+// switches on a DE2-115, see BoardProfile for other boards. The rightmost
+// switch is assigned to the least-significant bit. Unused higher order bits
+// are left as zero. While bounce emulation is enabled, this includes any
+// bouncing, see SetBounce(), and any injected faults are also included, see
+// SetFaults().
 func (s *UIState) SW() uint32 {
-	val := uint32(0)
-	for i := 0; i < numSwitches; i++ {
-		if s.switchChecks[i].Checked {
-			val |= 1 << (numSwitches - 1 - i)
-		}
-	}
-	return val
+	return s.faults.SW.Apply(s.sw^s.swBounce.mask^s.swGlitch) & bitmask(s.profile.SW)
 }
 
-// KEY returns the current value of the KEY controls. There are 4 keys.
-// The rightmost key is the least-significant bit. Unused higher order bits
-// are left as zero.
+// KEY returns the current value of the KEY controls. There are 4 keys on a
+// DE2-115, see BoardProfile for other boards. The rightmost key is the
+// least-significant bit. Unused higher order bits are left as zero. While
+// bounce emulation is enabled, this includes any bouncing, see SetBounce(),
+// and any injected faults are also included, see SetFaults().
 func (s *UIState) KEY() uint32 {
+	return s.faults.KEY.Apply(s.key^s.keyBounce.mask^s.keyGlitch) & bitmask(s.profile.KEY)
+}
+
+// PressedKEYs returns the KEYs which are pressed, by PressKEY(), HoldKEY()
+// or the KEY buttons, in the same order as for KEY(). Unlike KEY(), it does
+// not include any bounce, glitches or faults, so it tells whether a KEY is
+// held down by the user.
+func (s *UIState) PressedKEYs() uint32 {
 	return s.key
 }
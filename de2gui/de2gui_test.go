@@ -0,0 +1,158 @@
+package de2gui
+
+import (
+	"io/ioutil"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/herclab/de2gui/de2gui/internal/widgettest"
+	"github.com/herclab/de2gui/de2gui/session"
+)
+
+// newWindowedUIState returns a UIState with widgets, as if it were shown
+// in a window, whose design just counts the ticks. It should be closed.
+func newWindowedUIState() *UIState {
+	widgettest.NewApp()
+
+	s := NewUIState()
+	s.LogOutput = ioutil.Discard
+	s.OnTick = func(s *UIState, final bool) { s.Tick++ }
+	return s
+}
+
+// waitFor polls cond until it returns true, failing the test if it does
+// not do so within a few seconds
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+
+	for deadline := time.Now().Add(5 * time.Second); time.Now().Before(deadline); time.Sleep(10 * time.Millisecond) {
+		if cond() {
+			return
+		}
+	}
+	t.Fatalf("timed out waiting for %s", what)
+}
+
+// autoTicks is long enough for the auto-ticking goroutine to tick twice
+const autoTicks = 2 * time.Duration(autoTickInterval) * time.Millisecond
+
+func TestSetSW(t *testing.T) {
+	s := NewHeadlessUIState()
+	calls := 0
+	s.OnSW = func(s *UIState) { calls++ }
+
+	s.StartRecording()
+
+	// bits past SW17 are ignored
+	s.SetSW(0xffff0005)
+	if s.SW() != 0x30005 || calls != 1 {
+		t.Errorf("SW() = %#x and OnSW ran %d times, expected 0x30005 and once", s.SW(), calls)
+	}
+
+	tests := []struct {
+		i    int
+		on   bool
+		want uint32
+	}{
+		{1, true, 0x30007},
+		{0, false, 0x30006},
+		{17, false, 0x10006},
+		{18, true, 0x10006},
+		{-1, true, 0x10006},
+	}
+
+	for _, test := range tests {
+		s.SetSWBit(test.i, test.on)
+		if s.SW() != test.want {
+			t.Errorf("SetSWBit(%d, %v) left SW() = %#x, expected %#x", test.i, test.on, s.SW(), test.want)
+		}
+	}
+
+	// switches which do not exist do not run OnSW
+	if calls != 4 {
+		t.Errorf("OnSW ran %d times, expected 4", calls)
+	}
+
+	values := []uint32{}
+	for _, e := range s.StopRecording().Events {
+		if e.Signal == "SW" {
+			values = append(values, e.Value)
+		}
+	}
+	want := []uint32{0, 0x30005, 0x30007, 0x30006, 0x10006}
+	if !reflect.DeepEqual(values, want) {
+		t.Errorf("recorded SW %#x, expected %#x", values, want)
+	}
+}
+
+func TestPressKEY(t *testing.T) {
+	s := NewHeadlessUIState()
+	calls := 0
+	s.OnKEY = func(s *UIState) { calls++ }
+	pressed := 0
+	s.OnTick = func(s *UIState, final bool) {
+		if s.KEY()&0x4 != 0 {
+			pressed++
+		}
+		s.Tick++
+	}
+
+	s.StartRecording()
+
+	// the design sees the KEY pressed for 3 ticks
+	s.PressKEY(2, 3)
+	s.RunTicks(10)
+	if pressed != 3 || s.KEY() != 0 || calls != 2 {
+		t.Errorf("KEY2 was pressed for %d ticks, and KEY() = %#x and OnKEY ran %d times afterwards, expected 3, 0 and 2", pressed, s.KEY(), calls)
+	}
+
+	// holding a KEY cancels the release of an earlier press
+	s.PressKEY(0, 1)
+	s.HoldKEY(0)
+	s.RunTicks(5)
+	if s.KEY() != 0x1 || calls != 3 {
+		t.Errorf("KEY() = %#x and OnKEY ran %d times while held, expected 0x1 and 3", s.KEY(), calls)
+	}
+
+	s.ReleaseKEY(0)
+	s.ReleaseKEY(0)
+	s.HoldKEY(4)
+	if s.KEY() != 0 || calls != 4 {
+		t.Errorf("KEY() = %#x and OnKEY ran %d times after the release, expected 0 and 4", s.KEY(), calls)
+	}
+
+	events := []session.Event{}
+	for _, e := range s.StopRecording().Events {
+		if e.Signal == "KEY" {
+			events = append(events, e)
+		}
+	}
+	want := []session.Event{
+		{Cycle: 0, Tick: 0, Signal: "KEY", Value: 0},
+		{Cycle: 0, Tick: 0, Signal: "KEY", Value: 0x4},
+		{Cycle: 3, Tick: 3, Signal: "KEY", Value: 0},
+		{Cycle: 10, Tick: 10, Signal: "KEY", Value: 0x1},
+		{Cycle: 15, Tick: 15, Signal: "KEY", Value: 0},
+	}
+	if !reflect.DeepEqual(events, want) {
+		t.Errorf("recorded %+v, expected %+v", events, want)
+	}
+}
+
+func TestClose(t *testing.T) {
+	s := newWindowedUIState()
+	s.autoTickCheck.SetChecked(true)
+	waitFor(t, "a tick", func() bool { return s.currentTick() > 0 })
+
+	// no more ticks are run once Close() returns
+	s.Close()
+	tick := s.currentTick()
+	time.Sleep(autoTicks)
+	if s.currentTick() != tick {
+		t.Errorf("the board ticked from %d to %d after Close()", tick, s.currentTick())
+	}
+
+	s.Close()
+	NewHeadlessUIState().Close()
+}
@@ -0,0 +1,217 @@
+// Package de2guitest provides helpers for testing designs which use de2gui
+// with the standard testing package, without a display. For example, given
+// a function setup() which installs a design's callbacks into a UIState:
+//
+//	func TestCounter(t *testing.T) {
+//		b := de2guitest.New(setup)
+//		b.SetSW(0x2a)
+//		b.PressKEY(0, 10)
+//		b.Tick(100)
+//		b.ExpectLEDR(t, 0x2a)
+//		b.ExpectHEXDigits(t, "0012")
+//	}
+//
+// The checks performed by the Expect methods are the same as those of the
+// expect command in de2gui test scripts, see the script package.
+//
+// A panic in one of the design's callbacks is recovered by the UIState,
+// which stops ticking, see UIState.OnError. The Expect methods, TickUntil()
+// and RunScript() stop the test if a callback has panicked since the last
+// of them was called, so that a design which crashes cannot pass.
+package de2guitest
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/herclab/de2gui/de2gui"
+	"github.com/herclab/de2gui/de2gui/script"
+)
+
+// Board is a simulated board, backed by a headless de2gui.UIState.
+type Board struct {
+	// UI is the underlying UIState, which can be used directly for
+	// anything not covered by the methods of Board.
+	UI *de2gui.UIState
+
+	// errors are the panics in callbacks, of which the first reported
+	// have been reported to a test
+	errors   []error
+	reported int
+}
+
+// New creates a new Board. The setup function is called with the underlying
+// UIState, and should install the design's callbacks (OnTick, OnSW, and so
+// on) exactly as it would for a UIState with a GUI.
+func New(setup func(*de2gui.UIState)) *Board {
+	return NewFor(de2gui.ProfileDE2115, setup)
+}
+
+// NewFor is like New(), but simulates the board described by the given
+// profile rather than a DE2-115.
+func NewFor(p de2gui.BoardProfile, setup func(*de2gui.UIState)) *Board {
+	b := &Board{UI: de2gui.NewHeadlessUIStateFor(p)}
+	if setup != nil {
+		setup(b.UI)
+	}
+
+	// an OnError installed by setup still runs
+	onError := b.UI.OnError
+	b.UI.OnError = func(s *de2gui.UIState, err error) {
+		b.errors = append(b.errors, err)
+		if onError != nil {
+			onError(s, err)
+		}
+	}
+
+	return b
+}
+
+// Errors returns the panics in the design's callbacks since the board was
+// created, see UIState.OnError.
+func (b *Board) Errors() []error {
+	return append([]error{}, b.errors...)
+}
+
+// checkErrors stops the test if a callback has panicked since it was last
+// called
+func (b *Board) checkErrors(t testing.TB) {
+	t.Helper()
+
+	if b.reported == len(b.errors) {
+		return
+	}
+
+	errors := b.errors[b.reported:]
+	b.reported = len(b.errors)
+
+	for _, err := range errors {
+		if e, ok := err.(*de2gui.CallbackError); ok {
+			t.Errorf("%v\n%s", e, e.Stack)
+		} else {
+			t.Errorf("%v", err)
+		}
+	}
+	t.FailNow()
+}
+
+// SetSW changes all of the switches at once, see UIState.SetSW().
+func (b *Board) SetSW(v uint32) {
+	b.UI.SetSW(v)
+}
+
+// SetSWBit turns the i-th switch on or off, see UIState.SetSWBit().
+func (b *Board) SetSWBit(i int, on bool) {
+	b.UI.SetSWBit(i, on)
+}
+
+// PressKEY presses the i-th KEY, and releases it after the given number of
+// ticks, see UIState.PressKEY().
+func (b *Board) PressKEY(i int, ticks uint64) {
+	b.UI.PressKEY(i, ticks)
+}
+
+// HoldKEY presses the i-th KEY until ReleaseKEY() is called, see
+// UIState.HoldKEY().
+func (b *Board) HoldKEY(i int) {
+	b.UI.HoldKEY(i)
+}
+
+// ReleaseKEY releases the i-th KEY, see UIState.ReleaseKEY().
+func (b *Board) ReleaseKEY(i int) {
+	b.UI.ReleaseKEY(i)
+}
+
+// Tick advances the simulation by n ticks.
+func (b *Board) Tick(n int) {
+	b.UI.RunTicks(n)
+}
+
+// TickUntil advances the simulation one tick at a time until cond returns
+// true, failing the test if it has not done so after max ticks.
+func (b *Board) TickUntil(t testing.TB, max int, cond func(*de2gui.UIState) bool) {
+	t.Helper()
+
+	for i := 0; i < max; i++ {
+		b.checkErrors(t)
+		if cond(b.UI) {
+			return
+		}
+		b.UI.RunTicks(1)
+	}
+	b.checkErrors(t)
+
+	if !cond(b.UI) {
+		t.Fatalf("tick %d: condition not met after %d ticks", b.UI.Tick, max)
+	}
+}
+
+// expect runs an expect command, reporting a failed check to t
+func (b *Board) expect(t testing.TB, args ...string) {
+	t.Helper()
+	b.checkErrors(t)
+
+	r, err := b.UI.Exec(script.Command{Name: "expect", Args: args})
+	if err != nil {
+		t.Fatalf("expect %s: %v", strings.Join(args, " "), err)
+		return
+	}
+
+	if !r.Pass {
+		t.Errorf("tick %d: %s is %s, expected %s", r.Tick, strings.ToUpper(r.Target), r.Observed, r.Expected)
+	}
+}
+
+// ExpectLEDR checks that the red LEDs have the given value.
+func (b *Board) ExpectLEDR(t testing.TB, want uint32) {
+	t.Helper()
+	b.expect(t, "ledr", fmt.Sprintf("0x%x", want))
+}
+
+// ExpectLEDG checks that the green LEDs have the given value.
+func (b *Board) ExpectLEDG(t testing.TB, want uint32) {
+	t.Helper()
+	b.expect(t, "ledg", fmt.Sprintf("0x%x", want))
+}
+
+// ExpectHEX checks that the i-th HEX display has the given segments, in the
+// same encoding used by UIState.SetHEX().
+func (b *Board) ExpectHEX(t testing.TB, i int, segments uint8) {
+	t.Helper()
+	b.expect(t, fmt.Sprintf("hex%d", i), fmt.Sprintf("0x%02x", segments))
+}
+
+// ExpectHEXDigits checks the characters shown on the rightmost HEX displays.
+// The last character of digits corresponds to HEX0, and the character _
+// matches a blank display. For example, "0012" checks that HEX3...HEX0
+// show 0, 0, 1 and 2.
+func (b *Board) ExpectHEXDigits(t testing.TB, digits string) {
+	t.Helper()
+	b.expect(t, "hex", digits)
+}
+
+// RunScript runs a test script, given as text, against the board. Failed
+// checks are reported as test errors, and any other problem with the script
+// stops the test.
+func (b *Board) RunScript(t testing.TB, text string) {
+	t.Helper()
+
+	sc, err := script.Parse(t.Name(), strings.NewReader(text))
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+
+	b.checkErrors(t)
+	r := b.UI.RunScript(sc)
+	for _, v := range r.Results {
+		if !v.Pass {
+			t.Errorf("line %d, tick %d: %s: observed %s", v.Line, v.Tick, v.Check, v.Observed)
+		}
+	}
+
+	b.checkErrors(t)
+	if r.Error != "" {
+		t.Fatalf("%s", r.Error)
+	}
+}
@@ -0,0 +1,173 @@
+package de2guitest
+
+import (
+	"io/ioutil"
+	"testing"
+
+	"github.com/herclab/de2gui/de2gui"
+)
+
+// counter is a design which shows the tick number on LEDR
+func counter(s *de2gui.UIState) {
+	s.OnTick = func(s *de2gui.UIState, final bool) {
+		s.Tick++
+		s.SetLEDR(uint32(s.Tick))
+	}
+}
+
+func TestCounter(t *testing.T) {
+	b := New(counter)
+	b.Tick(10)
+	b.ExpectLEDR(t, 10)
+	b.RunScript(t, "tick 5\nexpect ledr 15\n")
+	b.TickUntil(t, 100, func(s *de2gui.UIState) bool { return s.LEDR() == 20 })
+}
+
+// mirror is a design which shows the switches on LEDR and HEX1-0, and the
+// KEYs on LEDG
+func mirror(s *de2gui.UIState) {
+	s.OnSW = func(s *de2gui.UIState) {
+		s.SetLEDR(s.SW())
+		s.SetHEX(0, de2gui.HexSegments(uint8(s.SW()&0xf)))
+		s.SetHEX(1, de2gui.HexSegments(uint8(s.SW()>>4&0xf)))
+	}
+	s.OnKEY = func(s *de2gui.UIState) {
+		s.SetLEDG(s.KEY())
+	}
+	s.OnTick = func(s *de2gui.UIState, final bool) {
+		s.Tick++
+	}
+}
+
+func TestInputs(t *testing.T) {
+	b := New(mirror)
+	b.SetSW(0x3a)
+	b.ExpectLEDR(t, 0x3a)
+	b.ExpectHEXDigits(t, "3A")
+	b.ExpectHEX(t, 0, de2gui.HexSegments(0xa))
+
+	b.SetSWBit(0, true)
+	b.SetSWBit(5, false)
+	b.ExpectHEXDigits(t, "_____1B")
+
+	b.PressKEY(1, 2)
+	b.ExpectLEDG(t, 0x2)
+	b.Tick(3)
+	b.ExpectLEDG(t, 0)
+
+	b.HoldKEY(3)
+	b.Tick(10)
+	b.ExpectLEDG(t, 0x8)
+	b.ReleaseKEY(3)
+	b.ExpectLEDG(t, 0)
+}
+
+func TestFailures(t *testing.T) {
+	b := New(mirror)
+	b.SetSW(0x12)
+
+	tests := []struct {
+		name string
+		f    func(t testing.TB)
+	}{
+		{"ExpectLEDR", func(t testing.TB) { b.ExpectLEDR(t, 0x13) }},
+		{"ExpectLEDG", func(t testing.TB) { b.ExpectLEDG(t, 0x1) }},
+		{"ExpectHEX", func(t testing.TB) { b.ExpectHEX(t, 1, de2gui.HexSegments(2)) }},
+		{"ExpectHEXDigits", func(t testing.TB) { b.ExpectHEXDigits(t, "21") }},
+		{"ExpectHEXDigits with a bad digit", func(t testing.TB) { b.ExpectHEXDigits(t, "1G") }},
+		{"TickUntil", func(t testing.TB) {
+			b.TickUntil(t, 5, func(s *de2gui.UIState) bool { return s.LEDR() == 0 })
+		}},
+		{"RunScript", func(t testing.TB) { b.RunScript(t, "expect ledr 0\n") }},
+		{"RunScript with a syntax error", func(t testing.TB) { b.RunScript(t, "frobnicate\n") }},
+	}
+
+	for _, test := range tests {
+		if !run(test.f) {
+			t.Errorf("%s passed, expected it to fail", test.name)
+		}
+	}
+
+	// TickUntil gave up after 5 ticks
+	if b.UI.Tick != 5 {
+		t.Errorf("Tick = %d, expected 5", b.UI.Tick)
+	}
+}
+
+// recorder is a testing.TB which records failures, rather than reporting
+// them
+type recorder struct {
+	testing.TB
+	failed bool
+	errors []string
+}
+
+func (r *recorder) Helper() {}
+
+func (r *recorder) Errorf(format string, args ...interface{}) {
+	r.failed = true
+	r.errors = append(r.errors, format)
+}
+
+func (r *recorder) Fatalf(format string, args ...interface{}) {
+	r.Errorf(format, args...)
+	r.FailNow()
+}
+
+// FailNow stops the caller by panicking, which is recovered by run()
+func (r *recorder) FailNow() {
+	r.failed = true
+	panic(r)
+}
+
+// run calls f with a recorder, returning true if it failed
+func run(f func(t testing.TB)) bool {
+	r := &recorder{TB: &testing.T{}}
+
+	func() {
+		defer func() {
+			if v := recover(); v != nil && v != r {
+				panic(v)
+			}
+		}()
+		f(r)
+	}()
+
+	return r.failed
+}
+
+func TestPanicFailsExpect(t *testing.T) {
+	setup := func(s *de2gui.UIState) {
+		counter(s)
+		tick := s.OnTick
+		s.OnTick = func(s *de2gui.UIState, final bool) {
+			if s.Tick == 3 {
+				panic("crash")
+			}
+			tick(s, final)
+		}
+	}
+
+	b := New(setup)
+	b.UI.LogOutput = ioutil.Discard
+	b.Tick(10)
+
+	// ticking stopped at 3, with LEDR showing 3
+	if !run(func(t testing.TB) { b.ExpectLEDR(t, 3) }) {
+		t.Errorf("ExpectLEDR passed after a panic in OnTick")
+	}
+	if n := len(b.Errors()); n != 1 {
+		t.Errorf("Errors() returned %d errors, expected 1", n)
+	}
+
+	// the panic is only reported once
+	if run(func(t testing.TB) { b.ExpectLEDR(t, 3) }) {
+		t.Errorf("ExpectLEDR failed again for the same panic")
+	}
+
+	b = New(setup)
+	b.UI.LogOutput = ioutil.Discard
+	if !run(func(t testing.TB) { b.RunScript(t, "tick 10\n") }) {
+		t.Errorf("RunScript passed after a panic in OnTick")
+	}
+}
@@ -0,0 +1,163 @@
+package de2gui
+
+import (
+	"fmt"
+	"runtime/debug"
+	"strconv"
+	"strings"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/widget"
+)
+
+// CallbackError describes a panic in one of the callbacks, such as OnTick,
+// or in a function scheduled with ScheduleFuture(). It is passed to OnError.
+type CallbackError struct {
+	// Callback is the name of the callback which panicked: "OnTick",
+	// "OnSW", "OnKEY", "OnReset", "OnSnapshot", "OnRestore", or "future".
+	Callback string
+
+	// Tick is the value of the Tick field when the panic happened.
+	Tick uint64
+
+	// Value is the value passed to panic().
+	Value interface{}
+
+	// Stack is the stack trace of the goroutine which panicked.
+	Stack string
+}
+
+// Error implements error.
+func (e *CallbackError) Error() string {
+	return fmt.Sprintf("%s panicked at tick %d: %v", e.Callback, e.Tick, e.Value)
+}
+
+// Errors returns the number of panics recovered from callbacks since the
+// UIState was created.
+func (s *UIState) Errors() int {
+	s.errorMutex.Lock()
+	defer s.errorMutex.Unlock()
+
+	return s.errorCount
+}
+
+// Internal function which returns the most recent panic in a callback, if
+// Errors() is no longer count, and otherwise nil
+func (s *UIState) errorSince(count int) error {
+	s.errorMutex.Lock()
+	defer s.errorMutex.Unlock()
+
+	if s.errorCount == count {
+		return nil
+	}
+	return s.lastError
+}
+
+// Internal function which runs f, a callback named name, recovering from
+// any panic, which is reported with reportError(). It returns false if f
+// panicked.
+func (s *UIState) call(name string, f func()) (ok bool) {
+	defer func() {
+		if v := recover(); v != nil {
+			ok = false
+			s.reportError(&CallbackError{
+				Callback: name,
+				Tick:     s.Tick,
+				Value:    v,
+				Stack:    string(debug.Stack()),
+			})
+		}
+	}()
+
+	f()
+	return true
+}
+
+// Internal function which handles a panic in a callback. Auto-ticking is
+// stopped, the panic is written to ErrorLog, and it is passed to OnError,
+// or shown in the error panel if OnError is nil.
+func (s *UIState) reportError(e *CallbackError) {
+	s.errorMutex.Lock()
+	s.errorCount++
+	s.lastError = e
+	count := s.errorCount
+	s.errorMutex.Unlock()
+
+	paused := !s.headless && s.autoTickCheck.Checked
+	if paused {
+		s.autoTickCheck.SetChecked(false)
+	}
+
+	s.logError(e)
+
+	if s.OnError != nil {
+		s.OnError(s, e)
+		return
+	}
+
+	s.showError(e, count, paused)
+}
+
+// Internal function which writes a panic to ErrorLog as a line of
+// key=value pairs, followed by the stack trace indented by a tab. If
+// ErrorLog is nil, the panic is added to the log, with the stack trace at
+// LevelDebug.
+func (s *UIState) logError(e *CallbackError) {
+	if s.ErrorLog == nil {
+		s.Logf(LevelError, "%v", e)
+		s.Logf(LevelDebug, "%s", e.Stack)
+		return
+	}
+
+	w := s.ErrorLog
+	fmt.Fprintf(w, "level=error callback=%s tick=%d panic=%s\n",
+		e.Callback, e.Tick, strconv.Quote(fmt.Sprint(e.Value)))
+
+	for _, line := range strings.Split(strings.TrimRight(e.Stack, "\n"), "\n") {
+		fmt.Fprintf(w, "\t%s\n", line)
+	}
+}
+
+// Internal function which creates the error panel, which is hidden until a
+// callback panics
+func (s *UIState) newErrorPanel() fyne.CanvasObject {
+	s.errorLabel = widget.NewLabelWithStyle("", fyne.TextAlignLeading, fyne.TextStyle{Bold: true})
+	s.errorStack = widget.NewLabelWithStyle("", fyne.TextAlignLeading, fyne.TextStyle{Monospace: true})
+
+	scroll := container.NewScroll(s.errorStack)
+	scroll.SetMinSize(fyne.NewSize(560, 200))
+
+	s.errorPanel = container.NewVBox(
+		container.NewBorder(nil, nil, nil,
+			widget.NewButton("Dismiss", func() { s.errorPanel.Hide() }),
+			s.errorLabel,
+		),
+		widget.NewAccordion(widget.NewAccordionItem("Stack trace", scroll)),
+		widget.NewSeparator(),
+	)
+	s.errorPanel.Hide()
+
+	return s.errorPanel
+}
+
+// Internal function which shows a panic in the error panel, along with the
+// number of panics so far. paused is true if auto-ticking was stopped
+// because of it.
+func (s *UIState) showError(e *CallbackError, count int, paused bool) {
+	if s.headless {
+		return
+	}
+
+	msg := e.Error()
+	if count > 1 {
+		msg += fmt.Sprintf(" (%d errors so far)", count)
+	}
+	if paused {
+		msg += "\nAuto Tick has been stopped."
+	}
+
+	s.errorLabel.SetText(msg)
+	s.errorStack.SetText(e.Stack)
+	s.errorPanel.Show()
+}
@@ -0,0 +1,131 @@
+package de2gui
+
+import (
+	"bytes"
+	"io/ioutil"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCallbackPanic(t *testing.T) {
+	s := NewHeadlessUIState()
+	s.LogOutput = ioutil.Discard
+
+	ticks := 0
+	s.OnTick = func(s *UIState, final bool) {
+		if s.Tick == 3 {
+			panic("broken")
+		}
+		ticks++
+		s.Tick++
+	}
+
+	var errs []error
+	s.OnError = func(s *UIState, err error) { errs = append(errs, err) }
+
+	// the rest of the group of ticks is skipped
+	s.RunTicks(10)
+	if ticks != 3 || s.Errors() != 1 || len(errs) != 1 {
+		t.Fatalf("%d ticks ran, Errors() = %d, and OnError got %v, expected 3 ticks and 1 error", ticks, s.Errors(), errs)
+	}
+
+	e, ok := errs[0].(*CallbackError)
+	if !ok {
+		t.Fatalf("OnError got a %T, expected a *CallbackError", errs[0])
+	}
+	if e.Callback != "OnTick" || e.Tick != 3 || e.Value != "broken" {
+		t.Errorf("OnError got %+v", e)
+	}
+	if e.Error() != "OnTick panicked at tick 3: broken" {
+		t.Errorf("Error() = %q", e.Error())
+	}
+	if !strings.Contains(e.Stack, "TestCallbackPanic") {
+		t.Errorf("the stack does not include the callback:\n%s", e.Stack)
+	}
+
+	// ticking carries on from where it stopped
+	s.Tick = 4
+	s.RunTicks(2)
+	if ticks != 5 || s.Errors() != 1 {
+		t.Errorf("%d ticks ran, and Errors() = %d, expected 5 and 1", ticks, s.Errors())
+	}
+}
+
+func TestCallbackPanicLogged(t *testing.T) {
+	s := NewHeadlessUIState()
+	s.LogOutput = ioutil.Discard
+	s.OnSW = func(s *UIState) { panic("bad switch") }
+	s.ScheduleFuture(0, func(s *UIState) { panic(42) })
+
+	s.SetSW(1)
+	s.RunTicks(1)
+	if s.Errors() != 2 {
+		t.Fatalf("Errors() = %d, expected 2", s.Errors())
+	}
+
+	// without ErrorLog, each panic is logged, with the stack as detail
+	errors, stack := []string{}, 0
+	for _, e := range s.Log() {
+		if e.Level == LevelDebug {
+			stack++
+		} else {
+			errors = append(errors, e.String())
+		}
+	}
+
+	want := []string{"[0] error: OnSW panicked at tick 0: bad switch", "[0] error: future panicked at tick 0: 42"}
+	if !reflect.DeepEqual(errors, want) || stack == 0 {
+		t.Errorf("logged %q and %d lines of stack, expected %q and the stacks", errors, stack, want)
+	}
+
+	var errorLog bytes.Buffer
+	s.ErrorLog = &errorLog
+	s.SetSW(0)
+
+	lines := strings.Split(errorLog.String(), "\n")
+	if lines[0] != `level=error callback=OnSW tick=0 panic="bad switch"` || !strings.HasPrefix(lines[1], "\tgoroutine ") {
+		t.Errorf("ErrorLog got:\n%s", errorLog.String())
+	}
+}
+
+func TestPanicStopsAutoTick(t *testing.T) {
+	s := newWindowedUIState()
+	defer s.Close()
+
+	// the design waits for the Auto Tick checkbox to finish refreshing,
+	// since it is not safe to change it at the same time
+	checked := make(chan bool)
+	s.OnTick = func(s *UIState, final bool) {
+		switch s.Tick {
+		case 0:
+			<-checked
+		case 2:
+			panic("broken")
+		}
+		s.Tick++
+	}
+
+	// OnError is run after Auto Tick is unchecked
+	errs := make(chan error, 1)
+	s.OnError = func(s *UIState, err error) { errs <- err }
+
+	s.autoTickCheck.SetChecked(true)
+	close(checked)
+	select {
+	case <-errs:
+	case <-time.After(5 * time.Second):
+		t.Fatal("OnTick did not panic")
+	}
+
+	if s.autoTickCheck.Checked {
+		t.Errorf("Auto Tick is still checked after a panic")
+	}
+
+	// the ticking stops
+	time.Sleep(autoTicks)
+	if s.Errors() != 1 || s.currentTick() != 2 {
+		t.Errorf("Errors() = %d and Tick = %d, expected 1 and 2", s.Errors(), s.currentTick())
+	}
+}
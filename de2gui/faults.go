@@ -0,0 +1,333 @@
+package de2gui
+
+import (
+	"fmt"
+	"strings"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+
+	"github.com/herclab/de2gui/de2gui/script"
+)
+
+// StuckAt describes a stuck-at fault on some of the bits of a signal.
+type StuckAt struct {
+	// Zero contains the bits which are stuck at 0.
+	Zero uint32
+
+	// One contains the bits which are stuck at 1. If a bit is in both
+	// Zero and One, it is stuck at 1.
+	One uint32
+}
+
+// Apply returns v with the stuck bits forced to their stuck values.
+func (f StuckAt) Apply(v uint32) uint32 {
+	return (v &^ f.Zero) | f.One
+}
+
+// Faults describes the faults injected into the board's inputs and outputs,
+// see SetFaults(). The zero value has no faults.
+type Faults struct {
+	// SW and KEY are applied to the values returned by SW() and KEY(),
+	// so a KEY which is stuck at 1 is always pressed.
+	SW  StuckAt
+	KEY StuckAt
+
+	// LEDR and LEDG are applied to the values set by SetLEDR() and
+	// SetLEDG(), so an LED which is stuck at 0 is dead.
+	LEDR StuckAt
+	LEDG StuckAt
+
+	// HEX contains the faults for each HEX display, indexed by the
+	// display number. Displays past the end of the slice have no faults.
+	// The segments are active-low, as for SetHEX(), so a dead segment is
+	// stuck at 1, and a segment which is always lit is stuck at 0.
+	HEX []StuckAt
+}
+
+// none returns true if there are no faults
+func (f Faults) none() bool {
+	for _, v := range f.HEX {
+		if v != (StuckAt{}) {
+			return false
+		}
+	}
+
+	return f.SW == StuckAt{} && f.KEY == StuckAt{} &&
+		f.LEDR == StuckAt{} && f.LEDG == StuckAt{}
+}
+
+// String describes the faults as script commands separated by semicolons,
+// see the script package. The first command is always "fault clear".
+func (f Faults) String() string {
+	cmds := []string{"fault clear"}
+
+	add := func(target string, st StuckAt) {
+		if st.Zero != 0 {
+			cmds = append(cmds, fmt.Sprintf("fault %s stuck0 0x%x", target, st.Zero))
+		}
+		if st.One != 0 {
+			cmds = append(cmds, fmt.Sprintf("fault %s stuck1 0x%x", target, st.One))
+		}
+	}
+
+	add("sw", f.SW)
+	add("key", f.KEY)
+	add("ledr", f.LEDR)
+	add("ledg", f.LEDG)
+	for i, v := range f.HEX {
+		add(fmt.Sprintf("hex%d", i), v)
+	}
+
+	return strings.Join(cmds, "; ")
+}
+
+// SetFaults replaces the faults injected into the board. Faults are applied
+// transparently: the design keeps using SW(), KEY(), SetLEDR() and so on as
+// usual, but sees stuck inputs, and its outputs are shown with stuck LEDs
+// and segments. OnSW and OnKEY are run if the value seen by the design
+// changes.
+//
+// If a session is being recorded, the change is recorded as a FAULT event.
+// This can also be changed by the user with the Faults controls.
+func (s *UIState) SetFaults(f Faults) {
+	sw, key := s.SW(), s.KEY()
+
+	f.HEX = append([]StuckAt(nil), f.HEX...)
+	s.faults = f
+	s.recordFault(f.String())
+
+	s.showLEDR()
+	s.showLEDG()
+	for i := 0; i < s.profile.HEX; i++ {
+		s.showHEX(i)
+	}
+	s.showFaults()
+
+	if s.SW() != sw {
+		s.swUpdate()
+	}
+
+	if s.KEY() != key {
+		s.keyUpdate()
+	}
+}
+
+// Faults returns the faults currently injected into the board, see
+// SetFaults().
+func (s *UIState) Faults() Faults {
+	f := s.faults
+	f.HEX = append([]StuckAt(nil), f.HEX...)
+	return f
+}
+
+// ClearFaults removes all injected faults, see SetFaults().
+func (s *UIState) ClearFaults() {
+	s.SetFaults(Faults{})
+}
+
+// GlitchSW inverts the given bits of SW() for a single tick, as if the
+// switches had briefly lost contact. OnSW is run when the glitch starts and
+// when it ends. Glitching a switch again during the same tick leaves it
+// inverted, rather than restoring it.
+func (s *UIState) GlitchSW(mask uint32) {
+	mask &= bitmask(s.profile.SW)
+	if mask == 0 {
+		return
+	}
+
+	s.swGlitch |= mask
+	s.recordFault(fmt.Sprintf("glitch sw 0x%x", mask))
+	s.swUpdate()
+	s.scheduleGlitchEnd()
+}
+
+// GlitchKEY inverts the given bits of KEY() for a single tick. OnKEY is run
+// when the glitch starts and when it ends. As for GlitchSW(), glitches on
+// the same KEY during the same tick do not cancel out.
+func (s *UIState) GlitchKEY(mask uint32) {
+	mask &= bitmask(s.profile.KEY)
+	if mask == 0 {
+		return
+	}
+
+	s.keyGlitch |= mask
+	s.recordFault(fmt.Sprintf("glitch key 0x%x", mask))
+	s.keyUpdate()
+	s.scheduleGlitchEnd()
+}
+
+// Internal function which schedules the end of the glitches started during
+// the current tick, unless it is already scheduled. Every glitch ends on the
+// next tick, in this one future, so that each bit is restored exactly once.
+func (s *UIState) scheduleGlitchEnd() {
+	if s.glitchEnding {
+		return
+	}
+	s.glitchEnding = true
+
+	s.ScheduleFuture(s.Tick+1, func(*UIState) {
+		sw, key := s.swGlitch, s.keyGlitch
+		s.swGlitch, s.keyGlitch = 0, 0
+		s.glitchEnding = false
+
+		if sw != 0 {
+			s.swUpdate()
+		}
+		if key != 0 {
+			s.keyUpdate()
+		}
+	})
+}
+
+// Internal function which executes a fault command
+func (s *UIState) execFault(fc script.Fault) error {
+	if fc.Target == "" {
+		s.ClearFaults()
+		return nil
+	}
+
+	f := s.Faults()
+
+	var st *StuckAt
+	switch fc.Target {
+	case "sw":
+		st = &f.SW
+	case "key":
+		st = &f.KEY
+	case "ledr":
+		st = &f.LEDR
+	case "ledg":
+		st = &f.LEDG
+	default:
+		if fc.Index >= s.profile.HEX {
+			return fmt.Errorf("there is no HEX%d", fc.Index)
+		}
+		for len(f.HEX) <= fc.Index {
+			f.HEX = append(f.HEX, StuckAt{})
+		}
+		st = &f.HEX[fc.Index]
+	}
+
+	switch fc.Kind {
+	case "clear":
+		*st = StuckAt{}
+	case "stuck0":
+		st.Zero |= uint32(fc.Mask)
+	case "stuck1":
+		st.One |= uint32(fc.Mask)
+	}
+
+	s.SetFaults(f)
+	return nil
+}
+
+// Internal function which updates the fault summary shown in the GUI
+func (s *UIState) showFaults() {
+	if s.headless {
+		return
+	}
+
+	if s.faults.none() {
+		s.faultLabel.SetText("none")
+	} else {
+		s.faultLabel.SetText("active")
+	}
+}
+
+// Internal function wired into the faults "Edit..." button, which shows a
+// dialog for changing the stuck-at faults
+func (s *UIState) editFaults() {
+	w := s.window()
+	if w == nil {
+		return
+	}
+
+	f := s.Faults()
+	for len(f.HEX) < s.profile.HEX {
+		f.HEX = append(f.HEX, StuckAt{})
+	}
+
+	// each target has a pair of entries, for the bits stuck at 0 and 1
+	type row struct {
+		st        *StuckAt
+		zero, one *widget.Entry
+	}
+
+	rows := []row{}
+	items := []*widget.FormItem{}
+	add := func(name string, st *StuckAt) {
+		r := row{st: st, zero: widget.NewEntry(), one: widget.NewEntry()}
+		r.zero.SetText(fmt.Sprintf("0x%x", st.Zero))
+		r.one.SetText(fmt.Sprintf("0x%x", st.One))
+		rows = append(rows, r)
+		items = append(items, widget.NewFormItem(name, container.NewGridWithColumns(4,
+			widget.NewLabel("stuck at 0:"), r.zero,
+			widget.NewLabel("stuck at 1:"), r.one,
+		)))
+	}
+
+	add("SW", &f.SW)
+	add("KEY", &f.KEY)
+	add("LEDR", &f.LEDR)
+	add("LEDG", &f.LEDG)
+	for i := 0; i < s.profile.HEX; i++ {
+		add(fmt.Sprintf("HEX%d", i), &f.HEX[i])
+	}
+
+	dialog.ShowForm("Faults", "Apply", "Cancel", items, func(ok bool) {
+		if !ok {
+			return
+		}
+
+		for _, r := range rows {
+			zero, err := script.ParseNumber(r.zero.Text)
+			if err != nil {
+				dialog.ShowError(err, w)
+				return
+			}
+
+			one, err := script.ParseNumber(r.one.Text)
+			if err != nil {
+				dialog.ShowError(err, w)
+				return
+			}
+
+			r.st.Zero, r.st.One = uint32(zero), uint32(one)
+		}
+
+		s.SetFaults(f)
+	}, w)
+}
+
+// Internal function which creates the fault injection controls
+func (s *UIState) newFaultControls() fyne.CanvasObject {
+	glitchEntry := widget.NewEntry()
+	glitchEntry.SetText("0x1")
+
+	glitch := func(f func(uint32)) {
+		mask, err := script.ParseNumber(glitchEntry.Text)
+		if err != nil {
+			if w := s.window(); w != nil {
+				dialog.ShowError(err, w)
+			}
+			return
+		}
+		f(uint32(mask))
+	}
+
+	s.showFaults()
+
+	return container.NewHBox(
+		widget.NewLabel("Faults:"),
+		s.faultLabel,
+		widget.NewButton("Edit...", func() { s.editFaults() }),
+		widget.NewButton("Clear", func() { s.ClearFaults() }),
+		widget.NewLabel("glitch"),
+		glitchEntry,
+		widget.NewButton("on SW", func() { glitch(s.GlitchSW) }),
+		widget.NewButton("on KEY", func() { glitch(s.GlitchKEY) }),
+	)
+}
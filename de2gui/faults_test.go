@@ -0,0 +1,139 @@
+package de2gui
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/herclab/de2gui/de2gui/session"
+)
+
+func TestStuckAt(t *testing.T) {
+	tests := []struct {
+		f    StuckAt
+		v    uint32
+		want uint32
+	}{
+		{StuckAt{}, 0x5, 0x5},
+		{StuckAt{Zero: 0x1}, 0x5, 0x4},
+		{StuckAt{One: 0x2}, 0x5, 0x7},
+		{StuckAt{Zero: 0x3, One: 0x1}, 0x6, 0x5},
+	}
+
+	for _, test := range tests {
+		if got := test.f.Apply(test.v); got != test.want {
+			t.Errorf("%+v.Apply(%#x) = %#x, expected %#x", test.f, test.v, got, test.want)
+		}
+	}
+}
+
+func TestSetFaults(t *testing.T) {
+	s := NewHeadlessUIState()
+	swCalls, keyCalls := 0, 0
+	s.OnSW = func(s *UIState) { swCalls++ }
+	s.OnKEY = func(s *UIState) { keyCalls++ }
+
+	s.SetSW(0x3)
+	s.SetLEDR(0x30)
+	s.SetLEDG(0x1)
+	s.SetHEX(1, HexSegments(8))
+	swCalls = 0
+
+	s.StartRecording()
+	s.SetFaults(Faults{
+		SW:   StuckAt{Zero: 0x1},
+		KEY:  StuckAt{One: 0x8},
+		LEDR: StuckAt{Zero: 0x10, One: 0x1},
+		LEDG: StuckAt{Zero: 0x1},
+		HEX:  []StuckAt{{}, {One: 0x40}},
+	})
+
+	if s.SW() != 0x2 || s.KEY() != 0x8 {
+		t.Errorf("SW() = %#x and KEY() = %#x, expected 0x2 and 0x8", s.SW(), s.KEY())
+	}
+	if s.LEDR() != 0x21 || s.LEDG() != 0 {
+		t.Errorf("LEDR() = %#x and LEDG() = %#x, expected 0x21 and 0", s.LEDR(), s.LEDG())
+	}
+
+	// the middle segment of the 8 is dead, leaving a 0
+	if s.HEX(1) != HexSegments(0) || s.HEX(0) != 0xff {
+		t.Errorf("HEX(1) = %#x and HEX(0) = %#x, expected %#x and 0xff", s.HEX(1), s.HEX(0), HexSegments(0))
+	}
+	if swCalls != 1 || keyCalls != 1 {
+		t.Errorf("OnSW ran %d times and OnKEY %d times, expected once each", swCalls, keyCalls)
+	}
+
+	// the design's own values are kept, and shown again once the faults
+	// are cleared
+	s.ClearFaults()
+	if s.SW() != 0x3 || s.KEY() != 0 || s.LEDR() != 0x30 || s.LEDG() != 0x1 || s.HEX(1) != HexSegments(8) {
+		t.Errorf("SW() = %#x, KEY() = %#x, LEDR() = %#x, LEDG() = %#x and HEX(1) = %#x after ClearFaults()",
+			s.SW(), s.KEY(), s.LEDR(), s.LEDG(), s.HEX(1))
+	}
+
+	faults := []string{}
+	for _, e := range s.StopRecording().Events {
+		if e.Signal == "FAULT" {
+			faults = append(faults, e.Detail)
+		}
+	}
+
+	want := []string{
+		"fault clear; fault sw stuck0 0x1; fault key stuck1 0x8; fault ledr stuck0 0x10; fault ledr stuck1 0x1; fault ledg stuck0 0x1; fault hex1 stuck1 0x40",
+		"fault clear",
+	}
+	if !reflect.DeepEqual(faults, want) {
+		t.Errorf("recorded the faults %q, expected %q", faults, want)
+	}
+}
+
+func TestGlitch(t *testing.T) {
+	s := NewHeadlessUIState()
+
+	// the design counts the ticks during which SW0 is on
+	on := 0
+	s.OnTick = func(s *UIState, final bool) {
+		if s.SW()&1 != 0 {
+			on++
+		}
+		s.Tick++
+	}
+
+	s.StartRecording()
+	s.GlitchSW(0x1)
+	if s.SW() != 0x1 {
+		t.Errorf("SW() = %#x during the glitch, expected 0x1", s.SW())
+	}
+
+	// a second glitch on the same switch in the same tick does not end
+	// the first
+	s.GlitchSW(0x3)
+	s.GlitchKEY(0x2)
+	if s.SW() != 0x3 || s.KEY() != 0x2 {
+		t.Errorf("SW() = %#x and KEY() = %#x, expected 0x3 and 0x2", s.SW(), s.KEY())
+	}
+
+	s.RunTicks(3)
+	if on != 1 || s.SW() != 0 || s.KEY() != 0 {
+		t.Errorf("SW0 was on for %d ticks, and SW() = %#x and KEY() = %#x afterwards, expected 1, 0 and 0", on, s.SW(), s.KEY())
+	}
+
+	// glitches on switches which do not exist are ignored
+	s.GlitchSW(1 << 18)
+	s.RunTicks(1)
+
+	events := []session.Event{}
+	for _, e := range s.StopRecording().Events {
+		if e.Signal == "FAULT" {
+			events = append(events, e)
+		}
+	}
+
+	want := []session.Event{
+		{Signal: "FAULT", Detail: "glitch sw 0x1"},
+		{Signal: "FAULT", Detail: "glitch sw 0x3"},
+		{Signal: "FAULT", Detail: "glitch key 0x2"},
+	}
+	if !reflect.DeepEqual(events, want) {
+		t.Errorf("recorded the glitches %+v, expected %+v", events, want)
+	}
+}
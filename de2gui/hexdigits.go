@@ -0,0 +1,40 @@
+package de2gui
+
+// hexDigitSegments contains the conventional 7-segment encodings for the
+// hexadecimal digits 0...F, active-low, using the same segment numbering as
+// SetHEX().
+var hexDigitSegments = [16]uint8{
+	0x40, 0x79, 0x24, 0x30, 0x19, 0x12, 0x02, 0x78,
+	0x00, 0x10, 0x08, 0x03, 0x46, 0x21, 0x06, 0x0e,
+}
+
+// HexBlank is the (active-low) segment value for a HEX display with every
+// segment turned off.
+const HexBlank uint8 = 0x7f
+
+// HexSegments returns the segment encoding, suitable for use with SetHEX(),
+// which displays the hexadecimal digit d. Only the lower 4 bits of d are
+// considered.
+func HexSegments(d uint8) uint8 {
+	return hexDigitSegments[d&0xf]
+}
+
+// HexDigit is the inverse of HexSegments(). It returns the character shown
+// by the given segments, which is one of 0-9, A-F, or a space if the display
+// is blank. If the segments do not encode a hexadecimal digit, ok is false.
+//
+// The unused 8th bit of the segments is ignored.
+func HexDigit(segments uint8) (digit rune, ok bool) {
+	segments &= 0x7f
+	if segments == HexBlank {
+		return ' ', true
+	}
+
+	for i, v := range hexDigitSegments {
+		if v == segments {
+			return rune("0123456789ABCDEF"[i]), true
+		}
+	}
+
+	return '?', false
+}
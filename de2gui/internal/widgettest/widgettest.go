@@ -0,0 +1,72 @@
+// Package widgettest lets the widgets, and UIStates with widgets, be tested
+// without a display. Fyne's own test package would add testify to the
+// module's dependencies, so this installs a minimal app instead, which is
+// just enough for widgets to be created, refreshed and sent events.
+package widgettest
+
+import (
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/theme"
+)
+
+// app is an app with the dark theme and no windows. The methods which are
+// not needed to test widgets are left unimplemented, and panic.
+type app struct {
+	fyne.App
+}
+
+func (app) Settings() fyne.Settings {
+	return settings{}
+}
+
+func (app) Driver() fyne.Driver {
+	return driver{}
+}
+
+// settings are the default settings, with the dark theme
+type settings struct{}
+
+func (settings) Theme() fyne.Theme {
+	return theme.DarkTheme()
+}
+
+func (settings) SetTheme(fyne.Theme) {
+}
+
+func (settings) ThemeVariant() fyne.ThemeVariant {
+	return theme.VariantDark
+}
+
+func (settings) Scale() float32 {
+	return 1
+}
+
+func (settings) PrimaryColor() string {
+	return theme.ColorBlue
+}
+
+func (settings) AddChangeListener(chan fyne.Settings) {
+}
+
+func (settings) BuildType() fyne.BuildType {
+	return fyne.BuildStandard
+}
+
+// driver is a driver with no canvases, so that refreshing a widget does
+// nothing, and a fixed width font
+type driver struct {
+	fyne.Driver
+}
+
+func (driver) CanvasForObject(fyne.CanvasObject) fyne.Canvas {
+	return nil
+}
+
+func (driver) RenderedTextSize(text string, size float32, style fyne.TextStyle) fyne.Size {
+	return fyne.NewSize(float32(len(text))*size*0.6, size*1.2)
+}
+
+// NewApp makes a minimal app the current app, see fyne.SetCurrentApp().
+func NewApp() {
+	fyne.SetCurrentApp(app{})
+}
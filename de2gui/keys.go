@@ -0,0 +1,155 @@
+package de2gui
+
+import (
+	"fmt"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/widget"
+
+	"github.com/herclab/de2gui/de2gui/widgets/keywidget"
+)
+
+// SetKeyHoldMode enables or disables press-and-hold mode for the KEY buttons.
+// Normally, clicking a KEY button presses the KEY for a random number of
+// ticks between KeyPushMinTime and KeyPushMaxTime. In press-and-hold mode,
+// the KEY is pressed for as long as the mouse button is held down on it.
+// Since the design only sees the KEY while ticks run, the board ticks as if
+// Auto Tick were checked while a KEY is held down, unless it is already
+// auto-ticking. This can also be changed by the user with the "Hold KEYs"
+// checkbox.
+func (s *UIState) SetKeyHoldMode(on bool) {
+	s.keyHoldMode = on
+	if !s.headless && s.keyHoldCheck.Checked != on {
+		s.keyHoldCheck.SetChecked(on)
+	}
+}
+
+// SetKeyLatch enables or disables latching for the i-th KEY. While a KEY is
+// latched, clicking its button toggles it between pressed and released,
+// regardless of whether press-and-hold mode is enabled. Disabling the latch
+// releases the KEY. This can also be changed by the user with the "latch"
+// checkbox below each KEY.
+func (s *UIState) SetKeyLatch(i int, on bool) {
+	if i < 0 || i >= s.profile.KEY {
+		return
+	}
+
+	s.keyLatch[i] = on
+	if !on {
+		s.ReleaseKEY(i)
+	}
+
+	if !s.headless && s.keyLatchChecks[i].Checked != on {
+		s.keyLatchChecks[i].SetChecked(on)
+	}
+}
+
+// Internal function called whenever the state of the KEYs changes, which
+// updates the KEY widgets, starts any bounce, records the change, and runs
+// OnKEY
+func (s *UIState) keyUpdate() {
+	s.refreshKeys()
+	s.startBounce(&s.keyBounce, s.key, s.keyUpdate)
+	s.record("KEY", s.KEY())
+
+	if s.OnKEY != nil {
+		s.call("OnKEY", func() { s.OnKEY(s) })
+	}
+}
+
+// Internal function which updates the KEY widgets to match KEY(), so that
+// bounce, glitches and faults are shown
+func (s *UIState) refreshKeys() {
+	if s.headless {
+		return
+	}
+
+	key := s.KEY()
+	for i, w := range s.keyWidgets {
+		w.SetPressed(key&(1<<i) != 0)
+	}
+}
+
+// Internal function wired into KEY button taps
+func (s *UIState) keyTapped(i int) {
+	switch {
+	case s.keyLatch[i]:
+		if s.key&(1<<i) != 0 {
+			s.ReleaseKEY(i)
+		} else {
+			s.HoldKEY(i)
+		}
+
+	case s.keyHoldMode:
+		// already handled by keyDown() and keyUp()
+
+	default:
+		s.PushKEY(i)
+	}
+}
+
+// Internal function called when the mouse button is pressed on a KEY button
+func (s *UIState) keyDown(i int) {
+	if s.keyHoldMode && !s.keyLatch[i] {
+		s.HoldKEY(i)
+		s.startHoldTicking()
+	}
+}
+
+// Internal function called when the mouse button is released on a KEY button
+func (s *UIState) keyUp(i int) {
+	if s.keyHoldMode && !s.keyLatch[i] {
+		s.ReleaseKEY(i)
+	}
+	s.stopHoldTicking()
+}
+
+// Internal function which starts ticking while a KEY is held down in
+// press-and-hold mode, unless the board is already auto-ticking
+func (s *UIState) startHoldTicking() {
+	if s.headless || s.holdTicking || s.autoTickCheck.Checked {
+		return
+	}
+
+	s.holdTicking = true
+	s.tickChannel <- autoTickInterval
+}
+
+// Internal function which stops the ticking started by startHoldTicking(),
+// unless Auto Tick has been checked since
+func (s *UIState) stopHoldTicking() {
+	if !s.holdTicking {
+		return
+	}
+
+	s.holdTicking = false
+	if !s.autoTickCheck.Checked {
+		s.tickChannel <- 0
+	}
+}
+
+// Internal function which creates the KEY buttons and associated controls,
+// with the given label in front of them unless it is empty
+func (s *UIState) newKeyControls(label string) fyne.CanvasObject {
+	row := container.NewHBox()
+	if label != "" {
+		row.Objects = append(row.Objects, widget.NewLabel(label))
+	}
+
+	// KEY0 is the rightmost
+	for i := s.profile.KEY - 1; i >= 0; i-- {
+		i := i
+		s.keyWidgets[i] = keywidget.NewKeyWidget(fmt.Sprintf("KEY%d", i))
+		s.keyWidgets[i].OnTapped = func() { s.keyTapped(i) }
+		s.keyWidgets[i].OnMouseDown = func() { s.keyDown(i) }
+		s.keyWidgets[i].OnMouseUp = func() { s.keyUp(i) }
+		s.keyLatchChecks[i] = widget.NewCheck("latch", func(c bool) { s.SetKeyLatch(i, c) })
+		row.Objects = append(row.Objects, container.NewVBox(s.keyWidgets[i], s.keyLatchChecks[i]))
+	}
+
+	s.keyHoldCheck = widget.NewCheck("Hold KEYs", func(c bool) { s.SetKeyHoldMode(c) })
+	row.Objects = append(row.Objects, s.keyHoldCheck)
+
+	return row
+}
@@ -0,0 +1,97 @@
+package de2gui
+
+import (
+	"testing"
+	"time"
+)
+
+func TestKeyLatch(t *testing.T) {
+	s := NewHeadlessUIState()
+	calls := 0
+	s.OnKEY = func(s *UIState) { calls++ }
+
+	s.SetKeyLatch(2, true)
+	s.SetKeyLatch(7, true)
+
+	// a latched KEY is toggled by each click, even in press-and-hold mode
+	s.keyTapped(2)
+	if s.KEY() != 0x4 {
+		t.Errorf("KEY() = %#x after the first click, expected 0x4", s.KEY())
+	}
+
+	s.SetKeyHoldMode(true)
+	s.keyDown(2)
+	s.keyUp(2)
+	s.keyTapped(2)
+	if s.KEY() != 0 || calls != 2 {
+		t.Errorf("KEY() = %#x and OnKEY ran %d times after the second click, expected 0 and 2", s.KEY(), calls)
+	}
+
+	// removing the latch releases the KEY
+	s.keyTapped(2)
+	s.SetKeyLatch(2, false)
+	if s.KEY() != 0 || calls != 4 {
+		t.Errorf("KEY() = %#x and OnKEY ran %d times after unlatching, expected 0 and 4", s.KEY(), calls)
+	}
+}
+
+func TestKeyHoldMode(t *testing.T) {
+	defer func(min, max uint64) { KeyPushMinTime, KeyPushMaxTime = min, max }(KeyPushMinTime, KeyPushMaxTime)
+	KeyPushMinTime, KeyPushMaxTime = 5, 0
+
+	s := NewHeadlessUIState()
+	s.OnTick = func(s *UIState, final bool) { s.Tick++ }
+
+	// the KEY is held from mouse down to mouse up, however many ticks
+	// pass, and the click itself does nothing more
+	s.SetKeyHoldMode(true)
+	s.keyDown(1)
+	s.RunTicks(100)
+	if s.KEY() != 0x2 {
+		t.Errorf("KEY() = %#x while held, expected 0x2", s.KEY())
+	}
+	s.keyUp(1)
+	s.keyTapped(1)
+	if s.KEY() != 0 {
+		t.Errorf("KEY() = %#x after mouse up, expected 0", s.KEY())
+	}
+
+	// otherwise, a click pushes the KEY for KeyPushMinTime ticks
+	s.SetKeyHoldMode(false)
+	s.keyDown(1)
+	s.keyUp(1)
+	s.keyTapped(1)
+	s.RunTicks(5)
+	if s.KEY() != 0x2 {
+		t.Errorf("KEY() = %#x while pushed, expected 0x2", s.KEY())
+	}
+	s.RunTicks(1)
+	if s.KEY() != 0 {
+		t.Errorf("KEY() = %#x after the push, expected 0", s.KEY())
+	}
+}
+
+func TestKeyHoldTicking(t *testing.T) {
+	s := newWindowedUIState()
+	defer s.Close()
+
+	// the board ticks while a KEY is held down in press-and-hold mode
+	s.SetKeyHoldMode(true)
+	s.keyDown(0)
+	waitFor(t, "a tick while KEY0 is held", func() bool { return s.currentTick() > 0 })
+
+	// and stops once it is released, after any tick which was due
+	s.keyUp(0)
+	time.Sleep(autoTicks)
+	tick := s.currentTick()
+	time.Sleep(autoTicks)
+	if s.currentTick() != tick || s.KEY() != 0 {
+		t.Errorf("the board ticked from %d to %d after KEY0 was released, with KEY() = %#x", tick, s.currentTick(), s.KEY())
+	}
+
+	// releasing the KEY does not stop Auto Tick
+	s.autoTickCheck.SetChecked(true)
+	s.keyDown(0)
+	s.keyUp(0)
+	waitFor(t, "a tick with Auto Tick checked", func() bool { return s.currentTick() > tick })
+}
@@ -0,0 +1,228 @@
+package de2gui
+
+import (
+	"image/color"
+	"os"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/canvas"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/widget"
+
+	"github.com/herclab/de2gui/de2gui/layout"
+)
+
+// NewUIStateFromLayout is like NewUIState(), but shows the board described
+// by a layout file, see the layout package. The number of each kind of
+// signal is taken from the layout, see LayoutProfile().
+func NewUIStateFromLayout(l *layout.Layout) (*UIState, error) {
+	if err := l.Validate(); err != nil {
+		return nil, err
+	}
+
+	if l.Background != "" && !layout.BuiltinBackground(l.Background) {
+		if _, err := os.Stat(l.Background); err != nil {
+			return nil, err
+		}
+	}
+
+	return newUIState(LayoutProfile(l), l), nil
+}
+
+// LayoutProfile returns the profile of the board described by a layout
+// file. This can be used with NewHeadlessUIStateFor() to test a design
+// written for a custom board without a display.
+func LayoutProfile(l *layout.Layout) BoardProfile {
+	return BoardProfile{
+		Name: l.Name,
+		HEX:  l.Count("hex"),
+		LEDR: l.Count("ledr"),
+		LEDG: l.Count("ledg"),
+		SW:   l.Count("sw"),
+		KEY:  l.Count("key"),
+	}
+}
+
+// defaultLayout returns the layout used for a board profile, which shows
+// all of the board's signals and controls, stacked
+func defaultLayout(p BoardProfile) *layout.Layout {
+	l := &layout.Layout{Name: p.Name}
+
+	counts := []struct {
+		typ   string
+		count int
+	}{
+		{"hex", p.HEX}, {"ledr", p.LEDR}, {"ledg", p.LEDG}, {"sw", p.SW}, {"key", p.KEY},
+	}
+
+	// boards without e.g. green LEDs don't get an empty row
+	for _, v := range counts {
+		if v.count > 0 {
+			l.Widgets = append(l.Widgets, layout.Widget{Type: v.typ, Count: v.count})
+		}
+	}
+
+	for _, typ := range []string{"bounce", "faults", "ticks", "session", "vectors"} {
+		l.Widgets = append(l.Widgets, layout.Widget{Type: typ})
+	}
+
+	return l
+}
+
+// layoutLabel returns the label of the widget of the given type in l. If it
+// does not have one, def is used, unless the widgets are placed at fixed
+// positions, where a label would spoil their alignment.
+func layoutLabel(l *layout.Layout, typ, def string) string {
+	for _, w := range l.Widgets {
+		if w.Type == typ && w.Label != "" {
+			return w.Label
+		}
+	}
+
+	if l.Positioned() {
+		return ""
+	}
+	return def
+}
+
+// layoutPitch returns the pitch of the widget of the given type in l, or 0
+// if it does not have one
+func layoutPitch(l *layout.Layout, typ string) float32 {
+	for _, w := range l.Widgets {
+		if w.Type == typ {
+			return w.Pitch
+		}
+	}
+	return 0
+}
+
+// ledColors returns the active and inactive colors of the LED strip of the
+// given type in l, or the given defaults if it does not have a color
+func ledColors(l *layout.Layout, typ string, active, inactive color.RGBA) (color.RGBA, color.RGBA) {
+	for _, w := range l.Widgets {
+		if w.Type != typ || w.Color == "" {
+			continue
+		}
+
+		c, err := layout.ParseColor(w.Color)
+		if err != nil {
+			break
+		}
+
+		// unlit LEDs are a faint version of the lit color, as for the
+		// built-in colors
+		return c, color.RGBA{c.R / 8, c.G / 8, c.B / 8, 64}
+	}
+
+	return active, inactive
+}
+
+// arrange builds the widget tree described by l from the parts of the
+// board, which are indexed by widget type
+func arrange(l *layout.Layout, parts map[string]fyne.CanvasObject) fyne.CanvasObject {
+	objects := []fyne.CanvasObject{}
+	for _, w := range l.Widgets {
+		switch w.Type {
+		case "label":
+			objects = append(objects, widget.NewLabel(w.Label))
+		case "outline":
+			objects = append(objects, newOutline(w))
+		default:
+			objects = append(objects, parts[w.Type])
+		}
+	}
+
+	if !l.Positioned() {
+		return container.NewVBox(objects...)
+	}
+
+	fl := &fixedLayout{}
+	for _, w := range l.Widgets {
+		fl.positions = append(fl.positions, fyne.NewPos(w.Pos[0], w.Pos[1]))
+	}
+	if l.Size != nil {
+		fl.size = fyne.NewSize(l.Size[0], l.Size[1])
+	}
+
+	board := container.New(fl, objects...)
+
+	switch l.Background {
+	case "":
+		return board
+
+	case layout.BackgroundPCB:
+		pcb := canvas.NewRectangle(colorPCB)
+		pcb.StrokeColor = colorPCBEdge
+		pcb.StrokeWidth = 2
+		return container.NewMax(pcb, board)
+
+	case layout.BackgroundDE2115:
+		img := canvas.NewImageFromImage(layout.DE2115Image())
+		img.FillMode = canvas.ImageFillStretch
+		return container.NewMax(img, board)
+
+	default:
+		img := canvas.NewImageFromFile(l.Background)
+		img.FillMode = canvas.ImageFillStretch
+		return container.NewMax(img, board)
+	}
+}
+
+// colors of the board drawn for layout.BackgroundPCB
+var colorPCB color.RGBA = color.RGBA{20, 70, 45, 255}
+var colorPCBEdge color.RGBA = color.RGBA{10, 40, 25, 255}
+
+// colorSilkscreen is the color of outlines and their labels
+var colorSilkscreen color.RGBA = color.RGBA{220, 225, 215, 255}
+
+// newOutline returns the rectangle drawn for an outline widget, with its
+// label in the top left corner
+func newOutline(w layout.Widget) fyne.CanvasObject {
+	rect := canvas.NewRectangle(color.Transparent)
+	rect.StrokeColor = colorSilkscreen
+	rect.StrokeWidth = 1
+	rect.SetMinSize(fyne.NewSize(w.Size[0], w.Size[1]))
+
+	text := canvas.NewText(w.Label, colorSilkscreen)
+	text.TextSize = 10
+	text.Move(fyne.NewPos(4, 2))
+	text.Resize(text.MinSize())
+
+	// the label is inset from the corner, rather than centered
+	return container.NewMax(rect, container.NewWithoutLayout(text))
+}
+
+// fixedLayout is a fyne.Layout which places each object at a fixed
+// position, at its minimum size
+type fixedLayout struct {
+	positions []fyne.Position
+
+	// size is the minimum size of the container, which is otherwise
+	// just large enough to hold the objects
+	size fyne.Size
+}
+
+// Layout implements fyne.Layout
+func (f *fixedLayout) Layout(objects []fyne.CanvasObject, size fyne.Size) {
+	for i, o := range objects {
+		if i < len(f.positions) {
+			o.Move(f.positions[i])
+		}
+		o.Resize(o.MinSize())
+	}
+}
+
+// MinSize implements fyne.Layout
+func (f *fixedLayout) MinSize(objects []fyne.CanvasObject) fyne.Size {
+	size := f.size
+	for i, o := range objects {
+		if i >= len(f.positions) {
+			continue
+		}
+
+		min := o.MinSize()
+		pos := f.positions[i]
+		size = size.Max(fyne.NewSize(pos.X+min.Width, pos.Y+min.Height))
+	}
+	return size
+}
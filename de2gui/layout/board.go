@@ -0,0 +1,116 @@
+package layout
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+)
+
+// part is one of the chips or connectors on the DE2-115 which is not shown
+// as a widget, see SkinDE2115() and DE2115Image()
+type part struct {
+	label      string
+	kind       string // "chip", "fpga", "connector" or "header"
+	x, y, w, h int
+}
+
+// de2115Parts contains the parts drawn on the DE2-115 skin, both as outlines
+// and in the board image
+var de2115Parts = []part{
+	{"ETHERNET 0", "connector", 420, 8, 90, 70},
+	{"ETHERNET 1", "connector", 520, 8, 90, 70},
+	{"USB", "connector", 620, 8, 60, 60},
+	{"VIDEO IN", "connector", 690, 8, 60, 50},
+	{"VGA", "connector", 760, 8, 110, 50},
+	{"RS-232", "connector", 880, 8, 90, 50},
+	{"PS/2", "connector", 980, 8, 60, 60},
+	{"AUDIO", "connector", 1050, 8, 130, 50},
+	{"POWER", "connector", 8, 260, 70, 60},
+	{"USB BLASTER", "connector", 8, 330, 90, 60},
+	{"FLASH", "chip", 340, 170, 110, 60},
+	{"CYCLONE IV E", "fpga", 490, 140, 170, 170},
+	{"SDRAM", "chip", 700, 150, 110, 60},
+	{"SRAM", "chip", 700, 240, 110, 60},
+	{"SD CARD", "connector", 880, 260, 90, 70},
+	{"GPIO", "header", 1070, 140, 40, 280},
+	{"HSMC", "header", 1130, 140, 50, 360},
+}
+
+// colors of the board image
+var (
+	imagePCB     = color.RGBA{20, 70, 45, 255}
+	imagePCBEdge = color.RGBA{10, 40, 25, 255}
+	imageTrace   = color.RGBA{30, 95, 60, 255}
+	imageChip    = color.RGBA{35, 35, 38, 255}
+	imageFPGA    = color.RGBA{150, 150, 155, 255}
+	imagePin     = color.RGBA{190, 190, 185, 255}
+	imageMetal   = color.RGBA{170, 172, 175, 255}
+	imageOpening = color.RGBA{25, 25, 25, 255}
+	imageHole    = color.RGBA{200, 170, 80, 255}
+)
+
+// de2115Bounds is the size of the board image, which is the size of the
+// DE2-115 skin
+var de2115Bounds = image.Rect(0, 0, 1200, 780)
+
+// DE2115Image returns the image used as the background of SkinDE2115(),
+// which is 1200x780. It is not a photograph: it is drawn from the same
+// table of parts as the skin's outlines, as a green circuit board with
+// plain shapes for the FPGA, the memory chips, the connectors along the
+// edges and the expansion headers, and some traces between them.
+func DE2115Image() image.Image {
+	img := image.NewRGBA(de2115Bounds)
+	fill := func(c color.Color, x, y, w, h int) {
+		draw.Draw(img, image.Rect(x, y, x+w, y+h), image.NewUniform(c), image.ZP, draw.Src)
+	}
+
+	b := de2115Bounds
+	fill(imagePCBEdge, 0, 0, b.Dx(), b.Dy())
+	fill(imagePCB, 4, 4, b.Dx()-8, b.Dy()-8)
+
+	// mounting holes in the corners
+	for _, p := range []image.Point{{20, 20}, {b.Dx() - 32, 80}, {20, b.Dy() - 32}, {b.Dx() - 32, b.Dy() - 32}} {
+		fill(imageHole, p.X, p.Y, 12, 12)
+		fill(imagePCBEdge, p.X+3, p.Y+3, 6, 6)
+	}
+
+	// buses from the FPGA to the memory chips, and down to the switches,
+	// LEDs and displays
+	for i := 0; i < 8; i++ {
+		fill(imageTrace, 450, 180+i*5, 40, 2)
+		fill(imageTrace, 660, 160+i*5, 40, 2)
+		fill(imageTrace, 660, 250+i*5, 40, 2)
+		fill(imageTrace, 520+i*16, 310, 2, 80)
+	}
+
+	for _, p := range de2115Parts {
+		switch p.kind {
+		case "chip":
+			// pins along the long edges
+			for x := p.x + 6; x < p.x+p.w-6; x += 8 {
+				fill(imagePin, x, p.y+2, 4, 6)
+				fill(imagePin, x, p.y+p.h-8, 4, 6)
+			}
+			fill(imageChip, p.x+4, p.y+8, p.w-8, p.h-16)
+
+		case "fpga":
+			// a package with a metal lid
+			fill(imageChip, p.x+4, p.y+4, p.w-8, p.h-8)
+			fill(imageFPGA, p.x+24, p.y+24, p.w-48, p.h-48)
+
+		case "connector":
+			fill(imageMetal, p.x+4, p.y+4, p.w-8, p.h-8)
+			fill(imageOpening, p.x+12, p.y+12, p.w-24, p.h-24)
+
+		case "header":
+			// two rows of pins, in a black shroud
+			fill(imageChip, p.x+4, p.y+4, p.w-8, p.h-8)
+			for y := p.y + 10; y < p.y+p.h-10; y += 10 {
+				fill(imagePin, p.x+p.w/2-9, y, 5, 5)
+				fill(imagePin, p.x+p.w/2+4, y, 5, 5)
+			}
+		}
+	}
+
+	return img
+}
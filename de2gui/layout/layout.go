@@ -0,0 +1,305 @@
+// Package layout reads board layout files, which describe a custom "virtual
+// lab board" for de2gui, see de2gui.NewUIStateFromLayout(). A layout lists
+// the widgets shown on the board, in JSON:
+//
+//	{
+//		"name": "Lab 3: ALU",
+//		"widgets": [
+//			{"type": "hex", "count": 4},
+//			{"type": "ledr", "count": 8, "label": "Result", "color": "#ff8000"},
+//			{"type": "sw", "count": 8, "label": "A, B"},
+//			{"type": "key", "count": 2},
+//			{"type": "ticks"}
+//		]
+//	}
+//
+// The widget types are:
+//
+//	hex       seven-segment displays, HEX0 is the rightmost
+//	ledr      a strip of red LEDs
+//	ledg      a strip of green LEDs
+//	sw        slide switches
+//	key       push buttons
+//	lcd       a 16x2 character LCD
+//	label     a line of text, given by "label"
+//	outline   a rectangle drawn on the board, with an optional "label"
+//	ticks     the tick and reset controls
+//	session   the session recording controls
+//	vectors   the test vector controls
+//	bounce    the contact bounce controls
+//	faults    the fault injection controls
+//
+// Each type except label and outline may appear at most once. The count of
+// the hex, ledr, ledg, sw and key widgets determines how many of each
+// signal the board has, and signals which do not appear are not present.
+// The label of an LED strip, switch bank or set of KEYs is shown beside it,
+// and the color of an LED strip is given as #rrggbb.
+//
+// By default, widgets are stacked top to bottom in the order they are listed.
+// If every widget has a "pos" of the form [x, y], they are instead placed at
+// those coordinates, and the board is given the size in "size", if any. Such a
+// layout may also have a "background", which is drawn behind the widgets,
+// stretched to the size of the board. It is either the path of an image file,
+// relative to the layout file, "pcb" for a plain circuit board, or "de2-115"
+// for the drawing of a DE2-115 from DE2115Image(). The "pitch" of an LED strip
+// or bank of switches is the distance between the centers of neighbouring LEDs
+// or switches, which is useful for lining them up with each other or with a
+// background image. An outline is a rectangle of the given "size", drawn like
+// the silkscreen on a circuit board, which marks a part of the board such as a
+// chip or a connector. It is only allowed in such a layout, and is drawn behind
+// any widgets listed after it. See SkinDE2115() for an example.
+package layout
+
+import (
+	"encoding/json"
+	"fmt"
+	"image/color"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// Widget describes a single widget in a layout.
+type Widget struct {
+	// Type is the kind of widget, see the package documentation.
+	Type string `json:"type"`
+
+	// Count is the number of displays, LEDs, switches or KEYs.
+	Count int `json:"count,omitempty"`
+
+	// Label is the text shown beside the widget, or the text of a label
+	// widget. If empty, a default such as "LEDR:" is used.
+	Label string `json:"label,omitempty"`
+
+	// Color is the color of a lit LED, as #rrggbb. If empty, the usual
+	// red or green is used.
+	Color string `json:"color,omitempty"`
+
+	// Pos is the position of the top left corner of the widget, if the
+	// widgets are placed at fixed positions.
+	Pos *[2]float32 `json:"pos,omitempty"`
+
+	// Pitch is the distance between neighbouring LEDs or switches. If it
+	// is 0, the usual spacing is used.
+	Pitch float32 `json:"pitch,omitempty"`
+
+	// Size is the width and height of an outline.
+	Size *[2]float32 `json:"size,omitempty"`
+}
+
+// Layout is a parsed layout file.
+type Layout struct {
+	// Name is shown as the window title by applications which support
+	// it.
+	Name string `json:"name,omitempty"`
+
+	// Size is the size of the board when the widgets are placed at fixed
+	// positions. If it is nil, the board is just large enough to hold
+	// them.
+	Size *[2]float32 `json:"size,omitempty"`
+
+	// Background is drawn behind the widgets when they are placed at
+	// fixed positions. It is either the path of an image file, or a
+	// built-in background such as BackgroundPCB. If it is empty, nothing
+	// is drawn.
+	Background string `json:"background,omitempty"`
+
+	Widgets []Widget `json:"widgets"`
+}
+
+// The built-in backgrounds, which are drawn by de2gui rather than read from
+// a file. BackgroundPCB is a plain circuit board, and BackgroundDE2115 is
+// the image returned by DE2115Image().
+const (
+	BackgroundPCB    = "pcb"
+	BackgroundDE2115 = "de2-115"
+)
+
+// BuiltinBackground returns true if background is the name of a built-in
+// background, rather than the path of an image file.
+func BuiltinBackground(background string) bool {
+	return background == BackgroundPCB || background == BackgroundDE2115
+}
+
+// counted contains the types of widget which have a count
+var counted = map[string]bool{"hex": true, "ledr": true, "ledg": true, "sw": true, "key": true}
+
+// others contains the types of widget which do not have a count
+var others = map[string]bool{
+	"lcd": true, "label": true, "ticks": true, "session": true,
+	"vectors": true, "bounce": true, "faults": true, "outline": true,
+}
+
+// maxCount is the most of any one signal a board may have
+const maxCount = 32
+
+// minPitch is the smallest pitch which leaves room for each switch
+const minPitch float32 = 14
+
+// Validate returns an error if the layout is not well formed.
+func (l *Layout) Validate() error {
+	seen := map[string]bool{}
+	positioned := 0
+	outlines := 0
+
+	for i, w := range l.Widgets {
+		switch {
+		case counted[w.Type]:
+			if w.Count < 1 || w.Count > maxCount {
+				return fmt.Errorf("widget %d (%s): count must be between 1 and %d", i, w.Type, maxCount)
+			}
+
+		case others[w.Type]:
+			if w.Count != 0 {
+				return fmt.Errorf("widget %d (%s): does not have a count", i, w.Type)
+			}
+
+		default:
+			return fmt.Errorf("widget %d: unknown type '%s'", i, w.Type)
+		}
+
+		if w.Type != "label" && w.Type != "outline" {
+			if seen[w.Type] {
+				return fmt.Errorf("widget %d: there is already a %s widget", i, w.Type)
+			}
+			seen[w.Type] = true
+		}
+
+		if w.Color != "" {
+			if w.Type != "ledr" && w.Type != "ledg" {
+				return fmt.Errorf("widget %d (%s): only LEDs have a color", i, w.Type)
+			}
+			if _, err := ParseColor(w.Color); err != nil {
+				return fmt.Errorf("widget %d (%s): %v", i, w.Type, err)
+			}
+		}
+
+		if w.Pitch != 0 {
+			if w.Type != "ledr" && w.Type != "ledg" && w.Type != "sw" {
+				return fmt.Errorf("widget %d (%s): only LEDs and switches have a pitch", i, w.Type)
+			}
+			if w.Pitch < minPitch {
+				return fmt.Errorf("widget %d (%s): pitch must be at least %g", i, w.Type, minPitch)
+			}
+		}
+
+		if (w.Size != nil) != (w.Type == "outline") {
+			return fmt.Errorf("widget %d (%s): only outlines, and all outlines, have a size", i, w.Type)
+		}
+		if w.Size != nil && (w.Size[0] <= 0 || w.Size[1] <= 0) {
+			return fmt.Errorf("widget %d (%s): size must be positive", i, w.Type)
+		}
+
+		if w.Pos != nil {
+			positioned++
+		}
+		if w.Type == "outline" {
+			outlines++
+		}
+	}
+
+	if positioned != 0 && positioned != len(l.Widgets) {
+		return fmt.Errorf("either all widgets or none must have a pos")
+	}
+
+	if l.Background != "" && positioned == 0 {
+		return fmt.Errorf("only layouts with a pos for each widget may have a background")
+	}
+
+	if outlines != 0 && positioned == 0 {
+		return fmt.Errorf("only layouts with a pos for each widget may have outlines")
+	}
+
+	return nil
+}
+
+// Positioned returns true if the widgets are placed at fixed positions,
+// rather than stacked.
+func (l *Layout) Positioned() bool {
+	return len(l.Widgets) > 0 && l.Widgets[0].Pos != nil
+}
+
+// Count returns the count of the widget with the given type, or 0 if there
+// is none.
+func (l *Layout) Count(typ string) int {
+	for _, w := range l.Widgets {
+		if w.Type == typ {
+			return w.Count
+		}
+	}
+	return 0
+}
+
+// Has returns true if the layout contains a widget of the given type.
+func (l *Layout) Has(typ string) bool {
+	for _, w := range l.Widgets {
+		if w.Type == typ {
+			return true
+		}
+	}
+	return false
+}
+
+// RemoveOutlines removes the outline widgets from the layout, for use when
+// its Background is a photograph which shows the real parts.
+func (l *Layout) RemoveOutlines() {
+	widgets := []Widget{}
+	for _, w := range l.Widgets {
+		if w.Type != "outline" {
+			widgets = append(widgets, w)
+		}
+	}
+	l.Widgets = widgets
+}
+
+// ParseColor parses a color written as #rrggbb.
+func ParseColor(s string) (color.RGBA, error) {
+	v, err := strconv.ParseUint(strings.TrimPrefix(s, "#"), 16, 32)
+	if err != nil || len(s) != 7 || s[0] != '#' {
+		return color.RGBA{}, fmt.Errorf("invalid color '%s', expected #rrggbb", s)
+	}
+
+	return color.RGBA{uint8(v >> 16), uint8(v >> 8), uint8(v), 255}, nil
+}
+
+// Parse reads a layout from r, and validates it. The name is used in error
+// messages. The Background is not checked, since it may be relative to a
+// file whose location is unknown.
+func Parse(name string, r io.Reader) (*Layout, error) {
+	l := &Layout{}
+
+	dec := json.NewDecoder(r)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(l); err != nil {
+		return nil, fmt.Errorf("%s: %v", name, err)
+	}
+
+	if err := l.Validate(); err != nil {
+		return nil, fmt.Errorf("%s: %v", name, err)
+	}
+
+	return l, nil
+}
+
+// ParseFile reads a layout from the file at path. A relative Background is
+// made relative to the directory containing the file.
+func ParseFile(path string) (*Layout, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	l, err := Parse(path, f)
+	if err != nil {
+		return nil, err
+	}
+
+	if l.Background != "" && !BuiltinBackground(l.Background) && !filepath.IsAbs(l.Background) {
+		l.Background = filepath.Join(filepath.Dir(path), l.Background)
+	}
+
+	return l, nil
+}
@@ -0,0 +1,183 @@
+package layout
+
+import (
+	"image/color"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	l, err := ParseFile("../../cmd/de2gui_demo/lab_board.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if l.Name != "Lab 3: ALU" || l.Positioned() || l.Background != "" {
+		t.Errorf("parsed %+v", l)
+	}
+
+	counts := map[string]int{"hex": 4, "ledr": 8, "ledg": 0, "sw": 8, "key": 2, "lcd": 0}
+	for typ, want := range counts {
+		if n := l.Count(typ); n != want {
+			t.Errorf("Count(%q) = %d, expected %d", typ, n, want)
+		}
+	}
+
+	for typ, want := range map[string]bool{"lcd": true, "ticks": true, "ledg": false, "session": false} {
+		if l.Has(typ) != want {
+			t.Errorf("Has(%q) = %v, expected %v", typ, !want, want)
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		json string
+		err  string
+	}{
+		{`{"widgets": [{"type": "hex", "count": 4}, {"type": "label", "label": "a"}, {"type": "label"}]}`, ""},
+		{`{"widgets": [{"type": "hex"}]}`, "widget 0 (hex): count must be between 1 and 32"},
+		{`{"widgets": [{"type": "ledr", "count": 33}]}`, "widget 0 (ledr): count must be between 1 and 32"},
+		{`{"widgets": [{"type": "lcd", "count": 2}]}`, "widget 0 (lcd): does not have a count"},
+		{`{"widgets": [{"type": "knob"}]}`, "widget 0: unknown type 'knob'"},
+		{`{"widgets": [{"type": "lcd"}, {"type": "lcd"}]}`, "widget 1: there is already a lcd widget"},
+		{`{"widgets": [{"type": "sw", "count": 1, "color": "#ff0000"}]}`, "widget 0 (sw): only LEDs have a color"},
+		{`{"widgets": [{"type": "ledr", "count": 1, "color": "red"}]}`, "widget 0 (ledr): invalid color 'red', expected #rrggbb"},
+		{`{"widgets": [{"type": "key", "count": 1, "pitch": 20}]}`, "widget 0 (key): only LEDs and switches have a pitch"},
+		{`{"widgets": [{"type": "sw", "count": 1, "pitch": 10}]}`, "widget 0 (sw): pitch must be at least 14"},
+		{`{"widgets": [{"type": "lcd", "pos": [0, 0]}, {"type": "ticks"}]}`, "either all widgets or none must have a pos"},
+		{`{"background": "pcb", "widgets": [{"type": "lcd"}]}`, "only layouts with a pos for each widget may have a background"},
+		{`{"widgets": [{"type": "outline", "size": [10, 10]}]}`, "only layouts with a pos for each widget may have outlines"},
+		{`{"widgets": [{"type": "outline", "pos": [0, 0]}]}`, "widget 0 (outline): only outlines, and all outlines, have a size"},
+		{`{"widgets": [{"type": "lcd", "pos": [0, 0], "size": [10, 10]}]}`, "widget 0 (lcd): only outlines, and all outlines, have a size"},
+		{`{"widgets": [{"type": "outline", "pos": [0, 0], "size": [10, 0]}]}`, "widget 0 (outline): size must be positive"},
+		{`{"widgets": [], "colour": "#000000"}`, `json: unknown field "colour"`},
+	}
+
+	for _, test := range tests {
+		_, err := Parse("test", strings.NewReader(test.json))
+		switch {
+		case test.err == "" && err != nil:
+			t.Errorf("%s: returned error %v", test.json, err)
+		case test.err != "" && (err == nil || err.Error() != "test: "+test.err):
+			t.Errorf("%s: returned error %v, expected %q", test.json, err, test.err)
+		}
+	}
+}
+
+func TestParseFileBackground(t *testing.T) {
+	dir, err := ioutil.TempDir("", "layout")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	write := func(name, background string) string {
+		path := filepath.Join(dir, name)
+		json := `{"background": "` + background + `", "widgets": [{"type": "lcd", "pos": [10, 20]}]}`
+		if err := ioutil.WriteFile(path, []byte(json), 0644); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+
+	tests := []struct {
+		background string
+		want       string
+	}{
+		{"board.png", filepath.Join(dir, "board.png")},
+		{"pcb", BackgroundPCB},
+		{"de2-115", BackgroundDE2115},
+		{"/images/board.png", "/images/board.png"},
+	}
+
+	for _, test := range tests {
+		l, err := ParseFile(write("layout.json", test.background))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if l.Background != test.want {
+			t.Errorf("the background %q became %q, expected %q", test.background, l.Background, test.want)
+		}
+		if !l.Positioned() || *l.Widgets[0].Pos != [2]float32{10, 20} {
+			t.Errorf("the lcd is at %v, expected [10 20]", l.Widgets[0].Pos)
+		}
+	}
+}
+
+func TestParseColor(t *testing.T) {
+	c, err := ParseColor("#ff8001")
+	if err != nil || c != (color.RGBA{0xff, 0x80, 0x01, 0xff}) {
+		t.Errorf("ParseColor(\"#ff8001\") = %v, %v", c, err)
+	}
+
+	for _, s := range []string{"", "ff8001", "#ff800", "#ff80011", "#gg8001", "#+f8001"} {
+		if _, err := ParseColor(s); err == nil {
+			t.Errorf("ParseColor(%q) succeeded, expected an error", s)
+		}
+	}
+}
+
+func TestSkinDE2115(t *testing.T) {
+	l := SkinDE2115()
+	if err := l.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	if l.Background != BackgroundDE2115 || !BuiltinBackground(l.Background) {
+		t.Errorf("the background is %q, expected the built-in %q", l.Background, BackgroundDE2115)
+	}
+
+	counts := map[string]int{"hex": 8, "ledr": 18, "ledg": 9, "sw": 18, "key": 4}
+	for typ, want := range counts {
+		if n := l.Count(typ); n != want {
+			t.Errorf("Count(%q) = %d, expected %d", typ, n, want)
+		}
+	}
+
+	// every widget is on the board
+	for i, w := range l.Widgets {
+		if w.Pos[0] < 0 || w.Pos[1] < 0 || w.Pos[0] >= l.Size[0] || w.Pos[1] >= l.Size[1] {
+			t.Errorf("widget %d (%s) is at %v, outside the board", i, w.Type, *w.Pos)
+		}
+		if w.Size != nil && (w.Pos[0]+w.Size[0] > l.Size[0] || w.Pos[1]+w.Size[1] > l.Size[1]) {
+			t.Errorf("outline %d (%s) extends beyond the board", i, w.Label)
+		}
+	}
+
+	n := len(l.Widgets)
+	l.RemoveOutlines()
+	if l.Has("outline") || len(l.Widgets) == n {
+		t.Errorf("RemoveOutlines() left %d of %d widgets, including outlines", len(l.Widgets), n)
+	}
+	if err := l.Validate(); err != nil {
+		t.Errorf("the skin without outlines is invalid: %v", err)
+	}
+}
+
+func TestDE2115Image(t *testing.T) {
+	img := DE2115Image()
+	if b := img.Bounds(); b.Dx() != 1200 || b.Dy() != 780 {
+		t.Fatalf("the image is %dx%d, expected 1200x780", b.Dx(), b.Dy())
+	}
+
+	// each outline on the skin has a part drawn under it
+	l := SkinDE2115()
+	for _, w := range l.Widgets {
+		if w.Type != "outline" {
+			continue
+		}
+
+		x, y := int(w.Pos[0]+w.Size[0]/2), int(w.Pos[1]+w.Size[1]/2)
+		if img.At(x, y) == imagePCB {
+			t.Errorf("nothing is drawn at the center of %s (%d, %d)", w.Label, x, y)
+		}
+	}
+
+	// and the rest is a circuit board
+	if img.At(300, 120) != imagePCB || img.At(0, 0) != imagePCBEdge {
+		t.Errorf("the board is %v, with the edge %v", img.At(300, 120), img.At(0, 0))
+	}
+}
@@ -0,0 +1,59 @@
+package layout
+
+// pos returns a pointer to a position, for use in Widget.Pos
+func pos(x, y float32) *[2]float32 {
+	return &[2]float32{x, y}
+}
+
+// outline returns an outline widget of the given size
+func outline(label string, x, y, w, h float32) Widget {
+	return Widget{Type: "outline", Label: label, Pos: pos(x, y), Size: &[2]float32{w, h}}
+}
+
+// SkinDE2115 returns a layout which places the widgets roughly where the
+// corresponding parts are on a physical DE2-115, with the switches, red
+// LEDs and KEYs along the bottom edge, the green LEDs above the KEYs, and
+// the HEX displays and LCD above them. The controls are placed in the
+// otherwise empty top left corner of the board.
+//
+// The board is 1200x780, and its Background is BackgroundDE2115, an image
+// of the board drawn by DE2115Image() with the FPGA, the memory chips and
+// the connectors along the edges, which are also labeled by outlines. It is
+// a drawing, not a photograph: de2gui does not ship one. A photograph of
+// the board, cropped to the edges of the PCB, can be used instead by
+// setting Background to its path; it is stretched to fit, and the outlines
+// should then be removed with RemoveOutlines().
+func SkinDE2115() *Layout {
+	l := &Layout{
+		Name:       "DE2-115",
+		Size:       &[2]float32{1200, 780},
+		Background: BackgroundDE2115,
+	}
+
+	// the outlines are listed first, so that they are drawn behind
+	// everything else
+	for _, p := range de2115Parts {
+		l.Widgets = append(l.Widgets, outline(p.label, float32(p.x), float32(p.y), float32(p.w), float32(p.h)))
+	}
+
+	l.Widgets = append(l.Widgets, []Widget{
+		{Type: "ticks", Pos: pos(16, 16)},
+		{Type: "session", Pos: pos(16, 60)},
+		{Type: "vectors", Pos: pos(16, 104)},
+		{Type: "bounce", Pos: pos(16, 148)},
+		{Type: "faults", Pos: pos(16, 192)},
+		{Type: "label", Label: "DE2-115", Pos: pos(880, 400)},
+
+		{Type: "lcd", Pos: pos(60, 420)},
+		{Type: "hex", Count: 8, Pos: pos(330, 400)},
+
+		// the LEDs are offset by 2 so that each is centered
+		// over its switch
+		{Type: "ledg", Count: 9, Pitch: 24, Pos: pos(782, 560)},
+		{Type: "ledr", Count: 18, Pitch: 24, Pos: pos(152, 600)},
+		{Type: "sw", Count: 18, Pitch: 24, Pos: pos(150, 640)},
+		{Type: "key", Count: 4, Pos: pos(780, 620)},
+	}...)
+
+	return l
+}
@@ -0,0 +1,37 @@
+package de2gui
+
+import (
+	"testing"
+
+	"github.com/herclab/de2gui/de2gui/layout"
+)
+
+func TestLayoutProfile(t *testing.T) {
+	l := &layout.Layout{
+		Name: "lab",
+		Widgets: []layout.Widget{
+			{Type: "hex", Count: 2},
+			{Type: "sw", Count: 4},
+			{Type: "label", Label: "KEY0 resets"},
+			{Type: "key", Count: 1},
+		},
+	}
+
+	want := BoardProfile{Name: "lab", HEX: 2, SW: 4, KEY: 1}
+	if p := LayoutProfile(l); p != want {
+		t.Errorf("LayoutProfile() = %+v, expected %+v", p, want)
+	}
+}
+
+func TestDefaultLayout(t *testing.T) {
+	for _, p := range Profiles {
+		l := defaultLayout(p)
+		if err := l.Validate(); err != nil {
+			t.Errorf("%s: the default layout is invalid: %v", p.Name, err)
+		}
+
+		if lp := LayoutProfile(l); lp != p {
+			t.Errorf("%s: the default layout is for %+v", p.Name, lp)
+		}
+	}
+}
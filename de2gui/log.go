@@ -0,0 +1,414 @@
+package de2gui
+
+import (
+	"bufio"
+	"fmt"
+	"image/color"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/canvas"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+)
+
+// Level is the severity of a message in the log, see Logf().
+type Level int
+
+const (
+	// LevelDebug is for detail which is usually hidden, such as stack
+	// traces.
+	LevelDebug Level = iota
+
+	// LevelInfo is for ordinary messages, such as $display output.
+	LevelInfo
+
+	// LevelWarning is for problems which do not stop the simulation, such
+	// as a test vector mismatch.
+	LevelWarning
+
+	// LevelError is for problems which do, such as a panic in a callback.
+	LevelError
+)
+
+var levelNames = []string{"debug", "info", "warning", "error"}
+
+// String returns the name of the level, such as "warning".
+func (l Level) String() string {
+	if l < 0 || int(l) >= len(levelNames) {
+		return fmt.Sprintf("level%d", int(l))
+	}
+	return levelNames[l]
+}
+
+// LogLimit is the number of messages kept in the log. Once it is exceeded,
+// the oldest tenth of the messages is discarded.
+var LogLimit = 10000
+
+// logRefreshInterval is the longest a message waits to be shown in the log
+// panel. Messages are shown in batches, since refreshing the panel for each
+// one is slow when a design logs on every tick.
+const logRefreshInterval = 100 * time.Millisecond
+
+// LogEntry is a message in the log.
+type LogEntry struct {
+	// Tick is the tick being run when the message was logged, or the
+	// value of the Tick field if it was logged between ticks.
+	Tick uint64
+
+	Level   Level
+	Message string
+}
+
+// String formats the entry as it is shown in the log panel, and saved.
+func (e LogEntry) String() string {
+	return fmt.Sprintf("[%d] %s: %s", e.Tick, e.Level, e.Message)
+}
+
+// boardLog holds the messages in the log, and the state of the log panel
+type boardLog struct {
+	// mutex guards the fields below, since messages may be logged from
+	// any goroutine
+	mutex   sync.Mutex
+	entries []LogEntry
+
+	// shown holds the indices in entries of the messages which pass the
+	// filter in the log panel
+	shown  []int
+	level  Level
+	search string
+
+	// refreshing is true while a refresh of the log panel is scheduled
+	refreshing bool
+
+	list *logList
+	item *widget.AccordionItem
+	acc  *widget.Accordion
+}
+
+// show opens the log panel, if it is closed
+func (l *boardLog) show() {
+	if l.acc != nil {
+		l.acc.Open(0)
+	}
+}
+
+// logList is the list of messages in the log panel, which keeps track of
+// its scroller, so that it can tell whether it is scrolled to the bottom
+type logList struct {
+	widget.List
+
+	scroller *container.Scroll
+}
+
+// newLogList creates the list of messages in the log panel
+func newLogList(length func() int, create func() fyne.CanvasObject, update func(widget.ListItemID, fyne.CanvasObject)) *logList {
+	l := &logList{}
+	l.Length = length
+	l.CreateItem = create
+	l.UpdateItem = update
+	l.ExtendBaseWidget(l)
+	return l
+}
+
+// CreateRenderer implements fyne.Widget
+func (l *logList) CreateRenderer() fyne.WidgetRenderer {
+	r := l.List.CreateRenderer()
+	for _, o := range r.Objects() {
+		if scroller, ok := o.(*container.Scroll); ok {
+			l.scroller = scroller
+		}
+	}
+	return r
+}
+
+// atBottom returns true if the newest message is in view, or the list has
+// not been shown yet
+func (l *logList) atBottom() bool {
+	if l.scroller == nil || l.scroller.Content == nil {
+		return true
+	}
+
+	// allow for part of a message being cut off
+	slack := l.scroller.Size().Height / 10
+	bottom := l.scroller.Offset.Y + l.scroller.Size().Height
+	return bottom+slack >= l.scroller.Content.Size().Height
+}
+
+// Logf adds a message to the log, stamped with the current tick, see
+// LogEntry. Messages containing several lines are split into one message
+// per line. The log is shown in a panel at the bottom of the window, where
+// it can be filtered by level, searched, and saved. Each message is also
+// written to LogOutput, or to os.Stderr for a headless UIState if LogOutput
+// is nil.
+//
+// Logf may be called from any goroutine.
+func (s *UIState) Logf(level Level, format string, args ...interface{}) {
+	tick := atomic.LoadUint64(&s.loggedTick)
+
+	msg := strings.TrimRight(fmt.Sprintf(format, args...), "\n")
+	for _, line := range strings.Split(msg, "\n") {
+		s.addLogEntry(LogEntry{Tick: tick, Level: level, Message: line})
+	}
+}
+
+// LogWriter returns a writer which adds each line written to it to the log
+// at the given level, see Logf(). The output of a design, such as that of
+// $display in a Verilated model, can be shown to the user by redirecting it
+// to a LogWriter. A final line without a newline is logged when the writer
+// is closed.
+func (s *UIState) LogWriter(level Level) io.WriteCloser {
+	return &logWriter{s: s, level: level}
+}
+
+// logWriter adds the lines written to it to the log, see LogWriter()
+type logWriter struct {
+	s     *UIState
+	level Level
+
+	mutex sync.Mutex
+
+	// partial is the last line, if it is not yet complete
+	partial string
+}
+
+// Write implements io.Writer
+func (w *logWriter) Write(p []byte) (int, error) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	lines := strings.Split(w.partial+string(p), "\n")
+	w.partial = lines[len(lines)-1]
+
+	for _, line := range lines[:len(lines)-1] {
+		w.s.Logf(w.level, "%s", strings.TrimSuffix(line, "\r"))
+	}
+
+	return len(p), nil
+}
+
+// Close implements io.Closer
+func (w *logWriter) Close() error {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	if w.partial != "" {
+		w.s.Logf(w.level, "%s", w.partial)
+		w.partial = ""
+	}
+	return nil
+}
+
+// Log returns the messages in the log, oldest first.
+func (s *UIState) Log() []LogEntry {
+	s.log.mutex.Lock()
+	defer s.log.mutex.Unlock()
+
+	return append([]LogEntry{}, s.log.entries...)
+}
+
+// ClearLog discards every message in the log.
+func (s *UIState) ClearLog() {
+	s.log.mutex.Lock()
+	s.log.entries = nil
+	s.log.shown = nil
+	s.log.mutex.Unlock()
+
+	s.refreshLog()
+}
+
+// SaveLog writes every message in the log to w, one per line, regardless of
+// any filter in the log panel.
+func (s *UIState) SaveLog(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	for _, e := range s.Log() {
+		if _, err := fmt.Fprintln(bw, e); err != nil {
+			return err
+		}
+	}
+	return bw.Flush()
+}
+
+// Internal function which adds an entry to the log, and writes it to
+// LogOutput
+func (s *UIState) addLogEntry(e LogEntry) {
+	out := s.LogOutput
+	if out == nil && s.headless {
+		out = os.Stderr
+	}
+	if out != nil {
+		fmt.Fprintln(out, e)
+	}
+
+	l := &s.log
+	l.mutex.Lock()
+
+	l.entries = append(l.entries, e)
+	if len(l.entries) > LogLimit {
+		drop := len(l.entries) - LogLimit + LogLimit/10
+		l.entries = append([]LogEntry{}, l.entries[drop:]...)
+		l.filter()
+	} else if l.matches(e) {
+		l.shown = append(l.shown, len(l.entries)-1)
+	}
+
+	schedule := !s.headless && !l.refreshing
+	if schedule {
+		l.refreshing = true
+	}
+
+	l.mutex.Unlock()
+
+	if schedule {
+		time.AfterFunc(logRefreshInterval, s.refreshLog)
+	}
+}
+
+// matches returns true if e passes the filter in the log panel
+func (l *boardLog) matches(e LogEntry) bool {
+	if e.Level < l.level {
+		return false
+	}
+	return l.search == "" || strings.Contains(strings.ToLower(e.String()), l.search)
+}
+
+// filter works out which messages pass the filter in the log panel. The
+// mutex must be held.
+func (l *boardLog) filter() {
+	l.shown = l.shown[:0]
+	for i, e := range l.entries {
+		if l.matches(e) {
+			l.shown = append(l.shown, i)
+		}
+	}
+}
+
+// Internal function which changes the filter in the log panel
+func (s *UIState) filterLog(level Level, search string) {
+	s.log.mutex.Lock()
+	s.log.level = level
+	s.log.search = strings.ToLower(search)
+	s.log.filter()
+	s.log.mutex.Unlock()
+
+	s.refreshLog()
+}
+
+// Internal function which shows the messages in the log panel. If it was
+// scrolled to the newest message, it scrolls to the new newest message, but
+// otherwise it is left where the user scrolled it.
+func (s *UIState) refreshLog() {
+	if s.headless {
+		return
+	}
+
+	s.log.mutex.Lock()
+	n, total := len(s.log.shown), len(s.log.entries)
+	s.log.refreshing = false
+	s.log.mutex.Unlock()
+
+	s.log.item.Title = fmt.Sprintf("Log (%d)", total)
+	s.log.acc.Refresh()
+
+	follow := s.log.list.atBottom()
+	s.log.list.Refresh()
+	if follow && n > 0 {
+		// selecting an item scrolls to it
+		s.log.list.Select(n - 1)
+		s.log.list.Unselect(n - 1)
+	}
+}
+
+// Internal function which creates the log panel
+func (s *UIState) newLogPanel() fyne.CanvasObject {
+	l := &s.log
+	l.level = LevelInfo
+
+	l.list = newLogList(
+		func() int {
+			l.mutex.Lock()
+			defer l.mutex.Unlock()
+			return len(l.shown)
+		},
+		func() fyne.CanvasObject {
+			return widget.NewLabelWithStyle("", fyne.TextAlignLeading, fyne.TextStyle{Monospace: true})
+		},
+		func(id widget.ListItemID, o fyne.CanvasObject) {
+			l.mutex.Lock()
+			text := ""
+			if id < len(l.shown) {
+				text = l.entries[l.shown[id]].String()
+			}
+			l.mutex.Unlock()
+
+			o.(*widget.Label).SetText(text)
+		},
+	)
+
+	search := widget.NewEntry()
+	search.SetPlaceHolder("Search")
+
+	level := widget.NewSelect(levelNames, nil)
+	level.SetSelected(LevelInfo.String())
+
+	update := func() {
+		s.filterLog(Level(level.SelectedIndex()), search.Text)
+	}
+	level.OnChanged = func(string) { update() }
+	search.OnChanged = func(string) { update() }
+
+	controls := container.NewBorder(nil, nil,
+		container.NewHBox(widget.NewLabel("Show"), level, widget.NewLabel("and above")),
+		container.NewHBox(
+			widget.NewButton("Clear", func() { s.ClearLog() }),
+			widget.NewButton("Save...", func() { s.saveLog() }),
+		),
+		search,
+	)
+
+	// the list is only as tall as a single message unless it is given a
+	// minimum size
+	space := canvas.NewRectangle(color.Transparent)
+	space.SetMinSize(fyne.NewSize(0, 160))
+
+	l.item = widget.NewAccordionItem("Log (0)", container.NewBorder(controls, nil, nil, nil,
+		container.NewMax(space, l.list)))
+	l.acc = widget.NewAccordion(l.item)
+
+	return l.acc
+}
+
+// Internal function wired into the log "Save..." button
+func (s *UIState) saveLog() {
+	w := s.window()
+	if w == nil {
+		return
+	}
+
+	dialog.ShowFileSave(func(f fyne.URIWriteCloser, err error) {
+		if err != nil {
+			dialog.ShowError(err, w)
+			return
+		}
+
+		if f == nil {
+			// canceled
+			return
+		}
+
+		err = s.SaveLog(f)
+		if err2 := f.Close(); err == nil {
+			err = err2
+		}
+
+		if err != nil {
+			dialog.ShowError(err, w)
+		}
+	}, w)
+}
@@ -0,0 +1,162 @@
+package de2gui
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"reflect"
+	"testing"
+)
+
+// logMessages returns the messages in the log of s, formatted as for
+// SaveLog()
+func logMessages(s *UIState) []string {
+	messages := []string{}
+	for _, e := range s.Log() {
+		messages = append(messages, e.String())
+	}
+	return messages
+}
+
+func TestLogf(t *testing.T) {
+	s := NewHeadlessUIState()
+	var out bytes.Buffer
+	s.LogOutput = &out
+
+	s.OnTick = func(s *UIState, final bool) {
+		s.Logf(LevelDebug, "ticking")
+		s.Tick++
+	}
+
+	s.Logf(LevelInfo, "first\nsecond\n")
+	s.RunTicks(2)
+	s.Logf(LevelWarning, "after %d ticks", s.Tick)
+	s.Logf(Level(7), "odd")
+
+	want := []string{
+		"[0] info: first",
+		"[0] info: second",
+		"[0] debug: ticking",
+		"[1] debug: ticking",
+		"[2] warning: after 2 ticks",
+		"[2] level7: odd",
+	}
+	if got := logMessages(s); !reflect.DeepEqual(got, want) {
+		t.Errorf("the log is %q, expected %q", got, want)
+	}
+
+	var saved bytes.Buffer
+	if err := s.SaveLog(&saved); err != nil {
+		t.Fatal(err)
+	}
+	if saved.String() != out.String() || saved.Len() == 0 {
+		t.Errorf("SaveLog() wrote %q, and LogOutput %q", saved.String(), out.String())
+	}
+
+	s.ClearLog()
+	if n := len(s.Log()); n != 0 {
+		t.Errorf("%d messages after ClearLog()", n)
+	}
+}
+
+func TestLogLimit(t *testing.T) {
+	defer func(limit int) { LogLimit = limit }(LogLimit)
+	LogLimit = 20
+
+	s := NewHeadlessUIState()
+	s.LogOutput = ioutil.Discard
+	for i := 0; i < 21; i++ {
+		s.Logf(LevelInfo, "%d", i)
+	}
+
+	// the oldest tenth is dropped when the limit is passed
+	log := s.Log()
+	if len(log) != 18 || log[0].Message != "3" || log[17].Message != "20" {
+		t.Errorf("the log is %v, expected messages 3 to 20", log)
+	}
+}
+
+func TestLogFilter(t *testing.T) {
+	s := NewHeadlessUIState()
+	s.LogOutput = ioutil.Discard
+
+	s.Logf(LevelDebug, "stack")
+	s.Logf(LevelInfo, "Hello")
+	s.Logf(LevelWarning, "mismatch")
+	s.Logf(LevelError, "hello again")
+
+	tests := []struct {
+		level  Level
+		search string
+		want   []int
+	}{
+		{LevelDebug, "", []int{0, 1, 2, 3}},
+		{LevelWarning, "", []int{2, 3}},
+		{LevelDebug, "HELLO", []int{1, 3}},
+		{LevelInfo, "warning", []int{2}},
+		{LevelError, "mismatch", []int{}},
+	}
+
+	for _, test := range tests {
+		s.filterLog(test.level, test.search)
+		if !reflect.DeepEqual(s.log.shown, test.want) {
+			t.Errorf("filterLog(%s, %q) shows %v, expected %v", test.level, test.search, s.log.shown, test.want)
+		}
+	}
+}
+
+func TestLogWriter(t *testing.T) {
+	s := NewHeadlessUIState()
+	s.LogOutput = ioutil.Discard
+
+	w := s.LogWriter(LevelWarning)
+	for _, p := range []string{"one\r\ntw", "o\n", "", "thr", "ee\nfour"} {
+		if n, err := w.Write([]byte(p)); n != len(p) || err != nil {
+			t.Errorf("Write(%q) = %d, %v", p, n, err)
+		}
+	}
+
+	// the partial line is only logged once the writer is closed
+	want := []string{"[0] warning: one", "[0] warning: two", "[0] warning: three"}
+	if got := logMessages(s); !reflect.DeepEqual(got, want) {
+		t.Errorf("the log is %q, expected %q", got, want)
+	}
+
+	w.Close()
+	want = append(want, "[0] warning: four")
+	if got := logMessages(s); !reflect.DeepEqual(got, want) {
+		t.Errorf("the log is %q after Close(), expected %q", got, want)
+	}
+}
+
+// TestLogWhileTicking logs from another goroutine while ticking. Run with
+// -race.
+func TestLogWhileTicking(t *testing.T) {
+	s := newCounterBoard()
+
+	done := make(chan bool)
+	go func() {
+		for i := 0; i < 100; i++ {
+			s.Logf(LevelInfo, "%d", i)
+		}
+		close(done)
+	}()
+
+	s.RunTicks(100)
+	<-done
+
+	if n := len(s.Log()); n != 100 {
+		t.Errorf("%d messages logged, expected 100", n)
+	}
+	for _, e := range s.Log() {
+		if e.Tick > 100 {
+			t.Errorf("%v is stamped with a tick which was not run", e)
+		}
+	}
+
+	// messages are stamped with the tick once ticking stops
+	s.Logf(LevelInfo, "done")
+	if log := s.Log(); log[len(log)-1].String() != fmt.Sprintf("[%d] info: done", s.Tick) {
+		t.Errorf("the last message is %v, expected it to be at tick %d", log[len(log)-1], s.Tick)
+	}
+}
@@ -0,0 +1,117 @@
+package de2gui
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/herclab/de2gui/de2gui/session"
+)
+
+// BoardProfile describes the inputs and outputs of a development board, see
+// NewUIStateFor(). Signals are numbered from 0, which is the rightmost on
+// the board, as on the DE2-115.
+type BoardProfile struct {
+	// Name is the name of the board, for example "DE2-115".
+	Name string
+
+	// HEX is the number of seven-segment displays.
+	HEX int
+
+	// LEDR and LEDG are the number of red and green LEDs. Boards without
+	// any green LEDs have an LEDG of 0.
+	LEDR int
+	LEDG int
+
+	// SW and KEY are the number of slide switches and push buttons.
+	SW  int
+	KEY int
+}
+
+// ProfileDE2115 describes the Terasic DE2-115, which is the default.
+var ProfileDE2115 = BoardProfile{Name: "DE2-115", HEX: 8, LEDR: 18, LEDG: 9, SW: 18, KEY: 4}
+
+// ProfileDE1SoC describes the Terasic DE1-SoC.
+var ProfileDE1SoC = BoardProfile{Name: "DE1-SoC", HEX: 6, LEDR: 10, LEDG: 0, SW: 10, KEY: 4}
+
+// ProfileDE0CV describes the Terasic DE0-CV.
+var ProfileDE0CV = BoardProfile{Name: "DE0-CV", HEX: 6, LEDR: 10, LEDG: 0, SW: 10, KEY: 4}
+
+// ProfileDE10Lite describes the Terasic DE10-Lite.
+var ProfileDE10Lite = BoardProfile{Name: "DE10-Lite", HEX: 6, LEDR: 10, LEDG: 0, SW: 10, KEY: 2}
+
+// Profiles contains all of the built-in board profiles.
+var Profiles = []BoardProfile{ProfileDE2115, ProfileDE1SoC, ProfileDE0CV, ProfileDE10Lite}
+
+// maxSignals is the most of any one kind of signal a board may have, since
+// their states are stored in a uint32
+const maxSignals int = 32
+
+// LookupProfile returns the built-in profile with the given name. Case, and
+// any '-' or '_' characters, are ignored, so "de10lite" finds the
+// DE10-Lite.
+func LookupProfile(name string) (BoardProfile, error) {
+	normalize := func(s string) string {
+		s = strings.ToLower(s)
+		s = strings.Replace(s, "-", "", -1)
+		return strings.Replace(s, "_", "", -1)
+	}
+
+	for _, p := range Profiles {
+		if normalize(p.Name) == normalize(name) {
+			return p, nil
+		}
+	}
+
+	names := make([]string, len(Profiles))
+	for i, p := range Profiles {
+		names[i] = p.Name
+	}
+	return BoardProfile{}, fmt.Errorf("unknown board '%s', expected one of %s", name, strings.Join(names, ", "))
+}
+
+// Validate returns an error if the profile describes a board which de2gui
+// cannot display, for example one with more than 32 LEDs in a group.
+func (p BoardProfile) Validate() error {
+	counts := []struct {
+		name string
+		n    int
+	}{
+		{"HEX", p.HEX}, {"LEDR", p.LEDR}, {"LEDG", p.LEDG}, {"SW", p.SW}, {"KEY", p.KEY},
+	}
+
+	for _, c := range counts {
+		if c.n < 0 || c.n > maxSignals {
+			return fmt.Errorf("board %s: invalid number of %s (%d)", p.Name, c.name, c.n)
+		}
+	}
+
+	return nil
+}
+
+// session returns the description of the board used in recorded sessions
+func (p BoardProfile) session() *session.Board {
+	return &session.Board{
+		Name: p.Name,
+		HEX:  p.HEX,
+		LEDR: p.LEDR,
+		LEDG: p.LEDG,
+		SW:   p.SW,
+		KEY:  p.KEY,
+	}
+}
+
+// bitmask returns a mask with the lowest n bits set
+func bitmask(n int) uint32 {
+	return uint32((uint64(1) << uint(n)) - 1)
+}
+
+// formatBits formats v in hexadecimal, padded to the number of digits
+// needed for n bits
+func formatBits(v uint32, n int) string {
+	return fmt.Sprintf("0x%0*x", (n+3)/4, v)
+}
+
+// Profile returns the profile of the board this UIState was created for.
+func (s *UIState) Profile() BoardProfile {
+	return s.profile
+}
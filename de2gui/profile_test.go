@@ -0,0 +1,125 @@
+package de2gui
+
+import (
+	"testing"
+
+	"github.com/herclab/de2gui/de2gui/script"
+	"github.com/herclab/de2gui/de2gui/session"
+)
+
+func TestLookupProfile(t *testing.T) {
+	tests := []struct {
+		name string
+		want BoardProfile
+	}{
+		{"DE2-115", ProfileDE2115},
+		{"de2115", ProfileDE2115},
+		{"DE1_SoC", ProfileDE1SoC},
+		{"de0-cv", ProfileDE0CV},
+		{"DE10LITE", ProfileDE10Lite},
+	}
+
+	for _, test := range tests {
+		p, err := LookupProfile(test.name)
+		if err != nil || p != test.want {
+			t.Errorf("LookupProfile(%q) = %+v, %v, expected %s", test.name, p, err, test.want.Name)
+		}
+	}
+
+	_, err := LookupProfile("DE0-Nano")
+	if err == nil || err.Error() != "unknown board 'DE0-Nano', expected one of DE2-115, DE1-SoC, DE0-CV, DE10-Lite" {
+		t.Errorf("LookupProfile(\"DE0-Nano\") returned the error %v", err)
+	}
+}
+
+func TestValidateProfile(t *testing.T) {
+	for _, p := range Profiles {
+		if err := p.Validate(); err != nil {
+			t.Errorf("%s: %v", p.Name, err)
+		}
+	}
+
+	p := BoardProfile{Name: "wide", HEX: 1, LEDR: 33}
+	if err := p.Validate(); err == nil || err.Error() != "board wide: invalid number of LEDR (33)" {
+		t.Errorf("Validate() returned the error %v", err)
+	}
+
+	p = BoardProfile{Name: "negative", KEY: -1}
+	if err := p.Validate(); err == nil {
+		t.Errorf("Validate() accepted %+v", p)
+	}
+}
+
+func TestFormatBits(t *testing.T) {
+	tests := []struct {
+		v    uint32
+		n    int
+		want string
+	}{
+		{0x5, 18, "0x00005"},
+		{0x1ff, 9, "0x1ff"},
+		{0x3, 4, "0x3"},
+		{0xffffffff, 32, "0xffffffff"},
+	}
+
+	for _, test := range tests {
+		if got := formatBits(test.v, test.n); got != test.want {
+			t.Errorf("formatBits(%#x, %d) = %q, expected %q", test.v, test.n, got, test.want)
+		}
+	}
+
+	if bitmask(32) != 0xffffffff || bitmask(0) != 0 || bitmask(10) != 0x3ff {
+		t.Errorf("bitmask() = %#x, %#x and %#x for 32, 0 and 10 bits", bitmask(32), bitmask(0), bitmask(10))
+	}
+}
+
+func TestProfileSignals(t *testing.T) {
+	s := NewHeadlessUIStateFor(ProfileDE10Lite)
+	if s.Profile() != ProfileDE10Lite {
+		t.Errorf("Profile() = %+v", s.Profile())
+	}
+
+	// signals which the board does not have are ignored
+	s.StartRecording()
+	s.SetSW(0xffff)
+	s.SetLEDR(0xffff)
+	s.SetLEDG(0x1)
+	s.SetHEX(6, 0)
+	s.HoldKEY(2)
+	if s.SW() != 0x3ff || s.LEDR() != 0x3ff || s.LEDG() != 0 || s.HEX(6) != 0xff || s.KEY() != 0 {
+		t.Errorf("SW() = %#x, LEDR() = %#x, LEDG() = %#x, HEX(6) = %#x and KEY() = %#x",
+			s.SW(), s.LEDR(), s.LEDG(), s.HEX(6), s.KEY())
+	}
+
+	board := s.StopRecording().Board
+	want := session.Board{Name: "DE10-Lite", HEX: 6, LEDR: 10, LEDG: 0, SW: 10, KEY: 2}
+	if board == nil || *board != want {
+		t.Errorf("the session was recorded for %+v, expected %+v", board, want)
+	}
+
+	tests := []struct {
+		line string
+		err  string
+	}{
+		{"key 2", "there is no KEY2"},
+		{"sw 10 on", "there is no SW10"},
+		{"expect hex 1234567", "there are only 6 HEX displays"},
+		{"expect hex6 0x7f", "there is no HEX6"},
+		{"expect hex 123456", ""},
+	}
+
+	for _, test := range tests {
+		c, err := script.ParseLine(test.line)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		_, err = s.Exec(*c)
+		switch {
+		case test.err == "" && err != nil:
+			t.Errorf("%s: returned the error %v", test.line, err)
+		case test.err != "" && (err == nil || err.Error() != test.err):
+			t.Errorf("%s: returned the error %v, expected %q", test.line, err, test.err)
+		}
+	}
+}
@@ -0,0 +1,55 @@
+package qsf
+
+// Board is the table of pins of a development board which are connected to
+// de2gui peripherals.
+type Board struct {
+	// Name is the name of the board, for example "DE2-115".
+	Name string
+
+	// Pins maps each FPGA pin, as written in a .qsf file (PIN_G19), to the
+	// signal it is connected to on the board.
+	Pins map[string]Signal
+}
+
+// DE2115 contains the pins of the Terasic DE2-115, as listed in its user
+// manual.
+var DE2115 = &Board{Name: "DE2-115", Pins: pins(
+	bus("SW",
+		"AB28", "AC28", "AC27", "AD27", "AB27", "AC26", "AD26", "AB26", "AC25",
+		"AB25", "AC24", "AB24", "AB23", "AA24", "AA23", "AA22", "Y24", "Y23"),
+	bus("KEY", "M23", "M21", "N21", "R24"),
+	bus("LEDR",
+		"G19", "F19", "E19", "F21", "F18", "E18", "J19", "H19", "J17",
+		"G17", "J15", "H16", "J16", "H17", "F15", "G15", "G16", "H15"),
+	bus("LEDG", "E21", "E22", "E25", "E24", "H21", "G20", "G22", "G21", "F17"),
+	bus("HEX0", "G18", "F22", "E17", "L26", "L25", "J22", "H22"),
+	bus("HEX1", "M24", "Y22", "W21", "W22", "W25", "U23", "U24"),
+	bus("HEX2", "AA25", "AA26", "Y25", "W26", "Y26", "W27", "W28"),
+	bus("HEX3", "V21", "U21", "AB20", "AA21", "AD24", "AF23", "Y19"),
+	bus("HEX4", "AB19", "AA19", "AG21", "AH21", "AE19", "AF19", "AE18"),
+	bus("HEX5", "AD18", "AC18", "AB18", "AH19", "AG19", "AF18", "AH18"),
+	bus("HEX6", "AA17", "AB16", "AA16", "AB17", "AB15", "AA15", "AC17"),
+	bus("HEX7", "AD17", "AE17", "AG17", "AH17", "AF17", "AG18", "AA14"),
+	map[string]Signal{"PIN_Y2": {Name: "CLOCK_50", Bit: -1}},
+)}
+
+// bus returns the pins of a bus, given the location of each bit, starting
+// at bit 0
+func bus(name string, locations ...string) map[string]Signal {
+	m := map[string]Signal{}
+	for i, v := range locations {
+		m["PIN_"+v] = Signal{Name: name, Bit: i}
+	}
+	return m
+}
+
+// pins merges pin tables
+func pins(tables ...map[string]Signal) map[string]Signal {
+	m := map[string]Signal{}
+	for _, t := range tables {
+		for k, v := range t {
+			m[k] = v
+		}
+	}
+	return m
+}
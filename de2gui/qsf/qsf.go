@@ -0,0 +1,302 @@
+// Package qsf reads the pin assignments in a Quartus settings file (.qsf),
+// so that the ports of a design can be connected to the de2gui peripherals
+// they are wired to on the physical board. Only lines of the form
+//
+//	set_location_assignment PIN_G19 -to LEDR[0]
+//
+// are used; everything else in the file is ignored. The target may be quoted
+// or enclosed in braces, and may name a single bit of a bus, as above, or a
+// scalar port.
+//
+// Map() looks up the pin of each assignment in a Board, such as DE2115,
+// giving the peripheral signal connected to each bit of each port. Since
+// most projects start from the board's own pin list, ports are usually named
+// after the signals they are connected to; Map() reports assignments where
+// this is not the case, for example LEDR[3] assigned to the pin of LEDR[2],
+// as problems, as well as pins which are assigned more than once.
+// Mapping.Check() reports bits of ports which are not assigned at all.
+package qsf
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Signal is a single bit of a signal on the board, or of a port of the
+// design.
+type Signal struct {
+	// Name is the name of the signal, for example "LEDR" or "HEX3".
+	Name string
+
+	// Bit is the index of the bit within the signal, or -1 if the signal
+	// is not a bus.
+	Bit int
+}
+
+// String returns the signal as written in a .qsf file, for example
+// "LEDR[3]".
+func (s Signal) String() string {
+	if s.Bit < 0 {
+		return s.Name
+	}
+	return fmt.Sprintf("%s[%d]", s.Name, s.Bit)
+}
+
+// parseSignal parses a signal written as NAME or NAME[bit]
+func parseSignal(s string) (Signal, error) {
+	open := strings.Index(s, "[")
+	if open < 0 {
+		if s == "" {
+			return Signal{}, fmt.Errorf("missing port name")
+		}
+		return Signal{Name: s, Bit: -1}, nil
+	}
+
+	bit, err := strconv.Atoi(strings.TrimSuffix(s[open+1:], "]"))
+	if err != nil || open == 0 || !strings.HasSuffix(s, "]") || bit < 0 {
+		return Signal{}, fmt.Errorf("invalid port '%s'", s)
+	}
+
+	return Signal{Name: s[:open], Bit: bit}, nil
+}
+
+// Assignment is a single set_location_assignment.
+type Assignment struct {
+	// Line is the line of the file the assignment appeared on.
+	Line int
+
+	// Location is the pin, for example "PIN_G19".
+	Location string
+
+	// Port is the port bit assigned to the pin.
+	Port Signal
+}
+
+// File is a parsed .qsf file.
+type File struct {
+	// Name is the name of the file, used in messages.
+	Name string
+
+	Assignments []Assignment
+}
+
+// unquote removes the quotes or braces around a Tcl word
+func unquote(w string) string {
+	if len(w) >= 2 && (w[0] == '"' && w[len(w)-1] == '"' || w[0] == '{' && w[len(w)-1] == '}') {
+		return w[1 : len(w)-1]
+	}
+	return w
+}
+
+// Parse reads a .qsf file from r. The name is used in error messages.
+func Parse(name string, r io.Reader) (*File, error) {
+	f := &File{Name: name}
+
+	scanner := bufio.NewScanner(r)
+	lineno := 0
+	for scanner.Scan() {
+		lineno++
+		errorf := func(format string, args ...interface{}) error {
+			return fmt.Errorf("%s:%d: %s", name, lineno, fmt.Sprintf(format, args...))
+		}
+
+		words := strings.Fields(scanner.Text())
+		if len(words) == 0 || words[0] != "set_location_assignment" {
+			continue
+		}
+
+		a := Assignment{Line: lineno}
+		target := ""
+		for i := 1; i < len(words); i++ {
+			switch {
+			case words[i] == "-to" && i+1 < len(words):
+				i++
+				target = unquote(words[i])
+
+			case strings.HasPrefix(words[i], "-"):
+				// other options, such as -comment, take a value
+				i++
+
+			case a.Location == "":
+				a.Location = strings.ToUpper(unquote(words[i]))
+			}
+		}
+
+		if a.Location == "" || target == "" {
+			return nil, errorf("expected set_location_assignment PIN_xx -to port")
+		}
+
+		port, err := parseSignal(strings.TrimPrefix(target, "\\"))
+		if err != nil {
+			return nil, errorf("%v", err)
+		}
+		a.Port = port
+
+		f.Assignments = append(f.Assignments, a)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return f, nil
+}
+
+// ParseFile reads a .qsf file from path, see Parse().
+func ParseFile(path string) (*File, error) {
+	fp, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer fp.Close()
+
+	return Parse(path, fp)
+}
+
+// Problem is a pin assignment which is likely to be a mistake.
+type Problem struct {
+	// Line is the line of the .qsf file the assignment appeared on, or 0
+	// if the problem is a missing assignment.
+	Line int
+
+	// Port is the port bit concerned.
+	Port Signal
+
+	Message string
+}
+
+// String returns the problem as a message for the user.
+func (p Problem) String() string {
+	if p.Line == 0 {
+		return fmt.Sprintf("%s: %s", p.Port, p.Message)
+	}
+	return fmt.Sprintf("line %d: %s: %s", p.Line, p.Port, p.Message)
+}
+
+// Mapping connects the bits of the ports of a design to the signals of a
+// board, as given by a .qsf file.
+type Mapping struct {
+	// Board is the board the pins were looked up in.
+	Board *Board
+
+	// Signals maps each port bit to the board signal its pin is connected
+	// to. Port bits assigned to pins which are not connected to a de2gui
+	// peripheral, such as GPIO pins, are not included.
+	Signals map[Signal]Signal
+
+	// Problems contains the assignments which are likely to be mistakes,
+	// in the order they appeared in the file.
+	Problems []Problem
+}
+
+// Map looks up the pins assigned in f on board b. As in Quartus, if a port
+// bit is assigned more than once, the last assignment is used.
+func Map(f *File, b *Board) *Mapping {
+	m := &Mapping{Board: b, Signals: map[Signal]Signal{}}
+
+	// known contains the names of the board's signals
+	known := map[string]bool{}
+	for _, v := range b.Pins {
+		known[v.Name] = true
+	}
+
+	// the port bit and line each pin was last assigned to
+	pinPort := map[string]Assignment{}
+	portLine := map[Signal]int{}
+
+	for _, a := range f.Assignments {
+		problem := func(format string, args ...interface{}) {
+			m.Problems = append(m.Problems, Problem{Line: a.Line, Port: a.Port, Message: fmt.Sprintf(format, args...)})
+		}
+
+		if line, ok := portLine[a.Port]; ok {
+			problem("already assigned on line %d", line)
+		}
+		portLine[a.Port] = a.Line
+
+		if prev, ok := pinPort[a.Location]; ok && prev.Port != a.Port {
+			problem("%s is already assigned to %s on line %d", a.Location, prev.Port, prev.Line)
+		}
+		pinPort[a.Location] = a
+
+		sig, ok := b.Pins[a.Location]
+		if !ok {
+			delete(m.Signals, a.Port)
+
+			if known[a.Port.Name] {
+				problem("%s is not connected to any %s on the %s", a.Location, a.Port.Name, b.Name)
+			}
+			continue
+		}
+
+		m.Signals[a.Port] = sig
+
+		if known[a.Port.Name] && sig != a.Port {
+			problem("%s is connected to %s, not %s", a.Location, sig, a.Port)
+		}
+	}
+
+	return m
+}
+
+// Signal returns the board signal connected to a bit of a port, and false if
+// it is not connected to a de2gui peripheral.
+func (m *Mapping) Signal(port string, bit int) (Signal, bool) {
+	sig, ok := m.Signals[Signal{Name: port, Bit: bit}]
+	return sig, ok
+}
+
+// Port returns the port bit connected to a board signal, and false if no
+// port is connected to it.
+func (m *Mapping) Port(sig Signal) (Signal, bool) {
+	for port, v := range m.Signals {
+		if v == sig {
+			return port, true
+		}
+	}
+	return Signal{}, false
+}
+
+// Check returns a problem for each bit of the given ports which is not
+// assigned to a pin, or which is assigned to a pin not connected to a de2gui
+// peripheral. Ports maps the name of each port to its width in bits; a width
+// of 0 means the port is not a bus. Ports whose bits are all unassigned are
+// reported as a single problem. The problems are sorted by port.
+func (m *Mapping) Check(ports map[string]int) []Problem {
+	names := []string{}
+	for name := range ports {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	problems := []Problem{}
+	for _, name := range names {
+		width := ports[name]
+		if width == 0 {
+			if _, ok := m.Signals[Signal{Name: name, Bit: -1}]; !ok {
+				problems = append(problems, Problem{Port: Signal{Name: name, Bit: -1}, Message: "not assigned to a board pin"})
+			}
+			continue
+		}
+
+		missing := []Problem{}
+		for i := 0; i < width; i++ {
+			if _, ok := m.Signals[Signal{Name: name, Bit: i}]; !ok {
+				missing = append(missing, Problem{Port: Signal{Name: name, Bit: i}, Message: "not assigned to a board pin"})
+			}
+		}
+
+		if len(missing) == width && width > 1 {
+			problems = append(problems, Problem{Port: Signal{Name: name, Bit: -1}, Message: "no bits are assigned to board pins"})
+		} else {
+			problems = append(problems, missing...)
+		}
+	}
+
+	return problems
+}
@@ -0,0 +1,160 @@
+package qsf
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// parse parses a .qsf file, failing the test on error
+func parse(t *testing.T, text string) *File {
+	t.Helper()
+
+	f, err := Parse("test.qsf", strings.NewReader(text))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return f
+}
+
+func TestParse(t *testing.T) {
+	f := parse(t, `# pin assignments
+set_global_assignment -name FAMILY "Cyclone IV E"
+set_location_assignment PIN_G19 -to LEDR[0]
+set_location_assignment pin_f19 -to "LEDR[1]"
+set_location_assignment PIN_Y2 -comment "the clock" -to {CLOCK_50}
+set_location_assignment -to \KEY[0] PIN_M23
+
+set_instance_assignment -name IO_STANDARD "2.5 V" -to LEDR[0]
+`)
+
+	want := []Assignment{
+		{Line: 3, Location: "PIN_G19", Port: Signal{Name: "LEDR", Bit: 0}},
+		{Line: 4, Location: "PIN_F19", Port: Signal{Name: "LEDR", Bit: 1}},
+		{Line: 5, Location: "PIN_Y2", Port: Signal{Name: "CLOCK_50", Bit: -1}},
+		{Line: 6, Location: "PIN_M23", Port: Signal{Name: "KEY", Bit: 0}},
+	}
+	if !reflect.DeepEqual(f.Assignments, want) {
+		t.Errorf("parsed\n%+v\nexpected\n%+v", f.Assignments, want)
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		text string
+		err  string
+	}{
+		{"set_location_assignment PIN_G19", "test.qsf:1: expected set_location_assignment PIN_xx -to port"},
+		{"set_location_assignment -to LEDR[0]", "test.qsf:1: expected set_location_assignment PIN_xx -to port"},
+		{"\nset_location_assignment PIN_G19 -to LEDR[x]", "test.qsf:2: invalid port 'LEDR[x]'"},
+		{"set_location_assignment PIN_G19 -to LEDR[0", "test.qsf:1: invalid port 'LEDR[0'"},
+		{"set_location_assignment PIN_G19 -to LEDR[-1]", "test.qsf:1: invalid port 'LEDR[-1]'"},
+		{"set_location_assignment PIN_G19 -to [0]", "test.qsf:1: invalid port '[0]'"},
+		{`set_location_assignment PIN_G19 -to ""`, "test.qsf:1: expected set_location_assignment PIN_xx -to port"},
+	}
+
+	for _, test := range tests {
+		_, err := Parse("test.qsf", strings.NewReader(test.text))
+		if err == nil || err.Error() != test.err {
+			t.Errorf("%q: returned error %v, expected %q", test.text, err, test.err)
+		}
+	}
+}
+
+func TestMap(t *testing.T) {
+	f := parse(t, `set_location_assignment PIN_G19 -to LEDR[0]
+set_location_assignment PIN_F21 -to LEDR[2]
+set_location_assignment PIN_E19 -to LEDR[3]
+set_location_assignment PIN_AB28 -to a[0]
+set_location_assignment PIN_AB28 -to a[1]
+set_location_assignment PIN_M23 -to go
+set_location_assignment PIN_M21 -to go
+set_location_assignment PIN_AB22 -to GPIO[0]
+set_location_assignment PIN_AB22 -to LEDG[0]
+set_location_assignment PIN_Y2 -to CLOCK_50
+`)
+
+	m := Map(f, DE2115)
+
+	signals := map[Signal]Signal{
+		{"LEDR", 0}:      {"LEDR", 0},
+		{"LEDR", 2}:      {"LEDR", 3},
+		{"LEDR", 3}:      {"LEDR", 2},
+		{"a", 0}:         {"SW", 0},
+		{"a", 1}:         {"SW", 0},
+		{"go", -1}:       {"KEY", 1},
+		{"CLOCK_50", -1}: {"CLOCK_50", -1},
+	}
+	if !reflect.DeepEqual(m.Signals, signals) {
+		t.Errorf("mapped\n%v\nexpected\n%v", m.Signals, signals)
+	}
+
+	problems := []string{}
+	for _, p := range m.Problems {
+		problems = append(problems, p.String())
+	}
+	want := []string{
+		"line 2: LEDR[2]: PIN_F21 is connected to LEDR[3], not LEDR[2]",
+		"line 3: LEDR[3]: PIN_E19 is connected to LEDR[2], not LEDR[3]",
+		"line 5: a[1]: PIN_AB28 is already assigned to a[0] on line 4",
+		"line 7: go: already assigned on line 6",
+		"line 9: LEDG[0]: PIN_AB22 is already assigned to GPIO[0] on line 8",
+		"line 9: LEDG[0]: PIN_AB22 is not connected to any LEDG on the DE2-115",
+	}
+	if !reflect.DeepEqual(problems, want) {
+		t.Errorf("found the problems\n%s\nexpected\n%s", strings.Join(problems, "\n"), strings.Join(want, "\n"))
+	}
+
+	if sig, ok := m.Signal("LEDR", 2); !ok || sig != (Signal{"LEDR", 3}) {
+		t.Errorf("Signal(LEDR, 2) = %v, %v, expected LEDR[3]", sig, ok)
+	}
+	if _, ok := m.Signal("GPIO", 0); ok {
+		t.Errorf("Signal(GPIO, 0) is connected, expected it not to be")
+	}
+	if port, ok := m.Port(Signal{"KEY", 1}); !ok || port != (Signal{"go", -1}) {
+		t.Errorf("Port(KEY[1]) = %v, %v, expected go", port, ok)
+	}
+	if _, ok := m.Port(Signal{"KEY", 0}); ok {
+		t.Errorf("Port(KEY[0]) is connected, expected it not to be")
+	}
+}
+
+func TestCheck(t *testing.T) {
+	f := parse(t, `set_location_assignment PIN_G19 -to LEDR[0]
+set_location_assignment PIN_F21 -to LEDR[2]
+set_location_assignment PIN_M23 -to go
+`)
+
+	problems := []string{}
+	for _, p := range Map(f, DE2115).Check(map[string]int{"LEDR": 4, "SW": 2, "go": 0, "stop": 0, "one": 1}) {
+		problems = append(problems, p.String())
+	}
+
+	want := []string{
+		"LEDR[1]: not assigned to a board pin",
+		"LEDR[3]: not assigned to a board pin",
+		"SW: no bits are assigned to board pins",
+		"one[0]: not assigned to a board pin",
+		"stop: not assigned to a board pin",
+	}
+	if !reflect.DeepEqual(problems, want) {
+		t.Errorf("found the problems\n%s\nexpected\n%s", strings.Join(problems, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+func TestDE2115(t *testing.T) {
+	counts := map[string]int{}
+	for _, sig := range DE2115.Pins {
+		counts[sig.Name]++
+	}
+
+	want := map[string]int{"SW": 18, "KEY": 4, "LEDR": 18, "LEDG": 9, "CLOCK_50": 1}
+	for i := 0; i < 8; i++ {
+		want["HEX"+string(rune('0'+i))] = 7
+	}
+
+	// a pin listed twice would leave a signal with too few pins
+	if !reflect.DeepEqual(counts, want) {
+		t.Errorf("the DE2-115 has the pins %v, expected %v", counts, want)
+	}
+}
@@ -0,0 +1,193 @@
+package de2gui
+
+import (
+	"fmt"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+
+	"github.com/herclab/de2gui/de2gui/session"
+)
+
+// StartRecording begins recording a session, see the session package. Every
+// subsequent change to SW, KEY, LEDR, LEDG, and the HEX displays is recorded
+// along with the tick it happened on, until StopRecording() is called. Any
+// previously recorded session is discarded.
+//
+// For the recording to be useful as a testbench, it should usually be
+// started immediately after the simulation is reset.
+func (s *UIState) StartRecording() {
+	s.recordMutex.Lock()
+	s.session = &session.Session{Board: s.profile.session()}
+	s.recording = true
+	s.recordMutex.Unlock()
+
+	s.record("SW", s.SW())
+	s.record("KEY", s.KEY())
+	s.record("LEDR", s.ledr)
+	s.record("LEDG", s.ledg)
+	for i := 0; i < s.profile.HEX; i++ {
+		s.record(fmt.Sprintf("HEX%d", i), uint32(s.hex[i]))
+	}
+
+	if !s.faults.none() {
+		s.recordFault(s.faults.String())
+	}
+}
+
+// StopRecording stops recording, and returns the recorded session. If there
+// is no recorded session, nil is returned.
+func (s *UIState) StopRecording() *session.Session {
+	s.recordMutex.Lock()
+	defer s.recordMutex.Unlock()
+
+	s.recording = false
+	return s.session
+}
+
+// Session returns the session which is being recorded, or which was most
+// recently recorded, or nil if StartRecording() has never been called.
+func (s *UIState) Session() *session.Session {
+	s.recordMutex.Lock()
+	defer s.recordMutex.Unlock()
+
+	return s.session
+}
+
+// Internal function used to record changes to signals while a session is
+// being recorded
+func (s *UIState) record(signal string, value uint32) {
+	s.recordMutex.Lock()
+	defer s.recordMutex.Unlock()
+
+	if !s.recording {
+		return
+	}
+
+	s.session.Events = append(s.session.Events, session.Event{
+		Cycle:  s.session.Length,
+		Tick:   s.Tick,
+		Signal: signal,
+		Value:  value,
+	})
+}
+
+// Internal function used to record changes to the injected faults, see
+// SetFaults()
+func (s *UIState) recordFault(detail string) {
+	s.recordDetail("FAULT", detail)
+}
+
+// Internal function used to record special events which are described by
+// text, rather than a value
+func (s *UIState) recordDetail(signal, detail string) {
+	s.recordMutex.Lock()
+	defer s.recordMutex.Unlock()
+
+	if !s.recording {
+		return
+	}
+
+	s.session.Events = append(s.session.Events, session.Event{
+		Cycle:  s.session.Length,
+		Tick:   s.Tick,
+		Signal: signal,
+		Detail: detail,
+	})
+}
+
+// Internal function which advances the recording by one tick
+func (s *UIState) recordTick() {
+	s.recordMutex.Lock()
+	defer s.recordMutex.Unlock()
+
+	if s.recording {
+		s.session.Length++
+	}
+}
+
+// Internal function which creates the session recording controls
+func (s *UIState) newSessionControls() fyne.CanvasObject {
+	return container.NewHBox(
+		widget.NewLabel("Session:"),
+		widget.NewCheck("Record", func(c bool) {
+			if c {
+				s.StartRecording()
+			} else {
+				s.StopRecording()
+			}
+		}),
+		widget.NewButton("Export Testbench...", func() { s.exportTestbench() }),
+	)
+}
+
+// window returns the window containing the widgets for this UIState, or nil
+// if they are not currently shown in a window
+func (s *UIState) window() fyne.Window {
+	// a headless UIState has no window, and there may be no app
+	if s.widgetTree == nil {
+		return nil
+	}
+
+	app := fyne.CurrentApp()
+	if app == nil {
+		return nil
+	}
+
+	c := app.Driver().CanvasForObject(s.widgetTree)
+	for _, w := range app.Driver().AllWindows() {
+		if w.Canvas() == c {
+			return w
+		}
+	}
+
+	return nil
+}
+
+// Internal function wired into the "Export Testbench" button
+func (s *UIState) exportTestbench() {
+	w := s.window()
+	if w == nil {
+		return
+	}
+
+	s.recordMutex.Lock()
+	recorded := s.session
+	var sess session.Session
+	if recorded != nil {
+		// take a copy, so the recording can continue while the dialog
+		// is open
+		sess = *recorded
+		sess.Events = append([]session.Event{}, recorded.Events...)
+	}
+	s.recordMutex.Unlock()
+
+	if recorded == nil {
+		dialog.ShowInformation("Export Testbench",
+			"Nothing has been recorded yet, use the Record checkbox\nto record a session first.", w)
+		return
+	}
+
+	dialog.ShowFileSave(func(f fyne.URIWriteCloser, err error) {
+		if err != nil {
+			dialog.ShowError(err, w)
+			return
+		}
+
+		if f == nil {
+			// canceled
+			return
+		}
+
+		err = sess.WriteTestbench(f, session.TestbenchOptions{Module: s.TopModule})
+		if err2 := f.Close(); err == nil {
+			err = err2
+		}
+
+		if err != nil {
+			dialog.ShowError(err, w)
+		}
+	}, w)
+}
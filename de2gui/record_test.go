@@ -0,0 +1,70 @@
+package de2gui
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/herclab/de2gui/de2gui/session"
+)
+
+func TestRecording(t *testing.T) {
+	s := newCounterBoard()
+	s.RunTicks(3)
+
+	s.StartRecording()
+	s.SetSW(1)
+	s.RunTicks(2)
+	for _, line := range []string{"fault ledr stuck1 0x1", "reset"} {
+		if _, err := s.ExecLine(line); err != nil {
+			t.Fatalf("%s: %v", line, err)
+		}
+	}
+	sess := s.StopRecording()
+
+	// nothing is recorded once the recording stops
+	s.RunTicks(5)
+
+	if sess.Length != 2 {
+		t.Errorf("the session is %d cycles long, expected 2", sess.Length)
+	}
+	if !reflect.DeepEqual(sess.Board, &session.Board{Name: "DE2-115", HEX: 8, LEDR: 18, LEDG: 9, SW: 18, KEY: 4}) {
+		t.Errorf("the session was recorded on %+v, expected the DE2-115", sess.Board)
+	}
+
+	// the HEX displays and LEDG are left out, for brevity
+	events := []session.Event{}
+	for _, e := range sess.Events {
+		if !strings.HasPrefix(e.Signal, "HEX") && e.Signal != "LEDG" {
+			events = append(events, e)
+		}
+	}
+
+	want := []session.Event{
+		{Cycle: 0, Tick: 3, Signal: "SW", Value: 0},
+		{Cycle: 0, Tick: 3, Signal: "KEY", Value: 0},
+		{Cycle: 0, Tick: 3, Signal: "LEDR", Value: 3},
+		{Cycle: 0, Tick: 3, Signal: "SW", Value: 1},
+		{Cycle: 1, Tick: 4, Signal: "LEDR", Value: 4},
+		{Cycle: 2, Tick: 5, Signal: "LEDR", Value: 5},
+		{Cycle: 2, Tick: 5, Signal: "FAULT", Detail: "fault clear; fault ledr stuck1 0x1"},
+		{Cycle: 2, Tick: 5, Signal: "RESET"},
+		{Cycle: 2, Tick: 5, Signal: "LEDR", Value: 0},
+	}
+	if !reflect.DeepEqual(events, want) {
+		t.Errorf("recorded the events:\n%+v\nexpected:\n%+v", events, want)
+	}
+
+	if s.Session() != sess {
+		t.Errorf("Session() does not return the recorded session")
+	}
+
+	var buf bytes.Buffer
+	if err := sess.WriteTestbench(&buf, session.TestbenchOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(buf.String(), "\t\tSW = 18'h00001;\n") {
+		t.Errorf("the testbench does not set SW:\n%s", buf.String())
+	}
+}
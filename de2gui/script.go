@@ -0,0 +1,438 @@
+package de2gui
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync/atomic"
+
+	"github.com/herclab/de2gui/de2gui/script"
+)
+
+// RunScript executes every command in the given script in order, and
+// returns a report containing the outcome of each expect command. Failed
+// expectations do not stop the script, but any other error does, in which
+// case the error is recorded in the report. A panic in a callback, see
+// OnError, also stops the script.
+//
+// Ticks caused by the script are handled exactly as if the user had used
+// the tick controls, so OnTick must be defined. The script runs in the
+// calling goroutine, and is typically used with a UIState created by
+// NewHeadlessUIState(). It may be stopped from another goroutine with
+// CancelCommand().
+func (s *UIState) RunScript(sc *script.Script) *script.Report {
+	s.beginExec()
+	defer s.endExec()
+
+	r := &script.Report{Name: sc.Name, Results: []script.Result{}}
+
+	for _, c := range sc.Commands {
+		count := s.Errors()
+		res, err := s.Exec(c)
+		if err == nil {
+			err = s.errorSince(count)
+		}
+		if err != nil {
+			r.Error = fmt.Sprintf("line %d: %s: %v", c.Line, c.Name, err)
+			break
+		}
+
+		if res != nil {
+			r.Results = append(r.Results, *res)
+		}
+	}
+
+	return r
+}
+
+// CommandTickGroup is the largest number of ticks which a command, such as
+// "tick 5000", runs at once. Between groups, the command checks whether it
+// has been canceled, see CancelCommand().
+const CommandTickGroup = 1000
+
+// errCanceled is returned by commands stopped by CancelCommand()
+var errCanceled = errors.New("the command was canceled")
+
+// Exec executes a single script command. If the command is an expect
+// command, the result of the check is returned, and otherwise the result is
+// nil. A failed check is not considered an error.
+//
+// Commands may be run in a goroutine other than the one handling the GUI,
+// so that the window keeps responding during a long "run until", and the
+// checks are made between ticks, so that auto-ticking does not disturb them.
+// A command may be stopped with CancelCommand().
+func (s *UIState) Exec(c script.Command) (*script.Result, error) {
+	s.beginExec()
+	defer s.endExec()
+
+	switch c.Name {
+	case "tick":
+		if len(c.Args) != 1 {
+			return nil, fmt.Errorf("expected 1 argument, got %d", len(c.Args))
+		}
+
+		n, err := script.ParseTicks(c.Args[0])
+		if err != nil {
+			return nil, err
+		}
+		return nil, s.commandTicks(n)
+
+	case "at":
+		if len(c.Args) != 1 {
+			return nil, fmt.Errorf("expected 1 argument, got %d", len(c.Args))
+		}
+
+		n, err := script.ParseNumber(c.Args[0])
+		if err != nil {
+			return nil, err
+		}
+		return nil, s.advanceTo(n)
+
+	case "sw":
+		sw, err := script.ParseSW(c.Args)
+		if err != nil {
+			return nil, err
+		}
+
+		if !sw.Single {
+			s.SetSW(uint32(sw.Value))
+			break
+		}
+
+		if sw.Index < 0 || sw.Index >= s.profile.SW {
+			return nil, fmt.Errorf("there is no SW%d", sw.Index)
+		}
+		s.SetSWBit(sw.Index, sw.On)
+
+	case "key":
+		k, err := script.ParseKey(c.Args)
+		if err != nil {
+			return nil, err
+		}
+
+		if k.Index < 0 || k.Index >= s.profile.KEY {
+			return nil, fmt.Errorf("there is no KEY%d", k.Index)
+		}
+
+		switch k.Action {
+		case "down":
+			s.HoldKEY(k.Index)
+		case "up":
+			s.ReleaseKEY(k.Index)
+		default:
+			if k.Hold == 0 {
+				k.Hold = KeyPushMinTime
+			}
+			s.PressKEY(k.Index, k.Hold)
+		}
+
+	case "reset":
+		s.reset()
+
+	case "bounce":
+		if len(c.Args) != 1 {
+			return nil, fmt.Errorf("expected 1 argument, got %d", len(c.Args))
+		}
+
+		n, err := script.ParseNumber(c.Args[0])
+		if err != nil {
+			return nil, err
+		}
+		if err := s.SetBounce(n); err != nil {
+			return nil, err
+		}
+
+	case "fault":
+		f, err := script.ParseFault(c.Args)
+		if err != nil {
+			return nil, err
+		}
+
+		if err := s.execFault(f); err != nil {
+			return nil, err
+		}
+
+	case "glitch":
+		g, err := script.ParseGlitch(c.Args)
+		if err != nil {
+			return nil, err
+		}
+
+		if g.Target == "sw" {
+			s.GlitchSW(uint32(g.Mask))
+		} else {
+			s.GlitchKEY(uint32(g.Mask))
+		}
+
+	case "expect":
+		e, err := script.ParseExpect(c.Args)
+		if err != nil {
+			return nil, err
+		}
+
+		res, _, err := s.checkNow(c, e)
+		return res, err
+
+	case "run":
+		r, err := script.ParseRun(c.Args)
+		if err != nil {
+			return nil, err
+		}
+
+		return nil, s.runUntil(c, r)
+
+	case "snap":
+		sn, err := script.ParseSnap(c.Args)
+		if err != nil {
+			return nil, err
+		}
+
+		if sn.Action == "save" {
+			s.SaveSnapshot(sn.Name)
+			break
+		}
+		return nil, s.LoadSnapshot(sn.Name)
+
+	default:
+		return nil, fmt.Errorf("unknown command '%s'", c.Name)
+	}
+
+	return nil, nil
+}
+
+// ExecLine parses a single line in the script format, and executes it as
+// for Exec(). Blank lines, and lines containing only a comment, do nothing.
+// This is used by the command line in the GUI.
+func (s *UIState) ExecLine(line string) (*script.Result, error) {
+	c, err := script.ParseLine(line)
+	if err != nil || c == nil {
+		return nil, err
+	}
+
+	return s.Exec(*c)
+}
+
+// CancelCommand stops the commands being run by Exec(), ExecLine(),
+// RunScript() and RunCommands() in other goroutines, which return an error
+// saying that they were canceled. Ticks are run in groups of at most
+// CommandTickGroup, so a command may finish its current group first. If no
+// command is running, CancelCommand does nothing.
+func (s *UIState) CancelCommand() {
+	if atomic.LoadInt32(&s.execRunning) > 0 {
+		atomic.StoreInt32(&s.execCanceled, 1)
+	}
+}
+
+// Internal function called when a command starts running
+func (s *UIState) beginExec() {
+	atomic.AddInt32(&s.execRunning, 1)
+}
+
+// Internal function called when a command finishes running, which forgets
+// any cancellation once no commands are running
+func (s *UIState) endExec() {
+	if atomic.AddInt32(&s.execRunning, -1) == 0 {
+		atomic.StoreInt32(&s.execCanceled, 0)
+	}
+}
+
+// Internal function which returns errCanceled if the running commands have
+// been canceled, see CancelCommand()
+func (s *UIState) canceled() error {
+	if atomic.LoadInt32(&s.execCanceled) != 0 {
+		return errCanceled
+	}
+	return nil
+}
+
+// Internal function which runs n ticks for a command, in groups of at most
+// CommandTickGroup so that it can be canceled between them. It stops at the
+// first panic in a callback, which is returned.
+func (s *UIState) commandTicks(n int) error {
+	for n > 0 {
+		if err := s.canceled(); err != nil {
+			return err
+		}
+
+		group := n
+		if group > CommandTickGroup {
+			group = CommandTickGroup
+		}
+
+		count := s.Errors()
+		s.tick(group)
+		if err := s.errorSince(count); err != nil {
+			return err
+		}
+
+		n -= group
+	}
+
+	return nil
+}
+
+// Internal function which returns the value of Tick between ticks
+func (s *UIState) currentTick() uint64 {
+	s.tickMutex.Lock()
+	defer s.tickMutex.Unlock()
+
+	return s.Tick
+}
+
+// advanceTo ticks until s.Tick is at least when.
+func (s *UIState) advanceTo(when uint64) error {
+	for {
+		before := s.currentTick()
+		if before >= when {
+			return nil
+		}
+
+		n := when - before
+		if n > CommandTickGroup {
+			n = CommandTickGroup
+		}
+
+		if err := s.commandTicks(int(n)); err != nil {
+			return err
+		}
+		if s.currentTick() == before {
+			return fmt.Errorf("OnTick did not advance Tick past %d", before)
+		}
+	}
+}
+
+// runUntil ticks one at a time until the condition in r holds, or r.Limit
+// ticks have passed.
+func (s *UIState) runUntil(c script.Command, r script.Run) error {
+	for n := uint64(0); ; n++ {
+		res, observed, err := s.checkNow(c, r.Expect)
+		if err != nil {
+			return err
+		}
+
+		if r.Holds(observed, res.Pass) {
+			return nil
+		}
+
+		if n == r.Limit {
+			return fmt.Errorf("the condition did not hold within %d ticks", r.Limit)
+		}
+
+		if err := s.commandTicks(1); err != nil {
+			return err
+		}
+	}
+}
+
+// hexChars returns the characters currently shown on the rightmost n HEX
+// displays, using the same conventions as script.Expect.Digits.
+func (s *UIState) hexChars(n int) string {
+	chars := make([]rune, n)
+	for i := 0; i < n; i++ {
+		c, _ := HexDigit(s.HEX(i))
+		if c == ' ' {
+			c = '_'
+		}
+		chars[n-1-i] = c
+	}
+	return string(chars)
+}
+
+// observe returns the current value of the target of e, other than the hex
+// target, along with the number of bits which exist in the target, and the
+// format used to show its value, which is empty for hexadecimal.
+func (s *UIState) observe(e script.Expect) (uint64, int, string) {
+	switch e.Target {
+	case "ledr":
+		return uint64(s.LEDR()), s.profile.LEDR, ""
+	case "ledg":
+		return uint64(s.LEDG()), s.profile.LEDG, ""
+	case "sw":
+		return uint64(s.SW()), s.profile.SW, ""
+	case "key":
+		return uint64(s.KEY()), s.profile.KEY, ""
+	case "tick":
+		return s.Tick, 64, "%d"
+	default:
+		return uint64(s.HEX(e.Index) & 0x7f), 7, ""
+	}
+}
+
+// checkNow performs the check described by e between ticks, returning the
+// result, along with the observed value unless e compares HEX digits.
+func (s *UIState) checkNow(c script.Command, e script.Expect) (*script.Result, uint64, error) {
+	s.tickMutex.Lock()
+	defer s.tickMutex.Unlock()
+
+	r, err := s.check(c, e)
+	if err != nil || e.Digits != "" {
+		return r, 0, err
+	}
+
+	observed, _, _ := s.observe(e)
+	return r, observed, nil
+}
+
+// check performs the check described by e, returning the result.
+func (s *UIState) check(c script.Command, e script.Expect) (*script.Result, error) {
+	r := &script.Result{
+		Line:   c.Line,
+		Tick:   s.Tick,
+		Check:  c.String(),
+		Target: e.Target,
+	}
+
+	if e.Target == "hex" || e.Digits != "" {
+		if e.Target == "hex" {
+			if len(e.Digits) > s.profile.HEX {
+				return nil, fmt.Errorf("there are only %d HEX displays", s.profile.HEX)
+			}
+			r.Observed = s.hexChars(len(e.Digits))
+		} else {
+			if e.Index >= s.profile.HEX {
+				return nil, fmt.Errorf("there is no HEX%d", e.Index)
+			}
+			r.Observed = string(s.hexChars(e.Index + 1)[0])
+		}
+
+		r.Expected = e.Digits
+		r.Pass = r.Expected == r.Observed
+		return r, nil
+	}
+
+	if strings.HasPrefix(e.Target, "hex") && e.Index >= s.profile.HEX {
+		return nil, fmt.Errorf("there is no HEX%d", e.Index)
+	}
+
+	observed, bits, format := s.observe(e)
+
+	// width is a mask of the bits which exist in the target
+	width := ^uint64(0)
+	if bits < 64 {
+		width = (1 << uint(bits)) - 1
+	}
+
+	if format == "" {
+		format = fmt.Sprintf("0x%%0%dx", (bits+3)/4)
+	}
+
+	mask := e.Mask
+	switch {
+	case strings.HasPrefix(e.Target, "hex"):
+		// the 8th bit is not a segment, so it is never compared
+		mask &= width
+	case e.Target != "tick":
+		// bits which do not exist are always compared, so that
+		// expecting an impossible value fails
+		mask |= ^width
+	}
+
+	r.Expected = fmt.Sprintf(format, e.Value)
+	if e.Mask&width != width {
+		r.Expected += fmt.Sprintf(" (mask 0x%x)", e.Mask&width)
+	}
+	r.Observed = fmt.Sprintf(format, observed)
+	r.Pass = (observed^e.Value)&mask == 0
+
+	return r, nil
+}
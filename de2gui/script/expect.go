@@ -0,0 +1,104 @@
+package script
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Expect is the parsed form of an expect command.
+type Expect struct {
+	// Target is the name of the thing being checked, one of ledr, ledg,
+	// sw, key, tick, hexN or hex.
+	Target string
+
+	// Index is the number of the HEX display for hexN targets.
+	Index int
+
+	// Value is the expected value. It is not used if Digits is non-empty.
+	Value uint64
+
+	// Mask selects which bits of Value are compared. It is all ones unless
+	// the expect command contains a mask clause.
+	Mask uint64
+
+	// Digits contains the expected characters for hex targets, and for
+	// hexN targets which were given a character rather than a segment
+	// pattern. The characters are upper-case, with the rightmost
+	// character corresponding to the lowest numbered display. A blank
+	// display is written as '_'.
+	Digits string
+}
+
+// isDigitChar returns true if c is allowed in Expect.Digits
+func isDigitChar(c rune) bool {
+	return c == '_' || strings.ContainsRune("0123456789ABCDEF", c)
+}
+
+// ParseExpect parses the arguments of an expect command.
+func ParseExpect(args []string) (Expect, error) {
+	e := Expect{Mask: ^uint64(0)}
+
+	if len(args) != 2 && len(args) != 4 {
+		return e, fmt.Errorf("expected 'expect TARGET VALUE [mask M]'")
+	}
+
+	e.Target = strings.ToLower(args[0])
+	val := args[1]
+
+	switch {
+	case e.Target == "ledr", e.Target == "ledg", e.Target == "sw",
+		e.Target == "key", e.Target == "tick":
+
+	case e.Target == "hex":
+		e.Digits = strings.ToUpper(val)
+		for _, c := range e.Digits {
+			if !isDigitChar(c) {
+				return e, fmt.Errorf("invalid HEX digit '%c'", c)
+			}
+		}
+
+	case strings.HasPrefix(e.Target, "hex"):
+		i, err := strconv.Atoi(e.Target[3:])
+		if err != nil || i < 0 {
+			return e, fmt.Errorf("unknown target '%s'", args[0])
+		}
+		e.Index = i
+
+		if len(val) == 1 {
+			e.Digits = strings.ToUpper(val)
+			if !isDigitChar(rune(e.Digits[0])) {
+				return e, fmt.Errorf("invalid HEX digit '%s'", val)
+			}
+		}
+
+	default:
+		return e, fmt.Errorf("unknown target '%s'", args[0])
+	}
+
+	if e.Digits == "" {
+		v, err := ParseNumber(val)
+		if err != nil {
+			return e, err
+		}
+		e.Value = v
+	}
+
+	if len(args) == 4 {
+		if args[2] != "mask" {
+			return e, fmt.Errorf("expected 'mask', got '%s'", args[2])
+		}
+
+		if e.Digits != "" {
+			return e, fmt.Errorf("mask cannot be used when comparing digits")
+		}
+
+		m, err := ParseNumber(args[3])
+		if err != nil {
+			return e, err
+		}
+		e.Mask = m
+	}
+
+	return e, nil
+}
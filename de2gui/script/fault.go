@@ -0,0 +1,118 @@
+package script
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Fault is the parsed form of a fault command, which changes the faults
+// injected into one of the board's inputs or outputs.
+//
+//	fault TARGET stuck0 M   the bits in M of TARGET are stuck at 0
+//	fault TARGET stuck1 M   the bits in M of TARGET are stuck at 1
+//	fault TARGET clear      remove all faults from TARGET
+//	fault clear             remove all faults
+//
+// The TARGET is one of sw, key, ledr, ledg, or hex0...hex7. HEX segments
+// use the active-low encoding of de2gui.UIState.SetHEX(), so a dead segment
+// is stuck at 1, and a segment which is always lit is stuck at 0.
+type Fault struct {
+	// Target is the name of the signal, or empty for "fault clear".
+	Target string
+
+	// Index is the number of the HEX display for hexN targets.
+	Index int
+
+	// Kind is one of "stuck0", "stuck1" or "clear".
+	Kind string
+
+	// Mask selects the affected bits for stuck0 and stuck1.
+	Mask uint64
+}
+
+// parseTarget parses the name of a signal which can be faulted, returning
+// the target and the HEX display index
+func parseTarget(name string) (string, int, error) {
+	name = strings.ToLower(name)
+
+	switch name {
+	case "sw", "key", "ledr", "ledg":
+		return name, 0, nil
+	}
+
+	if strings.HasPrefix(name, "hex") {
+		i, err := strconv.Atoi(name[3:])
+		if err == nil && i >= 0 {
+			return "hexN", i, nil
+		}
+	}
+
+	return "", 0, fmt.Errorf("unknown target '%s'", name)
+}
+
+// ParseFault parses the arguments of a fault command.
+func ParseFault(args []string) (Fault, error) {
+	var f Fault
+
+	if len(args) == 1 && strings.ToLower(args[0]) == "clear" {
+		f.Kind = "clear"
+		return f, nil
+	}
+
+	if len(args) < 2 || len(args) > 3 {
+		return f, fmt.Errorf("expected 'fault TARGET stuck0|stuck1 M', 'fault TARGET clear' or 'fault clear'")
+	}
+
+	var err error
+	f.Target, f.Index, err = parseTarget(args[0])
+	if err != nil {
+		return f, err
+	}
+
+	f.Kind = strings.ToLower(args[1])
+	switch {
+	case f.Kind == "clear" && len(args) == 2:
+
+	case (f.Kind == "stuck0" || f.Kind == "stuck1") && len(args) == 3:
+		f.Mask, err = ParseNumber(args[2])
+		if err != nil {
+			return f, err
+		}
+
+	default:
+		return f, fmt.Errorf("expected 'fault TARGET stuck0|stuck1 M', 'fault TARGET clear' or 'fault clear'")
+	}
+
+	return f, nil
+}
+
+// Glitch is the parsed form of a glitch command, which inverts some of the
+// bits of an input for a single tick.
+//
+//	glitch sw|key M         invert the bits in M for one tick
+type Glitch struct {
+	// Target is either "sw" or "key".
+	Target string
+
+	// Mask selects the bits which are inverted.
+	Mask uint64
+}
+
+// ParseGlitch parses the arguments of a glitch command.
+func ParseGlitch(args []string) (Glitch, error) {
+	var g Glitch
+
+	if len(args) != 2 {
+		return g, fmt.Errorf("expected 'glitch sw|key M'")
+	}
+
+	g.Target = strings.ToLower(args[0])
+	if g.Target != "sw" && g.Target != "key" {
+		return g, fmt.Errorf("expected 'sw' or 'key', got '%s'", args[0])
+	}
+
+	var err error
+	g.Mask, err = ParseNumber(args[1])
+	return g, err
+}
@@ -0,0 +1,144 @@
+package script
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+)
+
+// Result is the outcome of a single expect command.
+type Result struct {
+	// Line is the line of the script containing the expect command.
+	Line int `json:"line"`
+
+	// Tick is the value of UIState.Tick when the check was performed.
+	Tick uint64 `json:"tick"`
+
+	// Check is the text of the expect command.
+	Check string `json:"check"`
+
+	Target   string `json:"target"`
+	Expected string `json:"expected"`
+	Observed string `json:"observed"`
+	Pass     bool   `json:"pass"`
+}
+
+// Report collects the results of running a script.
+type Report struct {
+	// Name is the name of the script that was run.
+	Name string `json:"name"`
+
+	Results []Result `json:"results"`
+
+	// Error is non-empty if the script could not be run to completion,
+	// in which case Results contains only the checks which were performed
+	// before the error occurred.
+	Error string `json:"error,omitempty"`
+}
+
+// Passed returns the number of checks which passed.
+func (r *Report) Passed() int {
+	n := 0
+	for _, v := range r.Results {
+		if v.Pass {
+			n++
+		}
+	}
+	return n
+}
+
+// Failed returns the number of checks which failed.
+func (r *Report) Failed() int {
+	return len(r.Results) - r.Passed()
+}
+
+// OK returns true if the script ran to completion and every check passed.
+func (r *Report) OK() bool {
+	return r.Error == "" && r.Failed() == 0
+}
+
+// WriteJSON writes the report to w as a JSON document.
+func (r *Report) WriteJSON(w io.Writer) error {
+	doc := struct {
+		*Report
+		Passed int `json:"passed"`
+		Failed int `json:"failed"`
+	}{r, r.Passed(), r.Failed()}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "\t")
+	return enc.Encode(doc)
+}
+
+type junitFailure struct {
+	Message string `xml:"message,attr"`
+	Text    string `xml:",chardata"`
+}
+
+type junitCase struct {
+	Name      string        `xml:"name,attr"`
+	Classname string        `xml:"classname,attr"`
+	Failure   *junitFailure `xml:"failure,omitempty"`
+	Error     *junitFailure `xml:"error,omitempty"`
+}
+
+type junitSuite struct {
+	XMLName  xml.Name    `xml:"testsuite"`
+	Name     string      `xml:"name,attr"`
+	Tests    int         `xml:"tests,attr"`
+	Failures int         `xml:"failures,attr"`
+	Errors   int         `xml:"errors,attr"`
+	Cases    []junitCase `xml:"testcase"`
+}
+
+// WriteJUnit writes the report to w as JUnit-style XML, with one test case
+// per check. If the script did not run to completion, an additional test
+// case with an error is included.
+func (r *Report) WriteJUnit(w io.Writer) error {
+	suite := junitSuite{
+		Name:     r.Name,
+		Tests:    len(r.Results),
+		Failures: r.Failed(),
+	}
+
+	for _, v := range r.Results {
+		c := junitCase{
+			Name:      fmt.Sprintf("line %d: %s", v.Line, v.Check),
+			Classname: r.Name,
+		}
+
+		if !v.Pass {
+			c.Failure = &junitFailure{
+				Message: fmt.Sprintf("expected %s, observed %s", v.Expected, v.Observed),
+				Text: fmt.Sprintf("at tick %d: %s expected %s, observed %s",
+					v.Tick, v.Target, v.Expected, v.Observed),
+			}
+		}
+
+		suite.Cases = append(suite.Cases, c)
+	}
+
+	if r.Error != "" {
+		suite.Tests++
+		suite.Errors++
+		suite.Cases = append(suite.Cases, junitCase{
+			Name:      "script",
+			Classname: r.Name,
+			Error:     &junitFailure{Message: r.Error},
+		})
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "\t")
+	if err := enc.Encode(suite); err != nil {
+		return err
+	}
+
+	_, err := io.WriteString(w, "\n")
+	return err
+}
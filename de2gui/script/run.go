@@ -0,0 +1,135 @@
+package script
+
+import (
+	"fmt"
+	"strings"
+)
+
+// DefaultRunLimit is the number of ticks after which a run command gives up,
+// unless it has a limit clause.
+var DefaultRunLimit uint64 = 1000000
+
+// Run is the parsed form of a run command, which ticks until a condition
+// holds.
+//
+//	run until TARGET OP V [limit N]
+//
+// The TARGET and V are as for an expect command, and OP is one of ==, !=,
+// <, <=, > or >=, with = meaning ==. Spaces around OP are optional, so
+// "run until ledr!=0" and "run until ledr != 0" are the same. HEX digits
+// can only be compared with == and !=. The condition is checked before each
+// tick, and it is an error if it does not hold within N ticks.
+type Run struct {
+	// Expect gives the target and value to compare against.
+	Expect Expect
+
+	// Op is the comparison, one of "==", "!=", "<", "<=", ">" or ">=".
+	Op string
+
+	// Limit is the largest number of ticks to run.
+	Limit uint64
+}
+
+// ops are the comparisons allowed in a run command, with longer ones first
+// so that e.g. <= is not taken as <
+var ops = []string{"==", "!=", "<=", ">=", "<", ">", "="}
+
+// ParseRun parses the arguments of a run command.
+func ParseRun(args []string) (Run, error) {
+	r := Run{Limit: DefaultRunLimit}
+	usage := fmt.Errorf("expected 'run until TARGET OP V [limit N]'")
+
+	if len(args) < 2 || strings.ToLower(args[0]) != "until" {
+		return r, usage
+	}
+	args = args[1:]
+
+	if len(args) > 2 && strings.ToLower(args[len(args)-2]) == "limit" {
+		n, err := ParseNumber(args[len(args)-1])
+		if err != nil {
+			return r, err
+		}
+		r.Limit = n
+		args = args[:len(args)-2]
+	}
+
+	cond := strings.Join(args, "")
+	i := -1
+	for _, op := range ops {
+		if i = strings.Index(cond, op); i >= 0 {
+			r.Op = op
+			break
+		}
+	}
+
+	if i <= 0 || i+len(r.Op) == len(cond) {
+		return r, usage
+	}
+
+	target, value := cond[:i], cond[i+len(r.Op):]
+	if r.Op == "=" {
+		r.Op = "=="
+	}
+
+	var err error
+	r.Expect, err = ParseExpect([]string{target, value})
+	if err != nil {
+		return r, err
+	}
+
+	if r.Expect.Digits != "" && r.Op != "==" && r.Op != "!=" {
+		return r, fmt.Errorf("HEX digits can only be compared with == or !=")
+	}
+
+	return r, nil
+}
+
+// Holds returns true if the condition holds for an observed value. For
+// conditions comparing HEX digits, pass is the result of the equivalent
+// expect command, and observed is not used.
+func (r Run) Holds(observed uint64, pass bool) bool {
+	v := r.Expect.Value
+	switch r.Op {
+	case "==":
+		return pass
+	case "!=":
+		return !pass
+	case "<":
+		return observed < v
+	case "<=":
+		return observed <= v
+	case ">":
+		return observed > v
+	default:
+		return observed >= v
+	}
+}
+
+// Snap is the parsed form of a snap command, which saves the state of the
+// board and of the design under a name, or restores it.
+//
+//	snap save NAME          save a snapshot
+//	snap load NAME          restore a snapshot saved earlier
+type Snap struct {
+	// Action is either "save" or "load".
+	Action string
+
+	Name string
+}
+
+// ParseSnap parses the arguments of a snap command.
+func ParseSnap(args []string) (Snap, error) {
+	var s Snap
+
+	if len(args) != 2 {
+		return s, fmt.Errorf("expected 'snap save|load NAME'")
+	}
+
+	s.Action = strings.ToLower(args[0])
+	if s.Action != "save" && s.Action != "load" {
+		return s, fmt.Errorf("expected 'save' or 'load', got '%s'", args[0])
+	}
+
+	s.Name = args[1]
+	return s, nil
+}
@@ -0,0 +1,313 @@
+// Package script implements the de2gui stimulus script format, which is used
+// to drive a simulation without any user interaction, and to check its
+// outputs.
+//
+// A script is a plain text file containing one command per line. Blank lines
+// are ignored, as is anything following a '#' character. Numbers may be
+// written in any notation accepted by strconv.ParseUint with base 0, e.g.
+// 42, 0x2a, 0b101010, or 0o52.
+//
+//	tick N                  advance the simulation by N ticks, at most
+//	                        MaxTicks
+//	at N                    advance the simulation until the tick # is N
+//	sw V                    set the switches to V
+//	sw I on|off             turn switch I on or off
+//	key I [hold N]          press KEY I, releasing it after N ticks
+//	key I down|up           press or release KEY I until further notice
+//	reset                   run the OnReset callback
+//	bounce N                make SW and KEY changes bounce for N ticks
+//	fault ...               inject or remove a fault, see Fault
+//	glitch sw|key M         invert the bits in M for one tick
+//	expect TARGET V [mask M]
+//	                        check that TARGET has the value V
+//	run until TARGET OP V [limit N]
+//	                        tick until TARGET compares to V, see Run
+//	snap save|load NAME     save or restore a snapshot, see Snap
+//
+// The TARGET of an expect command is one of ledr, ledg, sw, key, tick,
+// hex0...hex7 (or however many HEX displays the board has), or hex. For the
+// hexN targets, V may either be a segment pattern in the same encoding used
+// by de2gui.UIState.SetHEX(), or a single character 0-9, A-F, or _ (blank)
+// which is compared against the digit being displayed. For the hex target,
+// V is a string of such characters, which is compared against the rightmost
+// HEX displays, for example "expect hex 0012" checks HEX3...HEX0.
+//
+// Scripts are executed by de2gui.UIState.RunScript(), which produces a
+// Report. The same commands can be typed into the command line in the
+// de2gui window, so that commands tried out interactively can be pasted
+// into a script.
+package script
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// Command is a single line of a script, split into whitespace-separated
+// fields.
+type Command struct {
+	// Line is the line number on which the command appeared, starting
+	// from 1. It is 0 for commands which were not parsed from a file.
+	Line int
+
+	// Name is the name of the command, such as "tick" or "expect".
+	Name string
+
+	// Args are the remaining fields of the command.
+	Args []string
+}
+
+// Script is a parsed stimulus script.
+type Script struct {
+	// Name is used to identify the script in error messages and reports,
+	// it is usually the file name.
+	Name string
+
+	Commands []Command
+}
+
+// SyntaxError is returned when a script cannot be parsed.
+type SyntaxError struct {
+	Name string
+	Line int
+	Msg  string
+}
+
+func (e *SyntaxError) Error() string {
+	if e.Name == "" {
+		return fmt.Sprintf("line %d: %s", e.Line, e.Msg)
+	}
+	return fmt.Sprintf("%s:%d: %s", e.Name, e.Line, e.Msg)
+}
+
+// validators maps every known command name to a function which checks that
+// its arguments are well formed.
+var validators = map[string]func([]string) error{
+	"tick":   func(args []string) error { _, err := parseTickArgs(args); return err },
+	"at":     validateCount,
+	"sw":     func(args []string) error { _, err := ParseSW(args); return err },
+	"key":    func(args []string) error { _, err := ParseKey(args); return err },
+	"reset":  validateNone,
+	"bounce": validateCount,
+	"fault":  func(args []string) error { _, err := ParseFault(args); return err },
+	"glitch": func(args []string) error { _, err := ParseGlitch(args); return err },
+	"expect": func(args []string) error { _, err := ParseExpect(args); return err },
+	"run":    func(args []string) error { _, err := ParseRun(args); return err },
+	"snap":   func(args []string) error { _, err := ParseSnap(args); return err },
+}
+
+func validateNone(args []string) error {
+	if len(args) != 0 {
+		return fmt.Errorf("expected no arguments, got %d", len(args))
+	}
+	return nil
+}
+
+func validateCount(args []string) error {
+	if len(args) != 1 {
+		return fmt.Errorf("expected 1 argument, got %d", len(args))
+	}
+	_, err := ParseNumber(args[0])
+	return err
+}
+
+// MaxTicks is the largest number of ticks a tick command may run.
+const MaxTicks = math.MaxInt32
+
+// ParseTicks parses the number of ticks of a tick command, which must be
+// at most MaxTicks.
+func ParseTicks(s string) (int, error) {
+	n, err := ParseNumber(s)
+	if err != nil {
+		return 0, err
+	}
+	if n > MaxTicks {
+		return 0, fmt.Errorf("cannot run more than %d ticks at once", MaxTicks)
+	}
+	return int(n), nil
+}
+
+func parseTickArgs(args []string) (int, error) {
+	if len(args) != 1 {
+		return 0, fmt.Errorf("expected 1 argument, got %d", len(args))
+	}
+	return ParseTicks(args[0])
+}
+
+// ParseNumber parses a number as it is written in a script.
+func ParseNumber(s string) (uint64, error) {
+	v, err := strconv.ParseUint(s, 0, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid number '%s'", s)
+	}
+	return v, nil
+}
+
+// Key is the parsed form of a key command.
+type Key struct {
+	// Index is the number of the KEY.
+	Index int
+
+	// Action is one of "press", "down" or "up".
+	Action string
+
+	// Hold is the number of ticks a pressed KEY is held for, or 0 if the
+	// command did not specify.
+	Hold uint64
+}
+
+// ParseKey parses the arguments of a key command.
+func ParseKey(args []string) (Key, error) {
+	k := Key{Action: "press"}
+
+	if len(args) < 1 || len(args) > 3 {
+		return k, fmt.Errorf("expected 'key I [hold N]' or 'key I down|up'")
+	}
+
+	i, err := ParseNumber(args[0])
+	if err != nil {
+		return k, err
+	}
+	k.Index = int(i)
+
+	switch {
+	case len(args) == 1:
+
+	case len(args) == 2 && (args[1] == "down" || args[1] == "up"):
+		k.Action = args[1]
+
+	case len(args) == 3 && args[1] == "hold":
+		k.Hold, err = ParseNumber(args[2])
+		if err != nil {
+			return k, err
+		}
+
+	default:
+		return k, fmt.Errorf("expected 'key I [hold N]' or 'key I down|up'")
+	}
+
+	return k, nil
+}
+
+// Switch is the parsed form of a sw command.
+type Switch struct {
+	// Value is the new value of all of the switches. It is only used if
+	// Single is false.
+	Value uint64
+
+	// Single is true if the command changes only one switch, given by
+	// Index, which is turned on if On is true.
+	Single bool
+	Index  int
+	On     bool
+}
+
+// ParseSW parses the arguments of a sw command.
+func ParseSW(args []string) (Switch, error) {
+	var sw Switch
+
+	if len(args) < 1 || len(args) > 2 {
+		return sw, fmt.Errorf("expected 'sw V' or 'sw I on|off'")
+	}
+
+	v, err := ParseNumber(args[0])
+	if err != nil {
+		return sw, err
+	}
+
+	if len(args) == 1 {
+		sw.Value = v
+		return sw, nil
+	}
+
+	sw.Single = true
+	sw.Index = int(v)
+	switch args[1] {
+	case "on":
+		sw.On = true
+	case "off":
+	default:
+		return sw, fmt.Errorf("expected 'on' or 'off', got '%s'", args[1])
+	}
+
+	return sw, nil
+}
+
+// ParseLine parses a single line of a script. If the line is blank or
+// contains only a comment, nil is returned.
+func ParseLine(line string) (*Command, error) {
+	if i := strings.IndexByte(line, '#'); i >= 0 {
+		line = line[:i]
+	}
+
+	fields := strings.Fields(line)
+	if len(fields) == 0 {
+		return nil, nil
+	}
+
+	c := &Command{
+		Name: strings.ToLower(fields[0]),
+		Args: fields[1:],
+	}
+
+	validate, ok := validators[c.Name]
+	if !ok {
+		return nil, fmt.Errorf("unknown command '%s'", fields[0])
+	}
+
+	if err := validate(c.Args); err != nil {
+		return nil, fmt.Errorf("%s: %v", c.Name, err)
+	}
+
+	return c, nil
+}
+
+// Parse reads an entire script from r. The name is used to label the script
+// in error messages and reports.
+func Parse(name string, r io.Reader) (*Script, error) {
+	sc := &Script{Name: name}
+
+	scanner := bufio.NewScanner(r)
+	lineno := 0
+	for scanner.Scan() {
+		lineno++
+		c, err := ParseLine(scanner.Text())
+		if err != nil {
+			return nil, &SyntaxError{Name: name, Line: lineno, Msg: err.Error()}
+		}
+
+		if c == nil {
+			continue
+		}
+
+		c.Line = lineno
+		sc.Commands = append(sc.Commands, *c)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return sc, nil
+}
+
+// ParseFile reads an entire script from the file at path.
+func ParseFile(path string) (*Script, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	return Parse(path, f)
+}
+
+// String formats the command as it would be written in a script.
+func (c Command) String() string {
+	return strings.Join(append([]string{c.Name}, c.Args...), " ")
+}
@@ -0,0 +1,271 @@
+package script
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseLine(t *testing.T) {
+	tests := []struct {
+		line string
+		want *Command
+		err  string
+	}{
+		{"", nil, ""},
+		{"   # just a comment", nil, ""},
+		{"tick 5", &Command{Name: "tick", Args: []string{"5"}}, ""},
+		{"  TICK 0x10  # comment", &Command{Name: "tick", Args: []string{"0x10"}}, ""},
+		{"reset", &Command{Name: "reset", Args: []string{}}, ""},
+		{"expect hex 0012", &Command{Name: "expect", Args: []string{"hex", "0012"}}, ""},
+		{"tick", nil, "tick: expected 1 argument, got 0"},
+		{"tick five", nil, "tick: invalid number 'five'"},
+		{"tick 0x80000000", nil, "tick: cannot run more than 2147483647 ticks at once"},
+		{"reset now", nil, "reset: expected no arguments, got 1"},
+		{"jump 5", nil, "unknown command 'jump'"},
+		{"expect ledz 1", nil, "expect: unknown target 'ledz'"},
+	}
+
+	for _, test := range tests {
+		c, err := ParseLine(test.line)
+		if test.err != "" {
+			if err == nil || err.Error() != test.err {
+				t.Errorf("ParseLine(%q) returned error %v, expected %q", test.line, err, test.err)
+			}
+			continue
+		}
+
+		if err != nil {
+			t.Errorf("ParseLine(%q) returned error %v", test.line, err)
+			continue
+		}
+		if !reflect.DeepEqual(c, test.want) {
+			t.Errorf("ParseLine(%q) = %+v, expected %+v", test.line, c, test.want)
+		}
+	}
+}
+
+func TestParse(t *testing.T) {
+	sc, err := Parse("test.txt", strings.NewReader("# a script\n\nsw 3\ntick 1\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []Command{
+		{Line: 3, Name: "sw", Args: []string{"3"}},
+		{Line: 4, Name: "tick", Args: []string{"1"}},
+	}
+	if sc.Name != "test.txt" || !reflect.DeepEqual(sc.Commands, want) {
+		t.Errorf("Parse() = %+v, expected the commands %+v", sc, want)
+	}
+
+	_, err = Parse("test.txt", strings.NewReader("tick 1\nbogus\n"))
+	if err == nil || err.Error() != "test.txt:2: unknown command 'bogus'" {
+		t.Errorf("Parse() returned error %v, expected a syntax error on line 2", err)
+	}
+}
+
+func TestParseExpect(t *testing.T) {
+	all := ^uint64(0)
+
+	tests := []struct {
+		args string
+		want Expect
+		err  bool
+	}{
+		{"ledr 5", Expect{Target: "ledr", Value: 5, Mask: all}, false},
+		{"LEDG 0b11 mask 0x2", Expect{Target: "ledg", Value: 3, Mask: 2}, false},
+		{"tick 100", Expect{Target: "tick", Value: 100, Mask: all}, false},
+		{"hex3 0x40", Expect{Target: "hex3", Index: 3, Value: 0x40, Mask: all}, false},
+		{"hex3 a", Expect{Target: "hex3", Index: 3, Digits: "A", Mask: all}, false},
+		{"hex3 _", Expect{Target: "hex3", Index: 3, Digits: "_", Mask: all}, false},
+		{"hex 00f_", Expect{Target: "hex", Digits: "00F_", Mask: all}, false},
+		{"hex 0g", Expect{}, true},
+		{"hex3 g", Expect{}, true},
+		{"hex-1 0", Expect{}, true},
+		{"hexa 0", Expect{}, true},
+		{"hex3 a mask 1", Expect{}, true},
+		{"ledr 5 mask", Expect{}, true},
+		{"ledr 5 with 1", Expect{}, true},
+		{"ledr", Expect{}, true},
+	}
+
+	for _, test := range tests {
+		e, err := ParseExpect(strings.Fields(test.args))
+		switch {
+		case test.err && err == nil:
+			t.Errorf("ParseExpect(%q) = %+v, expected an error", test.args, e)
+		case !test.err && err != nil:
+			t.Errorf("ParseExpect(%q) returned error %v", test.args, err)
+		case !test.err && e != test.want:
+			t.Errorf("ParseExpect(%q) = %+v, expected %+v", test.args, e, test.want)
+		}
+	}
+}
+
+func TestParseRun(t *testing.T) {
+	tests := []struct {
+		args   string
+		target string
+		op     string
+		value  uint64
+		digits string
+		limit  uint64
+	}{
+		{"until ledr<=5", "ledr", "<=", 5, "", DefaultRunLimit},
+		{"until ledr < 5", "ledr", "<", 5, "", DefaultRunLimit},
+		{"until ledr>=5", "ledr", ">=", 5, "", DefaultRunLimit},
+		{"until ledr > 5", "ledr", ">", 5, "", DefaultRunLimit},
+		{"until ledr=5", "ledr", "==", 5, "", DefaultRunLimit},
+		{"until ledr == 5", "ledr", "==", 5, "", DefaultRunLimit},
+		{"until ledr!=0 limit 10", "ledr", "!=", 0, "", 10},
+		{"UNTIL hex0 = 7 LIMIT 0x10", "hex0", "==", 0, "7", 16},
+		{"until hex != 0012", "hex", "!=", 0, "0012", DefaultRunLimit},
+	}
+
+	for _, test := range tests {
+		r, err := ParseRun(strings.Fields(test.args))
+		if err != nil {
+			t.Errorf("ParseRun(%q) returned error %v", test.args, err)
+			continue
+		}
+
+		e := r.Expect
+		if e.Target != test.target || r.Op != test.op || e.Value != test.value || e.Digits != test.digits || r.Limit != test.limit {
+			t.Errorf("ParseRun(%q) = %+v, expected %s %s %d%s limit %d",
+				test.args, r, test.target, test.op, test.value, test.digits, test.limit)
+		}
+	}
+
+	for _, args := range []string{
+		"",
+		"ledr == 5",
+		"until ledr",
+		"until == 5",
+		"until ledr ==",
+		"until ledr ~ 5",
+		"until ledr == 5 limit x",
+		"until hex0 < 7",
+		"until ledz == 5",
+	} {
+		if r, err := ParseRun(strings.Fields(args)); err == nil {
+			t.Errorf("ParseRun(%q) = %+v, expected an error", args, r)
+		}
+	}
+}
+
+func TestRunHolds(t *testing.T) {
+	tests := []struct {
+		op       string
+		observed uint64
+		pass     bool
+		want     bool
+	}{
+		{"==", 5, true, true},
+		{"==", 5, false, false},
+		{"!=", 5, false, true},
+		{"<", 4, false, true},
+		{"<", 5, false, false},
+		{"<=", 5, false, true},
+		{"<=", 6, false, false},
+		{">", 6, false, true},
+		{">", 5, false, false},
+		{">=", 5, false, true},
+		{">=", 4, false, false},
+	}
+
+	for _, test := range tests {
+		r := Run{Expect: Expect{Value: 5}, Op: test.op}
+		if got := r.Holds(test.observed, test.pass); got != test.want {
+			t.Errorf("%d %s 5 (pass %v) = %v, expected %v", test.observed, test.op, test.pass, got, test.want)
+		}
+	}
+}
+
+func TestParseKey(t *testing.T) {
+	tests := []struct {
+		args string
+		want Key
+		err  bool
+	}{
+		{"0", Key{Index: 0, Action: "press"}, false},
+		{"3 hold 0x20", Key{Index: 3, Action: "press", Hold: 32}, false},
+		{"1 down", Key{Index: 1, Action: "down"}, false},
+		{"1 up", Key{Index: 1, Action: "up"}, false},
+		{"", Key{}, true},
+		{"x", Key{}, true},
+		{"1 sideways", Key{}, true},
+		{"1 hold", Key{}, true},
+		{"1 hold x", Key{}, true},
+		{"1 down 5", Key{}, true},
+		{"1 hold 5 6", Key{}, true},
+	}
+
+	for _, test := range tests {
+		k, err := ParseKey(strings.Fields(test.args))
+		switch {
+		case test.err && err == nil:
+			t.Errorf("ParseKey(%q) = %+v, expected an error", test.args, k)
+		case !test.err && err != nil:
+			t.Errorf("ParseKey(%q) returned error %v", test.args, err)
+		case !test.err && k != test.want:
+			t.Errorf("ParseKey(%q) = %+v, expected %+v", test.args, k, test.want)
+		}
+	}
+}
+
+func TestParseSW(t *testing.T) {
+	tests := []struct {
+		args string
+		want Switch
+		err  bool
+	}{
+		{"0x3ffff", Switch{Value: 0x3ffff}, false},
+		{"0b101", Switch{Value: 5}, false},
+		{"4 on", Switch{Single: true, Index: 4, On: true}, false},
+		{"4 off", Switch{Single: true, Index: 4}, false},
+		{"", Switch{}, true},
+		{"on", Switch{}, true},
+		{"4 up", Switch{}, true},
+		{"4 on off", Switch{}, true},
+	}
+
+	for _, test := range tests {
+		sw, err := ParseSW(strings.Fields(test.args))
+		switch {
+		case test.err && err == nil:
+			t.Errorf("ParseSW(%q) = %+v, expected an error", test.args, sw)
+		case !test.err && err != nil:
+			t.Errorf("ParseSW(%q) returned error %v", test.args, err)
+		case !test.err && sw != test.want:
+			t.Errorf("ParseSW(%q) = %+v, expected %+v", test.args, sw, test.want)
+		}
+	}
+}
+
+func TestParseTicks(t *testing.T) {
+	tests := []struct {
+		s    string
+		want int
+		err  bool
+	}{
+		{"0", 0, false},
+		{"5000", 5000, false},
+		{"0x7fffffff", MaxTicks, false},
+		{"0x80000000", 0, true},
+		{"18446744073709551615", 0, true},
+		{"-1", 0, true},
+	}
+
+	for _, test := range tests {
+		n, err := ParseTicks(test.s)
+		switch {
+		case test.err && err == nil:
+			t.Errorf("ParseTicks(%q) = %d, expected an error", test.s, n)
+		case !test.err && err != nil:
+			t.Errorf("ParseTicks(%q) returned error %v", test.s, err)
+		case !test.err && n != test.want:
+			t.Errorf("ParseTicks(%q) = %d, expected %d", test.s, n, test.want)
+		}
+	}
+}
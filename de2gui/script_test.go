@@ -0,0 +1,154 @@
+package de2gui
+
+import (
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	"github.com/herclab/de2gui/de2gui/script"
+)
+
+// newCounterBoard returns a headless board with a design which counts
+// ticks, showing the count on LEDR and its low digit on HEX0, and KEY on
+// LEDG. The count is cleared by reset, and saved in snapshots, and the
+// design panics when SW17 is on.
+func newCounterBoard() *UIState {
+	s := NewHeadlessUIState()
+	s.LogOutput = ioutil.Discard
+
+	count := uint32(0)
+	show := func(s *UIState) {
+		s.SetLEDR(count)
+		s.SetHEX(0, HexSegments(uint8(count&0xf)))
+	}
+
+	s.OnTick = func(s *UIState, final bool) {
+		if s.SW()&(1<<17) != 0 {
+			panic("SW17 is on")
+		}
+		s.Tick++
+		count++
+		show(s)
+	}
+	s.OnKEY = func(s *UIState) { s.SetLEDG(s.KEY()) }
+	s.OnReset = func(s *UIState) {
+		count = 0
+		show(s)
+	}
+	s.OnSnapshot = func(s *UIState) interface{} { return count }
+	s.OnRestore = func(s *UIState, state interface{}) { count = state.(uint32) }
+
+	show(s)
+	return s
+}
+
+// runScript parses and runs a script on s, failing the test if it does not
+// parse
+func runScript(t *testing.T, s *UIState, text string) *script.Report {
+	t.Helper()
+
+	sc, err := script.Parse("test", strings.NewReader(text))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s.RunScript(sc)
+}
+
+func TestRunScript(t *testing.T) {
+	s := newCounterBoard()
+
+	r := runScript(t, s, `
+		expect ledr 0
+		expect hex0 0
+		tick 5
+		expect ledr 5
+		expect ledr 4           # fails
+		expect hex0 5
+		expect hex 5            # the rightmost display only
+		at 18
+		expect tick 18
+		expect hex0 2
+		expect ledr 0x10 mask 0x10
+		run until ledr >= 20
+		expect tick 20
+		run until hex0 = 0
+		expect ledr 32
+		key 1 down
+		expect ledg 2
+		key 1 up
+		expect ledg 0
+		key 2 hold 3
+		expect ledg 4
+		tick 2
+		expect ledg 4
+		tick 2
+		expect ledg 0
+		sw 3 on
+		expect sw 8
+		sw 0x5
+		expect sw 5
+		reset
+		expect ledr 0
+	`)
+
+	if r.Error != "" {
+		t.Fatalf("RunScript() failed: %s", r.Error)
+	}
+	if len(r.Results) != 19 {
+		t.Fatalf("RunScript() reported %d results, expected 19: %+v", len(r.Results), r.Results)
+	}
+
+	for i, res := range r.Results {
+		if res.Pass != (res.Check != "expect ledr 4") {
+			t.Errorf("result %d: %+v", i, res)
+		}
+	}
+
+	failed := r.Results[3]
+	if failed.Line != 6 || failed.Tick != 5 || failed.Expected != "0x00004" || failed.Observed != "0x00005" {
+		t.Errorf("the failed result is %+v", failed)
+	}
+}
+
+func TestRunScriptErrors(t *testing.T) {
+	tests := []struct {
+		text    string
+		results int
+		err     string
+	}{
+		{"expect hex8 0", 0, "line 1: expect: there is no HEX8"},
+		{"expect hex 000000000", 0, "line 1: expect: there are only 8 HEX displays"},
+		{"sw 18 on", 0, "line 1: sw: there is no SW18"},
+		{"key 4", 0, "line 1: key: there is no KEY4"},
+		{"snap load nothing", 0, "line 1: snap: there is no snapshot named 'nothing'"},
+		{"expect ledr 0\nrun until ledr == 100 limit 10\nexpect ledr 0", 1, "line 2: run: the condition did not hold within 10 ticks"},
+		{"expect ledr 0\nsw 17 on\ntick 1\nexpect ledr 0", 1, "line 3: tick: OnTick panicked at tick 0: SW17 is on"},
+		{"sw 17 on\nrun until ledr == 1", 0, "line 2: run: OnTick panicked at tick 0: SW17 is on"},
+	}
+
+	for _, test := range tests {
+		r := runScript(t, newCounterBoard(), test.text)
+		if len(r.Results) != test.results || !strings.HasPrefix(r.Error, test.err) {
+			t.Errorf("%q: got %d results and error %q, expected %d and %q",
+				test.text, len(r.Results), r.Error, test.results, test.err)
+		}
+	}
+}
+
+func TestRunScriptSnapshots(t *testing.T) {
+	r := runScript(t, newCounterBoard(), `
+		tick 3
+		snap save three
+		tick 4
+		expect ledr 7
+		snap load three
+		expect tick 3
+		expect ledr 3
+		tick 1
+		expect ledr 4
+	`)
+
+	if r.Error != "" || r.Passed() != 4 {
+		t.Errorf("RunScript() = %+v, expected 4 passing checks", r)
+	}
+}
@@ -0,0 +1,82 @@
+// Package session implements recordings of the inputs and outputs of a
+// de2gui board, see de2gui.UIState.StartRecording(). A recorded session can
+// be exported as a self-checking SystemVerilog testbench, so that a bug found
+// while using the GUI can be turned into a regression test.
+package session
+
+// Event is a single change to one of the board's signals.
+type Event struct {
+	// Cycle is the number of ticks which had elapsed since the recording
+	// started when the change occurred. Unlike Tick, it is not affected
+	// by the simulation being reset.
+	Cycle uint64 `json:"cycle"`
+
+	// Tick is the value of UIState.Tick when the change occurred.
+	Tick uint64 `json:"tick"`
+
+	// Signal is the name of the signal which changed, one of SW, KEY,
+	// LEDR, LEDG, or HEX0...HEX7. The special signal RESET is used to
+	// record that the reset button was used, FAULT to record that the
+	// injected faults changed, and RESTART to record that the simulator
+	// was restarted after it exited.
+	Signal string `json:"signal"`
+
+	// Value is the new value of the signal. KEY values are stored
+	// active-high, the same way as UIState.KEY() returns them.
+	//
+	// Inputs are recorded as the design saw them, including any bounce
+	// and faults. Outputs are recorded as the design produced them,
+	// before any faults were applied.
+	Value uint32 `json:"value"`
+
+	// Detail describes a FAULT event, as one or more script commands
+	// separated by semicolons, or the way the simulator exited for a
+	// RESTART event.
+	Detail string `json:"detail,omitempty"`
+}
+
+// Board describes the number of each kind of signal on the board a session
+// was recorded on, see de2gui.BoardProfile.
+type Board struct {
+	Name string `json:"name"`
+	HEX  int    `json:"hex"`
+	LEDR int    `json:"ledr"`
+	LEDG int    `json:"ledg"`
+	SW   int    `json:"sw"`
+	KEY  int    `json:"key"`
+}
+
+// DE2115 describes the DE2-115, which is assumed for sessions which do not
+// say which board they were recorded on.
+var DE2115 = Board{Name: "DE2-115", HEX: 8, LEDR: 18, LEDG: 9, SW: 18, KEY: 4}
+
+// Session is a recording of every change to the inputs and outputs of a
+// board, in the order they happened.
+//
+// The first events of a session, all with a Cycle of 0, record the state of
+// the board when the recording started.
+type Session struct {
+	Events []Event `json:"events"`
+
+	// Length is the total number of ticks which elapsed while the session
+	// was being recorded.
+	Length uint64 `json:"length"`
+
+	// Board is the board the session was recorded on. If it is nil, the
+	// DE2-115 is assumed.
+	Board *Board `json:"board,omitempty"`
+}
+
+// board returns the board the session was recorded on
+func (s *Session) board() Board {
+	if s.Board == nil {
+		return DE2115
+	}
+	return *s.Board
+}
+
+// IsInput returns true if the signal is one of the board's inputs (SW and
+// KEY).
+func IsInput(signal string) bool {
+	return signal == "SW" || signal == "KEY"
+}
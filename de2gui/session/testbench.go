@@ -0,0 +1,218 @@
+package session
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// TestbenchOptions controls the testbench generated by WriteTestbench().
+type TestbenchOptions struct {
+	// Module is the name of the design's top level module. It defaults
+	// to "top".
+	Module string
+
+	// Name is the name of the generated testbench module. It defaults to
+	// Module with "_tb" appended.
+	Name string
+
+	// ClockPeriod is the period of CLOCK_50 in nanoseconds. It defaults to
+	// 20 (50MHz).
+	ClockPeriod int
+
+	// KEYActiveHigh causes KEY to be driven active-high. By default it is
+	// driven active-low, as it is on all of the Terasic boards.
+	KEYActiveHigh bool
+}
+
+// widthHEX is the width of each HEX port
+const widthHEX = 7
+
+// width returns the width in bits of the named signal, or 0 if the board
+// does not have it
+func (b Board) width(signal string) int {
+	switch signal {
+	case "SW":
+		return b.SW
+	case "KEY":
+		return b.KEY
+	case "LEDR":
+		return b.LEDR
+	case "LEDG":
+		return b.LEDG
+	}
+
+	var i int
+	if _, err := fmt.Sscanf(signal, "HEX%d", &i); err == nil && i >= 0 && i < b.HEX {
+		return widthHEX
+	}
+	return 0
+}
+
+// literal formats v as a SystemVerilog literal of the given width
+func literal(width int, v uint32) string {
+	v &= (1 << uint(width)) - 1
+	return fmt.Sprintf("%d'h%0*x", width, (width+3)/4, v)
+}
+
+// WriteTestbench writes a self-checking SystemVerilog testbench to w which
+// replays the inputs recorded in the session, and checks that the design's
+// outputs change in the same way that they did during the recording.
+//
+// Each tick is treated as one cycle of CLOCK_50. The outputs recorded at the
+// start of the session are not checked, since they reflect the state of the
+// simulation before the recording began; for a faithful testbench, the
+// recording should be started immediately after a reset. Uses of the reset
+// button are noted in the testbench as comments, since the boards do not
+// have a dedicated reset input.
+//
+// The ports of the design are those of the board the session was recorded
+// on, see Session.Board. Signals which the board does not have, such as
+// LEDG on boards without green LEDs, have no port.
+func (s *Session) WriteTestbench(w io.Writer, opts TestbenchOptions) error {
+	if opts.Module == "" {
+		opts.Module = "top"
+	}
+
+	if opts.Name == "" {
+		opts.Name = opts.Module + "_tb"
+	}
+
+	if opts.ClockPeriod <= 0 {
+		opts.ClockPeriod = 20
+	}
+
+	keyValue := func(v uint32) uint32 {
+		if opts.KEYActiveHigh {
+			return v
+		}
+		return ^v
+	}
+
+	bw := bufio.NewWriter(w)
+	p := func(format string, args ...interface{}) {
+		fmt.Fprintf(bw, format+"\n", args...)
+	}
+
+	b := s.board()
+
+	hexNames := make([]string, b.HEX)
+	for i := range hexNames {
+		hexNames[i] = fmt.Sprintf("HEX%d", i)
+	}
+
+	p("// Generated by de2gui from a recorded session of %d cycles.", s.Length)
+	p("`timescale 1ns/1ps")
+	p("")
+	p("module %s;", opts.Name)
+	p("\tlogic CLOCK_50 = 1'b0;")
+	if b.SW > 0 {
+		p("\tlogic [%d:0] SW = %s;", b.SW-1, literal(b.SW, 0))
+	}
+	if b.KEY > 0 {
+		p("\tlogic [%d:0] KEY = %s;", b.KEY-1, literal(b.KEY, keyValue(0)))
+	}
+	if b.LEDR > 0 {
+		p("\twire [%d:0] LEDR;", b.LEDR-1)
+	}
+	if b.LEDG > 0 {
+		p("\twire [%d:0] LEDG;", b.LEDG-1)
+	}
+	if b.HEX > 0 {
+		p("\twire [%d:0] %s;", widthHEX-1, strings.Join(hexNames, ", "))
+	}
+	p("")
+	p("\tint cycle = 0;")
+	p("\tint errors = 0;")
+	p("")
+	p("\t%s dut (", opts.Module)
+	ports := []string{"CLOCK_50"}
+	for _, name := range []string{"SW", "KEY", "LEDR", "LEDG"} {
+		if b.width(name) > 0 {
+			ports = append(ports, name)
+		}
+	}
+	ports = append(ports, hexNames...)
+	for i, name := range ports {
+		sep := ","
+		if i == len(ports)-1 {
+			sep = ""
+		}
+		p("\t\t.%s(%s)%s", name, name, sep)
+	}
+	p("\t);")
+	p("")
+	p("\talways #%d CLOCK_50 = ~CLOCK_50;", (opts.ClockPeriod+1)/2)
+	p("")
+	p("\talways @(posedge CLOCK_50) cycle++;")
+	p("")
+	p("\ttask automatic check(input string name, input logic [31:0] observed, input logic [31:0] expected);")
+	p("\t\tif (observed !== expected) begin")
+	p("\t\t\t$error(\"cycle %%0d: %%s is 'h%%0h, expected 'h%%0h\", cycle, name, observed, expected);")
+	p("\t\t\terrors++;")
+	p("\t\tend")
+	p("\tendtask")
+	p("")
+	p("\tinitial begin")
+
+	cycle := uint64(0)
+	settled := true
+	for _, e := range s.Events {
+		if e.Cycle > cycle {
+			p("\t\trepeat (%d) @(posedge CLOCK_50);", e.Cycle-cycle)
+			p("\t\t#1;")
+			p("\t\t// cycle %d, tick %d", e.Cycle, e.Tick)
+			cycle = e.Cycle
+			settled = true
+		}
+
+		switch {
+		case e.Signal == "RESET":
+			p("\t\t// the reset button was used here")
+
+		case e.Signal == "FAULT":
+			p("\t\t// faults injected: %s", e.Detail)
+
+		case e.Signal == "RESTART":
+			p("\t\t// the simulator was restarted here, after: %s", e.Detail)
+
+		case b.width(e.Signal) == 0:
+			// the board has no such port
+
+		case e.Signal == "SW":
+			p("\t\tSW = %s;", literal(b.SW, e.Value))
+			settled = false
+
+		case e.Signal == "KEY":
+			p("\t\tKEY = %s;", literal(b.KEY, keyValue(e.Value)))
+			settled = false
+
+		case e.Cycle == 0:
+			// initial outputs are not checked
+
+		default:
+			if !settled {
+				p("\t\t#1;")
+				settled = true
+			}
+			width := b.width(e.Signal)
+			p("\t\tcheck(\"%s\", %s, %s);", e.Signal, e.Signal, literal(width, e.Value))
+		}
+	}
+
+	if s.Length > cycle {
+		p("\t\trepeat (%d) @(posedge CLOCK_50);", s.Length-cycle)
+	}
+
+	p("")
+	p("\t\tif (errors == 0)")
+	p("\t\t\t$display(\"PASS: all checks passed\");")
+	p("\t\telse")
+	p("\t\t\t$fatal(1, \"FAIL: %%0d checks failed\", errors);")
+	p("\t\t$finish;")
+	p("\tend")
+	p("endmodule")
+
+	return bw.Flush()
+}
@@ -0,0 +1,184 @@
+package session
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+// writeTestbench returns the testbench for s, failing the test on error
+func writeTestbench(t *testing.T, s *Session, opts TestbenchOptions) string {
+	t.Helper()
+
+	var buf bytes.Buffer
+	if err := s.WriteTestbench(&buf, opts); err != nil {
+		t.Fatal(err)
+	}
+	return buf.String()
+}
+
+func TestTestbenchZeroWidth(t *testing.T) {
+	s := &Session{
+		Length: 3,
+		Board:  &Board{Name: "small", HEX: 1, KEY: 2},
+		Events: []Event{
+			{Cycle: 1, Signal: "SW", Value: 1},
+			{Cycle: 1, Signal: "KEY", Value: 1},
+			{Cycle: 2, Signal: "LEDR", Value: 1},
+			{Cycle: 2, Signal: "HEX0", Value: 0x40},
+			{Cycle: 2, Signal: "HEX3", Value: 0x40},
+		},
+	}
+
+	tb := writeTestbench(t, s, TestbenchOptions{})
+
+	for _, bad := range []string{"[-1:0]", "SW", "LEDR", "LEDG", "HEX3", "0'h"} {
+		if strings.Contains(tb, bad) {
+			t.Errorf("testbench contains %q:\n%s", bad, tb)
+		}
+	}
+
+	for _, good := range []string{".KEY(KEY),", ".HEX0(HEX0)\n", "KEY = 2'h2;", `check("HEX0", HEX0, 7'h40);`} {
+		if !strings.Contains(tb, good) {
+			t.Errorf("testbench does not contain %q:\n%s", good, tb)
+		}
+	}
+}
+
+func TestTestbench(t *testing.T) {
+	s := &Session{
+		Length: 5,
+		Board:  &Board{Name: "tiny", HEX: 1, LEDR: 4, LEDG: 2, SW: 4, KEY: 2},
+		Events: []Event{
+			{Cycle: 0, Tick: 10, Signal: "SW", Value: 0},
+			{Cycle: 0, Tick: 10, Signal: "KEY", Value: 0},
+			{Cycle: 0, Tick: 10, Signal: "LEDR", Value: 3},
+			{Cycle: 0, Tick: 10, Signal: "HEX0", Value: 0x40},
+			{Cycle: 1, Tick: 11, Signal: "SW", Value: 5},
+			{Cycle: 1, Tick: 11, Signal: "LEDR", Value: 5},
+			{Cycle: 2, Tick: 12, Signal: "KEY", Value: 1},
+			{Cycle: 2, Tick: 12, Signal: "FAULT", Detail: "fault ledr 0 stuck 1"},
+			{Cycle: 3, Tick: 0, Signal: "RESET"},
+			{Cycle: 3, Tick: 0, Signal: "LEDG", Value: 2},
+			{Cycle: 3, Tick: 0, Signal: "HEX0", Value: 0x79},
+			{Cycle: 4, Tick: 1, Signal: "RESTART", Detail: "exit status 1"},
+		},
+	}
+
+	tb := writeTestbench(t, s, TestbenchOptions{Module: "counter", ClockPeriod: 10, KEYActiveHigh: true})
+
+	want := "// Generated by de2gui from a recorded session of 5 cycles.\n" +
+		"`timescale 1ns/1ps\n" +
+		`
+module counter_tb;
+	logic CLOCK_50 = 1'b0;
+	logic [3:0] SW = 4'h0;
+	logic [1:0] KEY = 2'h0;
+	wire [3:0] LEDR;
+	wire [1:0] LEDG;
+	wire [6:0] HEX0;
+
+	int cycle = 0;
+	int errors = 0;
+
+	counter dut (
+		.CLOCK_50(CLOCK_50),
+		.SW(SW),
+		.KEY(KEY),
+		.LEDR(LEDR),
+		.LEDG(LEDG),
+		.HEX0(HEX0)
+	);
+
+	always #5 CLOCK_50 = ~CLOCK_50;
+
+	always @(posedge CLOCK_50) cycle++;
+
+	task automatic check(input string name, input logic [31:0] observed, input logic [31:0] expected);
+		if (observed !== expected) begin
+			$error("cycle %0d: %s is 'h%0h, expected 'h%0h", cycle, name, observed, expected);
+			errors++;
+		end
+	endtask
+
+	initial begin
+		SW = 4'h0;
+		KEY = 2'h0;
+		repeat (1) @(posedge CLOCK_50);
+		#1;
+		// cycle 1, tick 11
+		SW = 4'h5;
+		#1;
+		check("LEDR", LEDR, 4'h5);
+		repeat (1) @(posedge CLOCK_50);
+		#1;
+		// cycle 2, tick 12
+		KEY = 2'h1;
+		// faults injected: fault ledr 0 stuck 1
+		repeat (1) @(posedge CLOCK_50);
+		#1;
+		// cycle 3, tick 0
+		// the reset button was used here
+		check("LEDG", LEDG, 2'h2);
+		check("HEX0", HEX0, 7'h79);
+		repeat (1) @(posedge CLOCK_50);
+		#1;
+		// cycle 4, tick 1
+		// the simulator was restarted here, after: exit status 1
+		repeat (1) @(posedge CLOCK_50);
+
+		if (errors == 0)
+			$display("PASS: all checks passed");
+		else
+			$fatal(1, "FAIL: %0d checks failed", errors);
+		$finish;
+	end
+endmodule
+`
+
+	if tb != want {
+		t.Errorf("got the testbench:\n%s\nexpected:\n%s", tb, want)
+	}
+}
+
+func TestTestbenchDefaults(t *testing.T) {
+	s := &Session{
+		Length: 1,
+		Events: []Event{
+			{Cycle: 0, Signal: "KEY", Value: 0},
+			{Cycle: 1, Signal: "KEY", Value: 0x5},
+		},
+	}
+
+	tb := writeTestbench(t, s, TestbenchOptions{})
+
+	// the DE2-115 is assumed, and KEY is active-low
+	for _, good := range []string{
+		"module top_tb;",
+		"logic [17:0] SW = 18'h00000;",
+		"logic [3:0] KEY = 4'hf;",
+		"wire [8:0] LEDG;",
+		"wire [6:0] HEX0, HEX1, HEX2, HEX3, HEX4, HEX5, HEX6, HEX7;",
+		"\ttop dut (",
+		".HEX7(HEX7)\n",
+		"always #10 CLOCK_50",
+		"KEY = 4'ha;",
+	} {
+		if !strings.Contains(tb, good) {
+			t.Errorf("testbench does not contain %q:\n%s", good, tb)
+		}
+	}
+}
+
+func TestIsInput(t *testing.T) {
+	for _, signal := range []string{"SW", "KEY"} {
+		if !IsInput(signal) {
+			t.Errorf("IsInput(%q) = false", signal)
+		}
+	}
+	for _, signal := range []string{"LEDR", "LEDG", "HEX0", "RESET", "FAULT"} {
+		if IsInput(signal) {
+			t.Errorf("IsInput(%q) = true", signal)
+		}
+	}
+}
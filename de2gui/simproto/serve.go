@@ -0,0 +1,116 @@
+package simproto
+
+import (
+	"fmt"
+	"io"
+)
+
+// Outputs is the state of a design's outputs.
+type Outputs struct {
+	LEDR uint32
+	LEDG uint32
+
+	// HEX contains the segments of each display, active-low.
+	HEX []uint8
+}
+
+// Model is a design simulated in Go, see Serve().
+type Model interface {
+	// SetInputs changes the design's inputs. KEY has a bit set for each
+	// KEY which is pressed.
+	SetInputs(sw, key uint32)
+
+	// Tick runs the design for a clock cycle.
+	Tick()
+
+	// Reset resets the design.
+	Reset()
+
+	// Outputs returns the state of the design's outputs.
+	Outputs() Outputs
+}
+
+// Serve runs the simulator's side of the protocol over rw, which is usually
+// a connection returned by Dial(), until de2gui disconnects. Only the
+// outputs which changed are sent after each message, except after HELLO and
+// RESET, when all of them are sent.
+func Serve(rw io.ReadWriter, m Model) error {
+	c := NewConn(rw)
+	var sw, key uint32
+	var last *Outputs
+
+	for {
+		msg, err := c.Receive()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		switch msg.Name {
+		case "HELLO":
+			if msg.Value != fmt.Sprint(Version) {
+				return fmt.Errorf("unsupported protocol version %s", msg.Value)
+			}
+			last = nil
+
+		case "SW", "KEY":
+			v, err := msg.Uint()
+			if err != nil {
+				return err
+			}
+
+			if msg.Name == "SW" {
+				sw = v
+			} else {
+				key = v
+			}
+			m.SetInputs(sw, key)
+
+		case "TICK":
+			n, err := msg.Uint()
+			if err != nil {
+				return err
+			}
+
+			for i := uint32(0); i < n; i++ {
+				m.Tick()
+			}
+
+		case "RESET":
+			m.Reset()
+			last = nil
+
+		default:
+			return fmt.Errorf("unexpected message %s", msg.Name)
+		}
+
+		out := m.Outputs()
+		if err := c.Send(append(changes(last, out), Message{Name: "DONE"})...); err != nil {
+			return err
+		}
+		last = &out
+	}
+}
+
+// changes returns the messages which update the outputs from last to out,
+// or all of them if last is nil
+func changes(last *Outputs, out Outputs) []Message {
+	msgs := []Message{}
+
+	if last == nil || last.LEDR != out.LEDR {
+		msgs = append(msgs, Hex("LEDR", out.LEDR))
+	}
+	if last == nil || last.LEDG != out.LEDG {
+		msgs = append(msgs, Hex("LEDG", out.LEDG))
+	}
+
+	for i, v := range out.HEX {
+		if last == nil || i >= len(last.HEX) || last.HEX[i] != v {
+			msgs = append(msgs, Hex(fmt.Sprintf("HEX%d", i), uint32(v)))
+		}
+	}
+
+	return msgs
+}
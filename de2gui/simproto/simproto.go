@@ -0,0 +1,210 @@
+// Package simproto implements the protocol spoken between de2gui and a
+// simulator running in another process, see de2gui.UIState.ConnectSimulator().
+// Keeping the simulator out of the GUI's process means that a crash in the
+// design's model does not take the GUI down with it, and lets the simulator
+// be written in any language.
+//
+// The protocol is line based. Each message is a line of the form NAME or
+// NAME=VALUE, where numeric values are written in hexadecimal without a
+// prefix, as accepted by $fscanf("%h"). Blank lines, and lines starting with
+// #, are ignored. de2gui sends:
+//
+//	HELLO=1    the protocol version, once, when the simulator connects
+//	SW=3ffff   the state of the switches
+//	KEY=1      the state of the KEYs, with each bit set while it is pressed
+//	TICK=a     run the design for the given number of clock cycles
+//	RESET      reset the design
+//
+// The simulator acknowledges each message with DONE, after sending any of
+// the following which changed as a result:
+//
+//	LEDR=155   the red LEDs
+//	LEDG=3     the green LEDs
+//	HEX0=40    the segments of a HEX display, active-low, segment 0 in bit 0
+//	LCD0=text  a line of the LCD
+//
+// The simulator may also send outputs, and LOG=text to print a message, at
+// any time. de2gui does not wait for DONE after every message: for example,
+// a group of ticks is sent as a single TICK, and de2gui only waits for the
+// simulator to catch up before it shows the outputs.
+//
+// The protocol is spoken either over a Unix or TCP socket, see Listen() and
+// Dial(), or over the standard input and output of a simulator started by
+// de2gui, see de2gui.UIState.StartSimulator(). In the latter case, the
+// simulator should flush its output after each DONE, and anything it prints
+// for the user should go to standard error, or be sent with LOG.
+//
+// Serve() implements the simulator's side of the protocol for models written
+// in Go.
+package simproto
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Version is the version of the protocol, sent in HELLO.
+const Version = 1
+
+// Message is a single line of the protocol.
+type Message struct {
+	Name string
+
+	// Value is the text after the '=', or "" if there is none.
+	Value string
+}
+
+// Hex returns a message with a numeric value.
+func Hex(name string, v uint32) Message {
+	return Message{Name: name, Value: strconv.FormatUint(uint64(v), 16)}
+}
+
+// Uint returns the numeric value of the message.
+func (m Message) Uint() (uint32, error) {
+	v, err := strconv.ParseUint(m.Value, 16, 32)
+	if err != nil {
+		return 0, fmt.Errorf("%s: invalid value '%s'", m.Name, m.Value)
+	}
+	return uint32(v), nil
+}
+
+// String returns the message as it is sent, without the newline.
+func (m Message) String() string {
+	if m.Value == "" {
+		return m.Name
+	}
+	return m.Name + "=" + m.Value
+}
+
+// Parse parses a line of the protocol. It returns a message with an empty
+// name for lines which are ignored.
+func Parse(line string) (Message, error) {
+	line = strings.TrimSpace(line)
+	if line == "" || strings.HasPrefix(line, "#") {
+		return Message{}, nil
+	}
+
+	m := Message{Name: line}
+	if i := strings.IndexByte(line, '='); i >= 0 {
+		m.Name, m.Value = line[:i], line[i+1:]
+	}
+
+	if m.Name == "" {
+		return Message{}, fmt.Errorf("missing message name in '%s'", line)
+	}
+	m.Name = strings.ToUpper(m.Name)
+
+	return m, nil
+}
+
+// HEXIndex returns the index of the display named by a HEXn message, and
+// false if the message is not one.
+func (m Message) HEXIndex() (int, bool) {
+	return index(m.Name, "HEX")
+}
+
+// LCDIndex returns the line named by an LCDn message, and false if the
+// message is not one.
+func (m Message) LCDIndex() (int, bool) {
+	return index(m.Name, "LCD")
+}
+
+// index parses names such as HEX3
+func index(name, prefix string) (int, bool) {
+	if !strings.HasPrefix(name, prefix) || len(name) == len(prefix) {
+		return 0, false
+	}
+
+	i, err := strconv.Atoi(name[len(prefix):])
+	return i, err == nil && i >= 0
+}
+
+// Conn sends and receives messages over a stream, such as a socket. Send
+// may be called concurrently with Receive.
+type Conn struct {
+	r *bufio.Reader
+
+	mutex sync.Mutex
+	w     *bufio.Writer
+}
+
+// NewConn returns a Conn using rw.
+func NewConn(rw io.ReadWriter) *Conn {
+	return &Conn{r: bufio.NewReader(rw), w: bufio.NewWriter(rw)}
+}
+
+// Send writes messages.
+func (c *Conn) Send(msgs ...Message) error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	for _, m := range msgs {
+		if _, err := c.w.WriteString(m.String() + "\n"); err != nil {
+			return err
+		}
+	}
+	return c.w.Flush()
+}
+
+// Receive reads the next message, skipping lines which are ignored. It
+// returns io.EOF when the stream ends.
+func (c *Conn) Receive() (Message, error) {
+	for {
+		line, err := c.r.ReadString('\n')
+		if err != nil && (err != io.EOF || line == "") {
+			return Message{}, err
+		}
+
+		m, perr := Parse(line)
+		if perr != nil {
+			return Message{}, perr
+		}
+		if m.Name != "" {
+			return m, nil
+		}
+		if err != nil {
+			return Message{}, err
+		}
+	}
+}
+
+// network splits an address of the form unix:/path/to/socket or
+// tcp:host:port into a network and address for the net package. An address
+// without a network, such as localhost:6110, is a TCP address.
+func network(address string) (string, string) {
+	if strings.HasPrefix(address, "unix:") {
+		return "unix", address[len("unix:"):]
+	}
+	return "tcp", strings.TrimPrefix(address, "tcp:")
+}
+
+// Listen listens for simulators on an address, as described for Dial(). A
+// stale Unix socket left by a previous run is removed.
+func Listen(address string) (net.Listener, error) {
+	netw, addr := network(address)
+
+	if netw == "unix" {
+		if fi, err := os.Stat(addr); err == nil && fi.Mode()&os.ModeSocket != 0 {
+			if c, err := net.Dial(netw, addr); err == nil {
+				c.Close()
+				return nil, fmt.Errorf("%s is already in use", addr)
+			}
+			os.Remove(addr)
+		}
+	}
+
+	return net.Listen(netw, addr)
+}
+
+// Dial connects to de2gui at an address of the form unix:/path/to/socket,
+// tcp:host:port, or host:port.
+func Dial(address string) (net.Conn, error) {
+	netw, addr := network(address)
+	return net.Dial(netw, addr)
+}
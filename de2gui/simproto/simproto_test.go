@@ -0,0 +1,239 @@
+package simproto
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		line string
+		want Message
+		err  bool
+	}{
+		{"DONE\n", Message{Name: "DONE"}, false},
+		{"  ledr=1f \r\n", Message{Name: "LEDR", Value: "1f"}, false},
+		{"LCD0=a = b", Message{Name: "LCD0", Value: "a = b"}, false},
+		{"LOG=", Message{Name: "LOG"}, false},
+		{"", Message{}, false},
+		{"# a comment", Message{}, false},
+		{"=5", Message{}, true},
+	}
+
+	for _, test := range tests {
+		m, err := Parse(test.line)
+		switch {
+		case test.err && err == nil:
+			t.Errorf("Parse(%q) = %+v, expected an error", test.line, m)
+		case !test.err && err != nil:
+			t.Errorf("Parse(%q) returned error %v", test.line, err)
+		case !test.err && m != test.want:
+			t.Errorf("Parse(%q) = %+v, expected %+v", test.line, m, test.want)
+		}
+	}
+}
+
+func TestMessage(t *testing.T) {
+	m := Hex("LEDR", 0x3ffff)
+	if m.String() != "LEDR=3ffff" {
+		t.Errorf("Hex() = %q, expected LEDR=3ffff", m)
+	}
+	if v, err := m.Uint(); err != nil || v != 0x3ffff {
+		t.Errorf("Uint() = %#x, %v", v, err)
+	}
+
+	if (Message{Name: "RESET"}).String() != "RESET" {
+		t.Errorf("a message without a value is written with an '='")
+	}
+
+	for _, value := range []string{"", "g", "-1", "100000000"} {
+		if _, err := (Message{Name: "SW", Value: value}).Uint(); err == nil {
+			t.Errorf("Uint(%q) succeeded, expected an error", value)
+		}
+	}
+}
+
+func TestIndex(t *testing.T) {
+	tests := []struct {
+		name string
+		hex  int
+		ok   bool
+	}{
+		{"HEX0", 0, true},
+		{"HEX7", 7, true},
+		{"HEX12", 12, true},
+		{"HEX", 0, false},
+		{"HEXA", 0, false},
+		{"HEX-1", 0, false},
+		{"LEDR", 0, false},
+	}
+
+	for _, test := range tests {
+		i, ok := Message{Name: test.name}.HEXIndex()
+		if ok != test.ok || ok && i != test.hex {
+			t.Errorf("HEXIndex(%s) = %d, %v, expected %d, %v", test.name, i, ok, test.hex, test.ok)
+		}
+	}
+
+	if i, ok := (Message{Name: "LCD1"}).LCDIndex(); i != 1 || !ok {
+		t.Errorf("LCDIndex(LCD1) = %d, %v", i, ok)
+	}
+	if _, ok := (Message{Name: "HEX1"}).LCDIndex(); ok {
+		t.Errorf("LCDIndex(HEX1) succeeded")
+	}
+}
+
+// stream joins a reader and a writer
+type stream struct {
+	io.Reader
+	io.Writer
+}
+
+func TestConn(t *testing.T) {
+	var out bytes.Buffer
+	c := NewConn(stream{strings.NewReader("# hello\n\nLEDR=1\r\nDONE"), &out})
+
+	for _, want := range []Message{{Name: "LEDR", Value: "1"}, {Name: "DONE"}} {
+		m, err := c.Receive()
+		if err != nil || m != want {
+			t.Errorf("Receive() = %+v, %v, expected %+v", m, err, want)
+		}
+	}
+	if m, err := c.Receive(); err != io.EOF {
+		t.Errorf("Receive() = %+v, %v at the end of the stream, expected io.EOF", m, err)
+	}
+
+	if err := c.Send(Hex("SW", 5), Message{Name: "TICK", Value: "a"}); err != nil {
+		t.Fatal(err)
+	}
+	if out.String() != "SW=5\nTICK=a\n" {
+		t.Errorf("sent %q", out.String())
+	}
+}
+
+// counter is a model which counts ticks, showing the count on LEDR and
+// HEX0, and the switches and KEYs on LEDG
+type counter struct {
+	sw, key, count uint32
+}
+
+func (m *counter) SetInputs(sw, key uint32) { m.sw, m.key = sw, key }
+func (m *counter) Tick()                    { m.count++ }
+func (m *counter) Reset()                   { m.count = 0 }
+
+func (m *counter) Outputs() Outputs {
+	return Outputs{LEDR: m.count, LEDG: m.sw | m.key<<4, HEX: []uint8{uint8(m.count & 0x7f), 0x7f}}
+}
+
+// serve runs Serve with the given input, returning its output and error
+func serve(input string) (string, error) {
+	var out bytes.Buffer
+	err := Serve(stream{strings.NewReader(input), &out}, &counter{})
+	return out.String(), err
+}
+
+func TestServe(t *testing.T) {
+	out, err := serve("HELLO=1\nSW=3\nTICK=2\nKEY=1\nTICK=0\nRESET\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// every output is sent after HELLO and RESET, and otherwise only
+	// those which changed, so TICK=0 sends none
+	want := []string{
+		"LEDR=0", "LEDG=0", "HEX0=0", "HEX1=7f", "DONE",
+		"LEDG=3", "DONE",
+		"LEDR=2", "HEX0=2", "DONE",
+		"LEDG=13", "DONE",
+		"DONE",
+		"LEDR=0", "LEDG=13", "HEX0=0", "HEX1=7f", "DONE",
+	}
+	if got := strings.Split(strings.TrimSuffix(out, "\n"), "\n"); !reflect.DeepEqual(got, want) {
+		t.Errorf("sent\n%s\nexpected\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+func TestServeErrors(t *testing.T) {
+	tests := []struct {
+		input string
+		err   string
+	}{
+		{"HELLO=2\n", "unsupported protocol version 2"},
+		{"SW=x\n", "SW: invalid value 'x'"},
+		{"TICK\n", "TICK: invalid value ''"},
+		{"LEDR=1\n", "unexpected message LEDR"},
+	}
+
+	for _, test := range tests {
+		if _, err := serve(test.input); err == nil || err.Error() != test.err {
+			t.Errorf("%q: returned error %v, expected %q", test.input, err, test.err)
+		}
+	}
+}
+
+func TestListenUnix(t *testing.T) {
+	dir, err := ioutil.TempDir("", "simproto")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	address := "unix:" + filepath.Join(dir, "sock")
+	l, err := Listen(address)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := Listen(address); err == nil || !strings.Contains(err.Error(), "already in use") {
+		t.Errorf("listening twice returned error %v, expected it to be in use", err)
+	}
+
+	accepted := make(chan net.Conn, 1)
+	go func() {
+		c, _ := l.Accept()
+		accepted <- c
+	}()
+
+	c, err := Dial(address)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.Close()
+	if a := <-accepted; a != nil {
+		a.Close()
+	}
+
+	// a socket left behind by a listener which went away is replaced,
+	// which requires the file to outlive the listener
+	l.(*net.UnixListener).SetUnlinkOnClose(false)
+	l.Close()
+
+	l, err = Listen(address)
+	if err != nil {
+		t.Fatalf("listening on a stale socket returned error %v", err)
+	}
+	l.Close()
+}
+
+func TestNetwork(t *testing.T) {
+	tests := []struct{ address, netw, addr string }{
+		{"unix:/tmp/de2gui.sock", "unix", "/tmp/de2gui.sock"},
+		{"tcp:localhost:6110", "tcp", "localhost:6110"},
+		{"localhost:6110", "tcp", "localhost:6110"},
+		{":6110", "tcp", ":6110"},
+	}
+
+	for _, test := range tests {
+		netw, addr := network(test.address)
+		if netw != test.netw || addr != test.addr {
+			t.Errorf("network(%q) = %q, %q, expected %q, %q", test.address, netw, addr, test.netw, test.addr)
+		}
+	}
+}
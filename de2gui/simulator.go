@@ -0,0 +1,508 @@
+package de2gui
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"os/exec"
+	"sync"
+	"time"
+
+	"github.com/herclab/de2gui/de2gui/simproto"
+)
+
+// SimulatorTimeout is how long to wait for a simulator to catch up before
+// showing its outputs, see ConnectSimulator(). Since the last tick of each
+// group waits while the tick controls are locked, a simulator which stops
+// responding freezes them for up to this long per group of ticks.
+var SimulatorTimeout = 10 * time.Second
+
+// Simulator is a connection to a simulator running in another process, see
+// ConnectSimulator().
+type Simulator struct {
+	s    *UIState
+	conn *simproto.Conn
+	rwc  io.ReadWriteCloser
+
+	// cmd is the simulator's process, if it was started by
+	// StartSimulator(), and exited is closed once it has exited, with
+	// the result in exitErr
+	cmd     *exec.Cmd
+	exited  chan struct{}
+	exitErr error
+
+	// mutex guards the fields below
+	mutex sync.Mutex
+
+	// pending is the number of ticks not yet sent, which are sent as a
+	// single TICK message
+	pending uint64
+
+	// outstanding is the number of messages the simulator has not yet
+	// acknowledged with DONE
+	outstanding int
+
+	// err is the reason the connection was closed
+	err error
+
+	// ack is signalled when a DONE is received
+	ack chan struct{}
+
+	// closed is closed when the connection is closed
+	closed chan struct{}
+
+	// outputs are the outputs received from the simulator which have not
+	// yet been shown, see apply()
+	outputs []simproto.Message
+
+	// applyMutex is held while outputs are shown, so that they are shown
+	// in the order they were received
+	applyMutex sync.Mutex
+
+	// received is signalled when outputs are received
+	received chan struct{}
+}
+
+// ConnectSimulator connects the board to a simulator running in another
+// process, which speaks the protocol described in the simproto package over
+// rwc. It replaces OnSW, OnKEY, OnTick and OnReset, so that input changes,
+// ticks and resets are sent to the simulator, and the outputs it sends back
+// are shown.
+//
+// Ticks are sent in groups, and at the end of each group, and after each
+// input change or reset, the board waits for the simulator to catch up, for
+// at most SimulatorTimeout, so that the outputs shown are those at the
+// current tick. The tick controls are locked while waiting at the end of a
+// group, so a simulator which hangs makes them unresponsive until the
+// timeout. Outputs the simulator sends at other times are shown once no
+// ticks are running.
+//
+// If the simulator disconnects or crashes, the board keeps running, without
+// a design. Once Wait() returns, ConnectSimulator() may be called again with
+// a new connection.
+func (s *UIState) ConnectSimulator(rwc io.ReadWriteCloser) (*Simulator, error) {
+	return s.connectSimulator(rwc, nil)
+}
+
+// StartSimulator starts cmd, which must not have been started, and connects
+// the board to it as for ConnectSimulator(), speaking the protocol over the
+// process's standard input and output. This suits simulators which can only
+// use $fscanf and $fwrite, such as Icarus Verilog. If cmd.Stderr is nil, the
+// simulator's standard error is passed through to os.Stderr.
+//
+// Closing the Simulator closes the process's standard input, and kills it
+// if it does not exit within a second.
+func (s *UIState) StartSimulator(cmd *exec.Cmd) (*Simulator, error) {
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+
+	if cmd.Stderr == nil {
+		cmd.Stderr = os.Stderr
+	}
+
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+
+	return s.connectSimulator(&pipeConn{Reader: stdout, WriteCloser: stdin, stdout: stdout}, cmd)
+}
+
+// pipeConn joins the standard output and input of a process into a single
+// stream
+type pipeConn struct {
+	io.Reader
+	io.WriteCloser
+	stdout io.Closer
+}
+
+// Close implements io.Closer
+func (p *pipeConn) Close() error {
+	err := p.WriteCloser.Close()
+	p.stdout.Close()
+	return err
+}
+
+// Internal function which implements ConnectSimulator() and
+// StartSimulator(). cmd is nil if the simulator is not a child process.
+func (s *UIState) connectSimulator(rwc io.ReadWriteCloser, cmd *exec.Cmd) (*Simulator, error) {
+	sim := &Simulator{
+		s:      s,
+		conn:   simproto.NewConn(rwc),
+		rwc:    rwc,
+		cmd:    cmd,
+		exited: make(chan struct{}),
+		ack:    make(chan struct{}, 1),
+		closed: make(chan struct{}),
+
+		received: make(chan struct{}, 1),
+	}
+
+	go sim.receive()
+	go sim.applyReceived()
+
+	// the process is only waited for once its output has been read, since
+	// waiting closes the pipe
+	if cmd != nil {
+		go func() {
+			<-sim.closed
+			kill := time.AfterFunc(time.Second, func() {
+				cmd.Process.Kill()
+			})
+			sim.exitErr = cmd.Wait()
+			kill.Stop()
+			close(sim.exited)
+		}()
+	} else {
+		close(sim.exited)
+	}
+
+	if err := sim.send(simproto.Hex("HELLO", simproto.Version)); err != nil {
+		sim.Close()
+		return nil, err
+	}
+
+	// the design starts with the board's current inputs
+	if err := sim.send(simproto.Hex("SW", s.SW()), simproto.Hex("KEY", s.KEY())); err != nil {
+		sim.Close()
+		return nil, err
+	}
+
+	if err := sim.sync(); err != nil {
+		sim.Close()
+		return nil, err
+	}
+
+	s.OnSW = func(s *UIState) {
+		sim.input("SW", s.SW())
+	}
+
+	s.OnKEY = func(s *UIState) {
+		sim.input("KEY", s.KEY())
+	}
+
+	s.OnTick = func(s *UIState, final bool) {
+		s.Tick++
+
+		sim.mutex.Lock()
+		sim.pending++
+		sim.mutex.Unlock()
+
+		if final {
+			sim.flush()
+			sim.sync()
+		}
+	}
+
+	s.OnReset = func(s *UIState) {
+		s.Tick = 0
+		s.ClearFutures()
+
+		sim.mutex.Lock()
+		sim.pending = 0
+		sim.mutex.Unlock()
+
+		sim.send(simproto.Message{Name: "RESET"})
+		sim.sync()
+	}
+
+	return sim, nil
+}
+
+// ServeSimulators accepts connections from simulators on l, which is usually
+// created with simproto.Listen(), and connects them to the board one at a
+// time, see ConnectSimulator(). Connections made while a simulator is
+// connected wait for it to disconnect. It returns when l is closed.
+func (s *UIState) ServeSimulators(l net.Listener) error {
+	for {
+		c, err := l.Accept()
+		if err != nil {
+			return err
+		}
+
+		sim, err := s.ConnectSimulator(c)
+		if err != nil {
+			s.logSimulator(LevelWarning, "simulator %s: %v\n", c.RemoteAddr(), err)
+			continue
+		}
+
+		s.logSimulator(LevelInfo, "simulator connected\n")
+		if err := sim.Wait(); err != nil {
+			s.logSimulator(LevelWarning, "simulator disconnected: %v\n", err)
+		} else {
+			s.logSimulator(LevelInfo, "simulator disconnected\n")
+		}
+	}
+}
+
+// Internal function which writes a message to SimulatorLog, or adds it to
+// the log if SimulatorLog is nil
+func (s *UIState) logSimulator(level Level, format string, args ...interface{}) {
+	if s.SimulatorLog == nil {
+		s.Logf(level, format, args...)
+		return
+	}
+	fmt.Fprintf(s.SimulatorLog, format, args...)
+}
+
+// Close disconnects from the simulator.
+func (sim *Simulator) Close() error {
+	sim.fail(nil)
+	return nil
+}
+
+// Wait waits until the simulator disconnects, and returns the reason, or nil
+// if it disconnected cleanly. For a simulator started by StartSimulator(),
+// it also waits for the process to exit, and returns an error if it did not
+// exit successfully.
+func (sim *Simulator) Wait() error {
+	<-sim.closed
+	<-sim.exited
+
+	if sim.exitErr != nil {
+		return fmt.Errorf("simulator exited: %v", sim.exitErr)
+	}
+
+	sim.mutex.Lock()
+	defer sim.mutex.Unlock()
+	return sim.err
+}
+
+// Internal function which closes the connection, recording the reason. Only
+// the first reason is kept.
+func (sim *Simulator) fail(err error) {
+	sim.mutex.Lock()
+	defer sim.mutex.Unlock()
+
+	select {
+	case <-sim.closed:
+		return
+	default:
+	}
+
+	sim.err = err
+	sim.rwc.Close()
+	close(sim.closed)
+}
+
+// Internal function which sends messages that the simulator acknowledges
+func (sim *Simulator) send(msgs ...simproto.Message) error {
+	select {
+	case <-sim.closed:
+		return io.ErrClosedPipe
+	default:
+	}
+
+	sim.mutex.Lock()
+	sim.outstanding += len(msgs)
+	sim.mutex.Unlock()
+
+	if err := sim.conn.Send(msgs...); err != nil {
+		sim.fail(err)
+		return err
+	}
+	return nil
+}
+
+// Internal function which sends a change of input, after any ticks which
+// came before it, and waits for the simulator to catch up
+func (sim *Simulator) input(name string, v uint32) {
+	sim.flush()
+	sim.send(simproto.Hex(name, v))
+	sim.sync()
+}
+
+// Internal function which sends any pending ticks
+func (sim *Simulator) flush() {
+	sim.mutex.Lock()
+	n := sim.pending
+	sim.pending = 0
+	sim.mutex.Unlock()
+
+	// TICK carries a 32 bit count
+	for n > 0 {
+		batch := n
+		if batch > 0xffffffff {
+			batch = 0xffffffff
+		}
+		n -= batch
+
+		if sim.send(simproto.Hex("TICK", uint32(batch))) != nil {
+			return
+		}
+	}
+}
+
+// Internal function which waits until the simulator has acknowledged every
+// message sent to it, or SimulatorTimeout passes, and then shows its
+// outputs. It is called from callbacks, so that the outputs are shown by
+// the goroutine running the simulation, which holds the tick mutex during
+// ticks.
+func (sim *Simulator) sync() error {
+	defer sim.apply()
+
+	timeout := time.After(SimulatorTimeout)
+
+	for {
+		sim.mutex.Lock()
+		n, err := sim.outstanding, sim.err
+		sim.mutex.Unlock()
+
+		select {
+		case <-sim.closed:
+			if err == nil {
+				err = io.ErrClosedPipe
+			}
+			return err
+		default:
+		}
+
+		if n <= 0 {
+			return nil
+		}
+
+		select {
+		case <-sim.ack:
+		case <-sim.closed:
+		case <-timeout:
+			err := fmt.Errorf("simulator did not respond within %v", SimulatorTimeout)
+			sim.s.logSimulator(LevelError, "%v\n", err)
+			return err
+		}
+	}
+}
+
+// Internal function which reads messages from the simulator until the
+// connection is closed
+func (sim *Simulator) receive() {
+	for {
+		m, err := sim.conn.Receive()
+		if err == io.EOF {
+			sim.fail(nil)
+			return
+		}
+		if err != nil {
+			sim.fail(err)
+			return
+		}
+
+		if err := sim.handle(m); err != nil {
+			sim.fail(err)
+			return
+		}
+	}
+}
+
+// Internal function which shows the outputs received from the simulator.
+// Outputs are not shown by the goroutine which receives them, since it
+// would race with the goroutine running the simulation, and it cannot wait
+// for the tick mutex, which is held while waiting for the simulator.
+func (sim *Simulator) apply() error {
+	sim.applyMutex.Lock()
+	defer sim.applyMutex.Unlock()
+
+	sim.mutex.Lock()
+	outputs := sim.outputs
+	sim.outputs = nil
+	sim.mutex.Unlock()
+
+	for _, m := range outputs {
+		if err := sim.show(m); err != nil {
+			sim.fail(err)
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Internal function which shows outputs which the simulator sends between
+// ticks and input changes, while holding the tick mutex, until the
+// connection is closed. Outputs sent in response to a tick or input change
+// are usually shown by sync() first.
+func (sim *Simulator) applyReceived() {
+	for {
+		select {
+		case <-sim.received:
+		case <-sim.closed:
+			return
+		}
+
+		sim.s.tickMutex.Lock()
+		sim.apply()
+		sim.s.tickMutex.Unlock()
+	}
+}
+
+// Internal function which handles a message from the simulator. Outputs
+// are queued to be shown by apply(), which closes the connection if they
+// are not valid.
+func (sim *Simulator) handle(m simproto.Message) error {
+	s := sim.s
+
+	if m.Name == "DONE" {
+		sim.mutex.Lock()
+		sim.outstanding--
+		sim.mutex.Unlock()
+
+		select {
+		case sim.ack <- struct{}{}:
+		default:
+		}
+		return nil
+	}
+
+	if m.Name == "LOG" {
+		s.logSimulator(LevelInfo, "%s\n", m.Value)
+		return nil
+	}
+
+	sim.mutex.Lock()
+	sim.outputs = append(sim.outputs, m)
+	sim.mutex.Unlock()
+
+	select {
+	case sim.received <- struct{}{}:
+	default:
+	}
+
+	return nil
+}
+
+// Internal function which shows an output received from the simulator
+func (sim *Simulator) show(m simproto.Message) error {
+	s := sim.s
+
+	if i, ok := m.LCDIndex(); ok {
+		s.SetLCD(i, m.Value)
+		return nil
+	}
+
+	v, err := m.Uint()
+	if err != nil {
+		return err
+	}
+
+	if i, ok := m.HEXIndex(); ok {
+		s.SetHEX(i, uint8(v))
+		return nil
+	}
+
+	switch m.Name {
+	case "LEDR":
+		s.SetLEDR(v)
+	case "LEDG":
+		s.SetLEDG(v)
+	default:
+		return fmt.Errorf("unexpected message %s from simulator", m.Name)
+	}
+
+	return nil
+}
@@ -0,0 +1,215 @@
+package de2gui
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/herclab/de2gui/de2gui/simproto"
+)
+
+// counterModel is a design which counts ticks while SW0 is on, showing the
+// count on HEX0 and the switches on LEDR
+type counterModel struct {
+	sw, count uint32
+}
+
+func (m *counterModel) SetInputs(sw, key uint32) { m.sw = sw }
+func (m *counterModel) Reset()                   { m.count = 0 }
+
+func (m *counterModel) Tick() {
+	if m.sw&1 != 0 {
+		m.count++
+	}
+}
+
+func (m *counterModel) Outputs() simproto.Outputs {
+	return simproto.Outputs{LEDR: m.sw, HEX: []uint8{HexSegments(uint8(m.count))}}
+}
+
+func TestSimulator(t *testing.T) {
+	s := NewHeadlessUIState()
+	s.StartRecording()
+
+	c1, c2 := net.Pipe()
+	served := make(chan error, 1)
+	go func() { served <- simproto.Serve(c2, &counterModel{}) }()
+
+	sim, err := s.ConnectSimulator(c1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s.SetSW(1)
+	s.RunTicks(5)
+	if s.LEDR() != 1 {
+		t.Errorf("LEDR() = %d, expected 1", s.LEDR())
+	}
+	if s.HEX(0) != HexSegments(5) {
+		t.Errorf("HEX(0) = %#x, expected %#x", s.HEX(0), HexSegments(5))
+	}
+
+	s.reset()
+	if s.HEX(0) != HexSegments(0) {
+		t.Errorf("HEX(0) = %#x after reset, expected %#x", s.HEX(0), HexSegments(0))
+	}
+
+	sim.Close()
+	if err := sim.Wait(); err != nil {
+		t.Errorf("Wait() = %v", err)
+	}
+	if err := <-served; err != nil {
+		t.Errorf("Serve() = %v", err)
+	}
+}
+
+// TestSimulatorUnsolicitedOutputs checks that outputs sent between ticks
+// are shown, while the board is ticked and recorded. Run with -race.
+func TestSimulatorUnsolicitedOutputs(t *testing.T) {
+	s := NewHeadlessUIState()
+	s.StartRecording()
+
+	c1, c2 := net.Pipe()
+	go func() {
+		c := simproto.NewConn(c2)
+		for n := uint32(1); ; n++ {
+			if _, err := c.Receive(); err != nil {
+				return
+			}
+
+			// LEDG is sent after DONE, so it is not part of the response
+			if c.Send(simproto.Message{Name: "DONE"}, simproto.Hex("LEDG", n)) != nil {
+				return
+			}
+		}
+	}()
+
+	sim, err := s.ConnectSimulator(c1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 100; i++ {
+		s.SetSW(uint32(i))
+		s.RunTicks(3)
+	}
+
+	sim.Close()
+	sim.Wait()
+
+	if s.LEDG() == 0 {
+		t.Errorf("LEDG was never set by the simulator")
+	}
+	if n := len(s.StopRecording().Events); n < 200 {
+		t.Errorf("%d events recorded, expected at least 200", n)
+	}
+}
+
+// buildSim builds the stand-in simulator, cmd/de2gui-sim, returning the
+// path of the binary and a function which removes it. The test is skipped
+// if the go command is not available.
+func buildSim(t *testing.T) (string, func()) {
+	t.Helper()
+
+	if testing.Short() {
+		t.Skip("building de2gui-sim is slow")
+	}
+	goCmd, err := exec.LookPath("go")
+	if err != nil {
+		t.Skip("the go command is needed to build de2gui-sim")
+	}
+
+	dir, err := ioutil.TempDir("", "de2gui-sim")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	path := filepath.Join(dir, "de2gui-sim")
+	out, err := exec.Command(goCmd, "build", "-o", path, "github.com/herclab/de2gui/cmd/de2gui-sim").CombinedOutput()
+	if err != nil {
+		os.RemoveAll(dir)
+		t.Fatalf("building de2gui-sim: %v\n%s", err, out)
+	}
+
+	return path, func() { os.RemoveAll(dir) }
+}
+
+func TestStartSimulator(t *testing.T) {
+	path, cleanup := buildSim(t)
+	defer cleanup()
+
+	s := NewHeadlessUIState()
+	sim, err := s.StartSimulator(exec.Command(path))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the counter runs while SW0 is on, and is shown in hexadecimal
+	s.SetSW(1)
+	s.RunTicks(0x12)
+	if s.LEDR() != 1 {
+		t.Errorf("LEDR() = %d, expected the switches", s.LEDR())
+	}
+	if s.HEX(1) != HexSegments(1) || s.HEX(0) != HexSegments(2) || s.HEX(2) != HexSegments(0) {
+		t.Errorf("HEX2-0 show %s, expected 012", s.hexChars(3))
+	}
+
+	// KEY0 clears it
+	s.HoldKEY(0)
+	s.RunTicks(3)
+	if s.LEDG() != 1 || s.hexChars(2) != "00" {
+		t.Errorf("LEDG() = %d and HEX1-0 show %s with KEY0 held, expected 1 and 00", s.LEDG(), s.hexChars(2))
+	}
+
+	s.ReleaseKEY(0)
+	s.RunTicks(5)
+	s.ExecLine("reset")
+	if s.hexChars(1) != "0" {
+		t.Errorf("HEX0 shows %s after reset, expected 0", s.hexChars(1))
+	}
+
+	sim.Close()
+	if err := sim.Wait(); err != nil {
+		t.Errorf("Wait() = %v, expected the simulator to exit cleanly", err)
+	}
+}
+
+func TestStartSimulatorCrash(t *testing.T) {
+	path, cleanup := buildSim(t)
+	defer cleanup()
+
+	s := NewHeadlessUIState()
+	s.SimulatorLog = ioutil.Discard
+
+	var stderr bytes.Buffer
+	cmd := exec.Command(path, "-crash-after", "3")
+	cmd.Stderr = &stderr
+
+	sim, err := s.StartSimulator(cmd)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s.SetSW(1)
+	s.RunTicks(5)
+
+	err = sim.Wait()
+	if err == nil || !strings.HasPrefix(err.Error(), "simulator exited: ") {
+		t.Errorf("Wait() = %v, expected the simulator to exit with an error", err)
+	}
+	if !strings.Contains(stderr.String(), "counter reached 3, crashing") {
+		t.Errorf("the simulator's standard error is %q", stderr.String())
+	}
+
+	// the board keeps running without a design, showing the outputs from
+	// before the group of ticks in which the simulator crashed
+	s.RunTicks(5)
+	if s.LEDR() != 1 || s.hexChars(1) != "0" {
+		t.Errorf("LEDR() = %d and HEX0 shows %s, expected 1 and 0", s.LEDR(), s.hexChars(1))
+	}
+}
@@ -0,0 +1,137 @@
+package de2gui
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/herclab/de2gui/de2gui/widgets/lcdwidget"
+)
+
+// Snapshot is the state of the board, and optionally of the design, at a
+// point in the simulation, see SaveSnapshot().
+type Snapshot struct {
+	Tick uint64
+
+	// SW and KEY are the positions of the switches and KEYs, without any
+	// bounce or faults.
+	SW  uint32
+	KEY uint32
+
+	LEDR uint32
+	LEDG uint32
+	HEX  []uint8
+	LCD  [lcdwidget.Rows]string
+
+	// Design is the state of the design returned by OnSnapshot, or nil.
+	Design interface{}
+}
+
+// SaveSnapshot saves the state of the board under the given name, replacing
+// any snapshot with the same name, and returns it. If OnSnapshot is set, the
+// state of the design it returns is saved too.
+func (s *UIState) SaveSnapshot(name string) *Snapshot {
+	s.tickMutex.Lock()
+	defer s.tickMutex.Unlock()
+
+	snap := &Snapshot{
+		Tick: s.Tick,
+		SW:   s.sw,
+		KEY:  s.key,
+		LEDR: s.ledr,
+		LEDG: s.ledg,
+		HEX:  append([]uint8{}, s.hex...),
+		LCD:  s.lcd,
+	}
+
+	if s.OnSnapshot != nil {
+		s.call("OnSnapshot", func() { snap.Design = s.OnSnapshot(s) })
+	}
+
+	if s.snapshots == nil {
+		s.snapshots = make(map[string]*Snapshot)
+	}
+	s.snapshots[name] = snap
+
+	return snap
+}
+
+// LoadSnapshot restores the board to the state saved by SaveSnapshot() under
+// the given name, including the Tick field. Futures are cleared, since they
+// belong to the abandoned part of the simulation, and so any pending KEY
+// releases are lost.
+//
+// If the snapshot contains the state of the design, it is passed to
+// OnRestore, and otherwise only the board is restored, and a warning is
+// logged. OnSW and OnKEY are then run, so that the design sees the restored
+// inputs. Since the recorded session cannot go back in time, recording is
+// stopped.
+func (s *UIState) LoadSnapshot(name string) error {
+	snap, ok := s.snapshots[name]
+	if !ok {
+		return fmt.Errorf("there is no snapshot named '%s'", name)
+	}
+
+	s.tickMutex.Lock()
+
+	s.recordMutex.Lock()
+	recording := s.recording
+	s.recording = false
+	s.recordMutex.Unlock()
+	if recording {
+		s.Logf(LevelWarning, "recording stopped, since snapshot '%s' was loaded", name)
+	}
+
+	s.ClearFutures()
+	s.Tick = snap.Tick
+	s.publishTick()
+
+	s.sw = snap.SW
+	s.key = snap.KEY
+	if !s.headless {
+		s.switchWidget.Update(s.sw)
+		s.switchLabel.SetText("(" + formatBits(s.sw, s.profile.SW) + ")")
+		s.cycleLabel.SetText(fmt.Sprintf("cycle# %d", s.Tick))
+	}
+	s.refreshKeys()
+
+	s.ledr = snap.LEDR
+	s.ledg = snap.LEDG
+	s.showLEDR()
+	s.showLEDG()
+
+	copy(s.hex, snap.HEX)
+	for i := range s.hex {
+		s.showHEX(i)
+	}
+
+	for i, text := range snap.LCD {
+		s.SetLCD(i, text)
+	}
+
+	if snap.Design != nil && s.OnRestore != nil {
+		s.call("OnRestore", func() { s.OnRestore(s, snap.Design) })
+	} else {
+		s.Logf(LevelWarning, "snapshot '%s' restored the board, but not the design", name)
+	}
+
+	s.tickMutex.Unlock()
+
+	if s.OnSW != nil {
+		s.call("OnSW", func() { s.OnSW(s) })
+	}
+	if s.OnKEY != nil {
+		s.call("OnKEY", func() { s.OnKEY(s) })
+	}
+
+	return nil
+}
+
+// Snapshots returns the names of the saved snapshots, in order.
+func (s *UIState) Snapshots() []string {
+	names := []string{}
+	for name := range s.snapshots {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,247 @@
+package de2gui
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+)
+
+// StderrTailLines is the number of lines at the end of a supervised
+// simulator's standard error which are shown when it exits.
+var StderrTailLines = 20
+
+// Supervisor runs a simulator process connected to the board, and handles it
+// exiting or crashing, see NewSupervisor().
+type Supervisor struct {
+	s       *UIState
+	command func() *exec.Cmd
+	stderr  *tailWriter
+
+	// mutex guards the fields below
+	mutex sync.Mutex
+
+	// sim is the current connection to the simulator, or nil
+	sim *Simulator
+
+	// stopped is true once Stop() has been called
+	stopped bool
+
+	// OnExit is run when the simulator exits, other than because Stop()
+	// was called. status describes how it exited, and is nil if it exited
+	// successfully, and stderr contains the end of its standard error.
+	//
+	// If OnExit is nil, a dialog showing the status and stderr is shown,
+	// which offers to restart the simulator, or the status is logged, see
+	// SimulatorLog, if there is no GUI.
+	OnExit func(sup *Supervisor, status error, stderr string)
+}
+
+// NewSupervisor creates a Supervisor for the simulator started by the
+// commands returned by command, which is called each time the simulator is
+// started. Call Start() to start it.
+//
+// The simulator is connected as for StartSimulator(). Its standard error is
+// passed through to the command's Stderr, or to os.Stderr if it is nil, and
+// the end of it is kept, to show when the simulator exits. Since the state
+// of the board lives in the GUI, the switches, and any session being
+// recorded, are unaffected by the simulator exiting or being restarted.
+func (s *UIState) NewSupervisor(command func() *exec.Cmd) *Supervisor {
+	return &Supervisor{s: s, command: command, stderr: &tailWriter{}}
+}
+
+// Start starts the simulator. It returns an error if it could not be
+// started, or exited before it connected, in which case Stderr() may explain
+// why.
+func (sup *Supervisor) Start() error {
+	cmd := sup.command()
+
+	var w io.Writer = os.Stderr
+	if cmd.Stderr != nil {
+		w = cmd.Stderr
+	}
+	sup.stderr.reset(w)
+	cmd.Stderr = sup.stderr
+
+	sim, err := sup.s.StartSimulator(cmd)
+	if err != nil {
+		return err
+	}
+
+	sup.mutex.Lock()
+	sup.sim = sim
+	sup.stopped = false
+	sup.mutex.Unlock()
+
+	go sup.watch(sim)
+	return nil
+}
+
+// Restart stops the simulator if it is running, and starts it again. The
+// restart is recorded in any session being recorded. The board's inputs are
+// sent to the new simulator as it starts.
+func (sup *Supervisor) Restart(reason string) error {
+	sup.Stop()
+	sup.s.recordDetail("RESTART", reason)
+	return sup.Start()
+}
+
+// Stderr returns the end of the standard error of the simulator which was
+// started most recently.
+func (sup *Supervisor) Stderr() string {
+	return sup.stderr.String()
+}
+
+// Stop disconnects from the simulator, which is killed if it does not exit.
+func (sup *Supervisor) Stop() {
+	sup.mutex.Lock()
+	sim := sup.sim
+	sup.sim = nil
+	sup.stopped = true
+	sup.mutex.Unlock()
+
+	if sim != nil {
+		sim.Close()
+		sim.Wait()
+	}
+}
+
+// Internal function which waits for the simulator to exit, and reports it
+func (sup *Supervisor) watch(sim *Simulator) {
+	status := sim.Wait()
+
+	sup.mutex.Lock()
+	current := sup.sim == sim && !sup.stopped
+	if current {
+		sup.sim = nil
+	}
+	sup.mutex.Unlock()
+
+	if !current {
+		return
+	}
+
+	stderr := sup.Stderr()
+
+	switch {
+	case sup.OnExit != nil:
+		sup.OnExit(sup, status, stderr)
+
+	case sup.s.window() != nil:
+		sup.showExit(status, stderr)
+
+	case status != nil:
+		sup.s.logSimulator(LevelError, "%s\n%s", exitMessage(status), stderr)
+
+	default:
+		sup.s.logSimulator(LevelInfo, "%s\n%s", exitMessage(status), stderr)
+	}
+}
+
+// exitMessage describes the way a simulator exited
+func exitMessage(status error) string {
+	if status == nil {
+		return "The simulator exited."
+	}
+	return fmt.Sprintf("The simulator exited unexpectedly: %v", status)
+}
+
+// Internal function which shows a dialog describing how the simulator
+// exited, offering to restart it
+func (sup *Supervisor) showExit(status error, stderr string) {
+	w := sup.s.window()
+	if w == nil {
+		return
+	}
+
+	if stderr == "" {
+		stderr = "(no output)"
+	}
+
+	output := widget.NewLabelWithStyle(stderr, fyne.TextAlignLeading, fyne.TextStyle{Monospace: true})
+	scroll := container.NewScroll(output)
+	scroll.SetMinSize(fyne.NewSize(560, 240))
+
+	content := container.NewBorder(
+		widget.NewLabel(exitMessage(status)+"\nThe end of its standard error was:"),
+		nil, nil, nil, scroll,
+	)
+
+	reason := "exited successfully"
+	if status != nil {
+		reason = status.Error()
+	}
+
+	dialog.ShowCustomConfirm("Simulator exited", "Restart", "Close", content, func(restart bool) {
+		if !restart {
+			return
+		}
+
+		if err := sup.Restart(reason); err != nil {
+			sup.showExit(err, sup.Stderr())
+		}
+	}, w)
+}
+
+// tailWriter passes everything written to it through to another writer,
+// keeping the last StderrTailLines lines
+type tailWriter struct {
+	mutex sync.Mutex
+	w     io.Writer
+	lines []string
+
+	// partial is the last line, if it is not yet complete
+	partial string
+}
+
+// reset discards the kept lines, and passes writes through to w
+func (t *tailWriter) reset(w io.Writer) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	t.w = w
+	t.lines = nil
+	t.partial = ""
+}
+
+// Write implements io.Writer
+func (t *tailWriter) Write(p []byte) (int, error) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	if t.w != nil {
+		t.w.Write(p)
+	}
+
+	parts := strings.Split(t.partial+string(p), "\n")
+	t.partial = parts[len(parts)-1]
+	t.lines = append(t.lines, parts[:len(parts)-1]...)
+
+	if len(t.lines) > StderrTailLines {
+		t.lines = append([]string{}, t.lines[len(t.lines)-StderrTailLines:]...)
+	}
+
+	return len(p), nil
+}
+
+// String returns the kept lines
+func (t *tailWriter) String() string {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	lines := t.lines
+	if t.partial != "" {
+		lines = append(append([]string{}, lines...), t.partial)
+		if len(lines) > StderrTailLines {
+			lines = lines[len(lines)-StderrTailLines:]
+		}
+	}
+	return strings.Join(lines, "\n")
+}
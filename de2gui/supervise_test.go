@@ -0,0 +1,132 @@
+package de2gui
+
+import (
+	"io/ioutil"
+	"os/exec"
+	"strings"
+	"testing"
+	"time"
+)
+
+// exit is how a supervised simulator exited, as passed to OnExit
+type exit struct {
+	status error
+	stderr string
+}
+
+func TestSupervisorRestart(t *testing.T) {
+	path, cleanup := buildSim(t)
+	defer cleanup()
+
+	s := NewHeadlessUIState()
+	s.StartRecording()
+
+	sup := s.NewSupervisor(func() *exec.Cmd {
+		cmd := exec.Command(path, "-crash-after", "3")
+		cmd.Stderr = ioutil.Discard
+		return cmd
+	})
+
+	exits := make(chan exit, 1)
+	sup.OnExit = func(sup *Supervisor, status error, stderr string) {
+		exits <- exit{status, stderr}
+	}
+
+	if err := sup.Start(); err != nil {
+		t.Fatal(err)
+	}
+
+	s.SetSW(1)
+	s.RunTicks(5)
+
+	var e exit
+	select {
+	case e = <-exits:
+	case <-time.After(10 * time.Second):
+		t.Fatal("OnExit was not run after the simulator crashed")
+	}
+
+	if e.status == nil || !strings.Contains(e.stderr, "counter reached 3, crashing") {
+		t.Fatalf("OnExit got the status %v and stderr %q", e.status, e.stderr)
+	}
+
+	// the switches are sent to the new simulator, whose counter starts
+	// again
+	if err := sup.Restart(e.status.Error()); err != nil {
+		t.Fatal(err)
+	}
+	s.RunTicks(2)
+	if s.hexChars(1) != "2" {
+		t.Errorf("HEX0 shows %s after restarting, expected 2", s.hexChars(1))
+	}
+
+	// stopping the simulator is not reported as an exit
+	sup.Stop()
+	select {
+	case e := <-exits:
+		t.Errorf("OnExit got %v after Stop()", e.status)
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	restarts := 0
+	for _, ev := range s.StopRecording().Events {
+		if ev.Signal == "RESTART" {
+			restarts++
+			if ev.Detail != e.status.Error() {
+				t.Errorf("the restart was recorded as %q, expected %q", ev.Detail, e.status.Error())
+			}
+		}
+	}
+	if restarts != 1 {
+		t.Errorf("%d restarts recorded, expected 1", restarts)
+	}
+}
+
+func TestSupervisorExitLogged(t *testing.T) {
+	path, cleanup := buildSim(t)
+	defer cleanup()
+
+	s := NewHeadlessUIState()
+	s.LogOutput = ioutil.Discard
+
+	// without OnExit or a window, the exit is logged, rather than
+	// offering to restart the simulator
+	sup := s.NewSupervisor(func() *exec.Cmd {
+		cmd := exec.Command(path, "-crash-after", "1")
+		cmd.Stderr = ioutil.Discard
+		return cmd
+	})
+	if err := sup.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer sup.Stop()
+
+	s.SetSW(1)
+	s.RunTicks(2)
+
+	for deadline := time.Now().Add(10 * time.Second); time.Now().Before(deadline); time.Sleep(10 * time.Millisecond) {
+		for _, e := range s.Log() {
+			if e.Level == LevelError && strings.HasPrefix(e.Message, "The simulator exited unexpectedly: ") {
+				return
+			}
+		}
+	}
+
+	t.Errorf("the exit was not logged: %v", s.Log())
+}
+
+func TestTailWriter(t *testing.T) {
+	defer func(n int) { StderrTailLines = n }(StderrTailLines)
+	StderrTailLines = 2
+
+	var all strings.Builder
+	tw := &tailWriter{}
+	tw.reset(&all)
+
+	tw.Write([]byte("one\ntwo\nth"))
+	tw.Write([]byte("ree\nfour"))
+
+	if tw.String() != "three\nfour" || all.String() != "one\ntwo\nthree\nfour" {
+		t.Errorf("kept %q, and passed through %q", tw.String(), all.String())
+	}
+}
@@ -0,0 +1,148 @@
+package de2gui
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/herclab/de2gui/de2gui/script"
+)
+
+// WriteState writes the state of the board to w as text, for use without a
+// display. HEX displays are shown as the characters they display, using the
+// same conventions as expect commands in scripts, followed by their
+// segments, and the LEDs, switches and KEYs are shown as binary, most
+// significant bit first, followed by their value in hexadecimal. Lines of the
+// LCD are only shown if they are not empty.
+func (s *UIState) WriteState(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	p := s.profile
+
+	fmt.Fprintf(bw, "tick %d\n", s.Tick)
+
+	if p.HEX > 0 {
+		chars := strings.Split(s.hexChars(p.HEX), "")
+		segments := []string{}
+		for i := p.HEX - 1; i >= 0; i-- {
+			segments = append(segments, fmt.Sprintf("%02x", s.HEX(i)&0x7f))
+		}
+		fmt.Fprintf(bw, "%-5s %s  (%s)\n", "HEX", strings.Join(chars, " "), strings.Join(segments, " "))
+	}
+
+	signals := []struct {
+		name  string
+		value uint32
+		bits  int
+	}{
+		{"LEDR", s.LEDR(), p.LEDR},
+		{"LEDG", s.LEDG(), p.LEDG},
+		{"SW", s.SW(), p.SW},
+		{"KEY", s.KEY(), p.KEY},
+	}
+
+	for _, v := range signals {
+		if v.bits > 0 {
+			fmt.Fprintf(bw, "%-5s %0*b  %s\n", v.name, v.bits, v.value, formatBits(v.value, v.bits))
+		}
+	}
+
+	for i, text := range s.lcd {
+		if text != "" {
+			fmt.Fprintf(bw, "LCD%d  %q\n", i, text)
+		}
+	}
+
+	return bw.Flush()
+}
+
+// CommandOptions controls RunCommands().
+type CommandOptions struct {
+	// Name identifies the commands in the report, for example the name
+	// of the script they were read from.
+	Name string
+
+	// Prompt is written before each command is read, if it is not
+	// empty. It is useful when the commands are typed by a user.
+	Prompt string
+
+	// KeepGoing continues after a command fails, rather than stopping.
+	// Failed expect commands never stop the commands.
+	KeepGoing bool
+
+	// Quiet only writes the outcome of each command, and not the state
+	// of the board.
+	Quiet bool
+}
+
+// RunCommands reads commands in the script format from r, see the script
+// package, and runs each one as it is read, writing its outcome to w,
+// followed by the state of the board, see WriteState(). This lets a
+// simulation be driven from a terminal, or by a grading script, without a
+// display, using the same callbacks as in the GUI. It returns a report of
+// the outcome of every expect command, and of the first command which
+// failed, if any.
+func (s *UIState) RunCommands(r io.Reader, w io.Writer, opts CommandOptions) *script.Report {
+	rep := &script.Report{Name: opts.Name, Results: []script.Result{}}
+	sc := bufio.NewScanner(r)
+
+	for lineno := 1; ; lineno++ {
+		if opts.Prompt != "" {
+			fmt.Fprint(w, opts.Prompt)
+		}
+
+		if !sc.Scan() {
+			if opts.Prompt != "" {
+				fmt.Fprintln(w)
+			}
+			break
+		}
+
+		c, err := script.ParseLine(sc.Text())
+		if err == nil && c == nil {
+			continue
+		}
+
+		var res *script.Result
+		if err == nil {
+			c.Line = lineno
+			count := s.Errors()
+			res, err = s.Exec(*c)
+			if err == nil {
+				err = s.errorSince(count)
+			}
+		}
+
+		switch {
+		case err != nil:
+			fmt.Fprintf(w, "ERROR line %d: %v\n", lineno, err)
+			if rep.Error == "" {
+				rep.Error = fmt.Sprintf("line %d: %v", lineno, err)
+			}
+
+		case res != nil:
+			rep.Results = append(rep.Results, *res)
+
+			status := "PASS"
+			if !res.Pass {
+				status = "FAIL"
+			}
+			fmt.Fprintf(w, "%s line %d tick %d: %s (observed %s)\n", status, res.Line, res.Tick, res.Check, res.Observed)
+		}
+
+		if !opts.Quiet {
+			s.WriteState(w)
+			fmt.Fprintln(w)
+		}
+
+		if err != nil && !opts.KeepGoing {
+			return rep
+		}
+	}
+
+	if err := sc.Err(); err != nil && rep.Error == "" {
+		rep.Error = err.Error()
+	}
+
+	return rep
+}
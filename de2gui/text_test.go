@@ -0,0 +1,90 @@
+package de2gui
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestWriteState(t *testing.T) {
+	s := newCounterBoard()
+	s.SetSW(0x25)
+	s.HoldKEY(1)
+	s.RunTicks(0x13)
+	s.SetHEX(7, HexBlank)
+	s.SetHEX(6, HexSegments(5))
+	s.SetLCD(1, "hello")
+
+	var buf bytes.Buffer
+	if err := s.WriteState(&buf); err != nil {
+		t.Fatal(err)
+	}
+
+	want := `tick 19
+HEX   _ 5 _ _ _ _ _ 3  (7f 12 7f 7f 7f 7f 7f 30)
+LEDR  000000000000010011  0x00013
+LEDG  000000010  0x002
+SW    000000000000100101  0x00025
+KEY   0010  0x2
+LCD1  "hello"
+`
+	if buf.String() != want {
+		t.Errorf("WriteState() wrote\n%s\nexpected\n%s", buf.String(), want)
+	}
+
+	// signals which the board does not have are left out
+	buf.Reset()
+	s = NewHeadlessUIStateFor(BoardProfile{Name: "small", LEDR: 3, KEY: 1})
+	if err := s.WriteState(&buf); err != nil {
+		t.Fatal(err)
+	}
+
+	want = "tick 0\nLEDR  000  0x0\nKEY   0  0x0\n"
+	if buf.String() != want {
+		t.Errorf("WriteState() wrote\n%s\nexpected\n%s", buf.String(), want)
+	}
+}
+
+func TestRunCommands(t *testing.T) {
+	input := "tick 2\n\n# a comment\nexpect ledr 2\nexpect ledr 3\nbogus\nsw 17 on\ntick 1\ntick 1\n"
+
+	var buf bytes.Buffer
+	s := newCounterBoard()
+	r := s.RunCommands(strings.NewReader(input), &buf, CommandOptions{Name: "cmds", Prompt: "> ", KeepGoing: true, Quiet: true})
+
+	want := `> > > > PASS line 4 tick 2: expect ledr 2 (observed 0x00002)
+> FAIL line 5 tick 2: expect ledr 3 (observed 0x00002)
+> ERROR line 6: unknown command 'bogus'
+> > ERROR line 8: OnTick panicked at tick 2: SW17 is on
+> ERROR line 9: OnTick panicked at tick 2: SW17 is on
+> 
+`
+	if buf.String() != want {
+		t.Errorf("RunCommands() wrote\n%s\nexpected\n%s", buf.String(), want)
+	}
+
+	// the first error is reported
+	if r.Name != "cmds" || len(r.Results) != 2 || r.Passed() != 1 || r.Error != "line 6: unknown command 'bogus'" {
+		t.Errorf("RunCommands() = %+v", r)
+	}
+}
+
+func TestRunCommandsStop(t *testing.T) {
+	var buf bytes.Buffer
+	s := newCounterBoard()
+	r := s.RunCommands(strings.NewReader("tick 1\nkey 9\ntick 1\n"), &buf, CommandOptions{})
+
+	// the state is written after each command, and the commands stop at
+	// the first error
+	var state bytes.Buffer
+	s.WriteState(&state)
+
+	want := state.String() + "\nERROR line 2: there is no KEY9\n" + state.String() + "\n"
+	if buf.String() != want {
+		t.Errorf("RunCommands() wrote\n%s\nexpected\n%s", buf.String(), want)
+	}
+
+	if s.Tick != 1 || r.Error != "line 2: there is no KEY9" {
+		t.Errorf("stopped at tick %d with error %q, expected tick 1 and the KEY9 error", s.Tick, r.Error)
+	}
+}
@@ -0,0 +1,466 @@
+// Package tui shows a de2gui board in a terminal, as an alternative to the
+// Fyne window for users connected over SSH, where forwarding the window is
+// too slow. The board is drawn with text and ANSI colors, the HEX displays
+// as seven-segment digits, and the switches and KEYs are worked with the
+// keyboard. It uses a UIState created with de2gui.NewHeadlessUIState(), so
+// the simulation is connected to it with the usual callbacks.
+//
+// The keys are:
+//
+//	0-9, a-h    flip SW0-SW9, SW10-SW17
+//	u i o p     push KEY0-KEY3, releasing it after a while
+//	U I O P     hold KEY0-KEY3 down, or release it
+//	t           tick once
+//	T           tick 100 times
+//	space       start or stop ticking 5 times a second
+//	R           reset
+//	:           type a command in the script format, see the script
+//	            package, such as "tick 5000" or "run until ledr!=0"
+//	escape      cancel the command which is running
+//	q           quit
+//
+// Boards with fewer switches or KEYs use the first of these keys. On boards
+// with more than 18 switches or 4 KEYs, the rest have no key, and are shown
+// without one, but can still be worked with commands such as "sw 0x80000".
+package tui
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/herclab/de2gui/de2gui"
+)
+
+// swKeys and keyKeys are the keys which work the switches and KEYs, in
+// order
+const (
+	swKeys  = "0123456789abcdefgh"
+	keyKeys = "uiop"
+)
+
+// AutoTickInterval is the time between ticks while ticking is started with
+// the space bar.
+var AutoTickInterval = 200 * time.Millisecond
+
+// logLines is the number of log messages shown below the board
+const logLines = 5
+
+// ANSI escape sequences
+const (
+	ansiReset = "\x1b[0m"
+	ansiRed   = "\x1b[1;31m"
+	ansiGreen = "\x1b[1;32m"
+	ansiDim   = "\x1b[90m"
+	ansiBold  = "\x1b[1m"
+)
+
+// Options controls Run() and Render().
+type Options struct {
+	// NoColor draws the board without colors, for terminals which do not
+	// support them.
+	NoColor bool
+}
+
+// painter writes text, in color unless colors are turned off
+type painter struct {
+	bytes.Buffer
+	color bool
+}
+
+// paint writes text in the color given by an ANSI escape sequence
+func (p *painter) paint(color, text string) {
+	if p.color {
+		p.WriteString(color + text + ansiReset)
+	} else {
+		p.WriteString(text)
+	}
+}
+
+// Render draws the board as text, with a line for each row of the HEX
+// displays, and lines for the LEDs, switches, KEYs and LCD. Lit segments
+// and LEDs are colored, or drawn with '#' if colors are turned off. The
+// key which works each switch and KEY, if any, is shown below it.
+func Render(s *de2gui.UIState, opts Options) string {
+	p := &painter{color: !opts.NoColor}
+	b := s.Profile()
+
+	lit := func(on bool, text string) {
+		switch {
+		case !on:
+			p.WriteString(strings.Repeat(" ", len(text)))
+		case p.color:
+			p.paint(ansiRed, text)
+		default:
+			p.WriteString(strings.Repeat("#", len(text)))
+		}
+	}
+
+	// each digit is three rows of three characters, segments being
+	// active-low
+	if b.HEX > 0 {
+		for row := 0; row < 3; row++ {
+			p.WriteString("  ")
+			for i := b.HEX - 1; i >= 0; i-- {
+				seg := s.HEX(i)
+				on := func(n uint) bool { return seg&(1<<n) == 0 }
+
+				switch row {
+				case 0:
+					p.WriteString(" ")
+					lit(on(0), "_")
+					p.WriteString(" ")
+				case 1:
+					lit(on(5), "|")
+					lit(on(6), "_")
+					lit(on(1), "|")
+				case 2:
+					lit(on(4), "|")
+					lit(on(3), "_")
+					lit(on(2), "|")
+				}
+				p.WriteString("  ")
+			}
+			p.WriteString("\n")
+		}
+
+		p.WriteString("  ")
+		for i := b.HEX - 1; i >= 0; i-- {
+			p.paint(ansiDim, fmt.Sprintf("HEX%d ", i))
+		}
+		p.WriteString("\n\n")
+	}
+
+	// LEDs, switches and KEYs are in columns three characters wide, so
+	// that LEDR i is above SW i, as on a DE2-115
+	leds := func(name string, n int, v uint32, color string) {
+		if n == 0 {
+			return
+		}
+
+		p.WriteString(fmt.Sprintf("  %-5s", name))
+		for i := n - 1; i >= 0; i-- {
+			switch {
+			case v&(1<<uint(i)) == 0:
+				p.paint(ansiDim, "..")
+			case p.color:
+				p.paint(color, "██")
+			default:
+				p.WriteString("##")
+			}
+			p.WriteString(" ")
+		}
+		p.WriteString(fmt.Sprintf(" 0x%0*x\n", (n+3)/4, v))
+	}
+
+	leds("LEDR", b.LEDR, s.LEDR(), ansiRed)
+	leds("LEDG", b.LEDG, s.LEDG(), ansiGreen)
+	p.WriteString("\n")
+
+	// labels writes the keys for the given number of controls, leaving
+	// those without a key blank
+	labels := func(keys string, n, width int) {
+		p.WriteString("       ")
+		for i := n - 1; i >= 0; i-- {
+			label := ""
+			if i < len(keys) {
+				label = keys[i : i+1]
+			}
+			p.paint(ansiDim, fmt.Sprintf("%-*s", width, label))
+		}
+		p.WriteString("\n")
+	}
+
+	if b.SW > 0 {
+		p.WriteString("  SW   ")
+		sw := s.SW()
+		for i := b.SW - 1; i >= 0; i-- {
+			if sw&(1<<uint(i)) != 0 {
+				p.paint(ansiGreen, "1  ")
+			} else {
+				p.paint(ansiDim, "0  ")
+			}
+		}
+		p.WriteString(fmt.Sprintf("0x%0*x\n", (b.SW+3)/4, sw))
+		labels(swKeys, b.SW, 3)
+	}
+
+	if b.KEY > 0 {
+		p.WriteString("  KEY  ")
+		key := s.KEY()
+		for i := b.KEY - 1; i >= 0; i-- {
+			if key&(1<<uint(i)) != 0 {
+				p.paint(ansiBold, "[#] ")
+			} else {
+				p.WriteString("[ ] ")
+			}
+		}
+		p.WriteString("\n")
+		labels(keyKeys, b.KEY, 4)
+	}
+
+	for i := 0; i < 2; i++ {
+		if text := s.LCD(i); text != "" {
+			p.WriteString(fmt.Sprintf("  LCD%d |%-16s|\n", i, text))
+		}
+	}
+
+	return p.String()
+}
+
+// terminal is the state of the frontend while it is running
+type terminal struct {
+	s    *de2gui.UIState
+	out  io.Writer
+	opts Options
+
+	// auto is true while ticking automatically
+	auto bool
+
+	// running is true while a command typed after ':' runs in another
+	// goroutine, which sends on done when it finishes
+	running bool
+	done    chan bool
+
+	// editing is true while a command is being typed into command
+	editing bool
+	command string
+
+	// last is the screen which was drawn most recently
+	last string
+}
+
+// Run shows s in the terminal in and out, usually os.Stdin and os.Stdout,
+// until the user quits, see the package documentation for the keys. While
+// it runs, messages logged by the UIState are shown below the board,
+// rather than written to LogOutput. The terminal is restored before Run
+// returns.
+func Run(s *de2gui.UIState, in *os.File, out io.Writer, opts Options) error {
+	restore, err := makeRaw(int(in.Fd()))
+	if err != nil {
+		return fmt.Errorf("cannot use the terminal: %v", err)
+	}
+	defer restore()
+
+	// use the alternate screen, and hide the cursor
+	fmt.Fprint(out, "\x1b[?1049h\x1b[?25l")
+	defer fmt.Fprint(out, "\x1b[?25h\x1b[?1049l")
+
+	logOutput := s.LogOutput
+	s.LogOutput = ioutil.Discard
+	defer func() { s.LogOutput = logOutput }()
+
+	keys := make(chan byte)
+	go func() {
+		buf := make([]byte, 64)
+		for {
+			n, err := in.Read(buf)
+			for _, b := range buf[:n] {
+				keys <- b
+			}
+			if err != nil {
+				close(keys)
+				return
+			}
+		}
+	}()
+
+	// the board is also redrawn regularly, since a simulator may change
+	// the outputs at any time
+	ticker := time.NewTicker(AutoTickInterval)
+	defer ticker.Stop()
+
+	t := newTerminal(s, out, opts)
+	for {
+		t.draw()
+
+		select {
+		case b, ok := <-keys:
+			if !ok || t.key(b) {
+				s.CancelCommand()
+				return nil
+			}
+
+		case <-t.done:
+			t.running = false
+
+		case <-ticker.C:
+			// a running command does its own ticking
+			if t.auto && !t.running {
+				s.RunTicks(1)
+			}
+		}
+	}
+}
+
+// newTerminal creates the state of the frontend
+func newTerminal(s *de2gui.UIState, out io.Writer, opts Options) *terminal {
+	// done is buffered, so that a command can finish after Run returns
+	return &terminal{s: s, out: out, opts: opts, done: make(chan bool, 1)}
+}
+
+// draw redraws the screen, if it changed
+func (t *terminal) draw() {
+	var b strings.Builder
+
+	status := "stopped"
+	switch {
+	case t.running:
+		status = "running a command, escape to cancel"
+	case t.auto:
+		status = "running"
+	}
+	fmt.Fprintf(&b, "  %s    tick %d    %s\n\n", t.s.Profile().Name, t.s.Tick, status)
+
+	b.WriteString(Render(t.s, t.opts))
+	b.WriteString("\n")
+
+	// the most recent messages, other than debugging detail
+	shown := []de2gui.LogEntry{}
+	log := t.s.Log()
+	for i := len(log) - 1; i >= 0 && len(shown) < logLines; i-- {
+		if log[i].Level > de2gui.LevelDebug {
+			shown = append([]de2gui.LogEntry{log[i]}, shown...)
+		}
+	}
+	for i := 0; i < logLines; i++ {
+		if i < len(shown) {
+			b.WriteString("  " + shown[i].String())
+		}
+		b.WriteString("\n")
+	}
+	b.WriteString("\n")
+
+	if t.editing {
+		b.WriteString("  : " + t.command + "_")
+	} else {
+		b.WriteString("  t tick  T tick 100  space run/stop  R reset  : command  q quit")
+	}
+
+	screen := b.String()
+	if screen == t.last {
+		return
+	}
+	t.last = screen
+
+	// overwrite the previous screen line by line, clearing what is left
+	// of each line, to avoid flicker
+	lines := strings.Split(screen, "\n")
+	fmt.Fprint(t.out, "\x1b[H"+strings.Join(lines, "\x1b[K\n")+"\x1b[K\x1b[J")
+}
+
+// key handles a key press, returning true if the user quit
+func (t *terminal) key(b byte) bool {
+	s := t.s
+
+	if t.running {
+		switch b {
+		case 'q':
+			return true
+		case 27, 3: // escape, ^C
+			s.CancelCommand()
+		}
+		return false
+	}
+
+	if t.editing {
+		t.edit(b)
+		return false
+	}
+
+	if i := strings.IndexByte(swKeys, b); i >= 0 {
+		s.ToggleSWBit(i)
+		return false
+	}
+
+	if i := strings.IndexByte(keyKeys, b); i >= 0 {
+		s.PushKEY(i)
+		return false
+	}
+
+	if i := strings.IndexByte(strings.ToUpper(keyKeys), b); i >= 0 {
+		// KEY() cannot be used, since it includes bounce and faults
+		if s.PressedKEYs()&(1<<uint(i)) != 0 {
+			s.ReleaseKEY(i)
+		} else {
+			s.HoldKEY(i)
+		}
+		return false
+	}
+
+	switch b {
+	case 'q', 3, 4: // q, ^C, ^D
+		return true
+	case 't':
+		s.RunTicks(1)
+	case 'T':
+		s.RunTicks(100)
+	case ' ':
+		t.auto = !t.auto
+	case 'R':
+		s.ExecLine("reset")
+	case ':':
+		t.editing = true
+		t.command = ""
+	}
+
+	return false
+}
+
+// edit handles a key press while a command is being typed
+func (t *terminal) edit(b byte) {
+	switch {
+	case b == '\r' || b == '\n':
+		t.editing = false
+		t.run(t.command)
+
+	case b == 27 || b == 3: // escape, ^C
+		t.editing = false
+
+	case b == 127 || b == 8: // backspace
+		if t.command != "" {
+			t.command = t.command[:len(t.command)-1]
+		}
+
+	case b >= ' ' && b < 127:
+		t.command += string(b)
+	}
+}
+
+// run runs a command in the script format in another goroutine, so that
+// the board is redrawn, and the command can be canceled, while it runs
+func (t *terminal) run(line string) {
+	line = strings.TrimSpace(line)
+	if line == "" {
+		return
+	}
+
+	t.running = true
+	go func() {
+		runCommand(t.s, line)
+		t.done <- true
+	}()
+}
+
+// runCommand runs a command in the script format, logging its outcome
+func runCommand(s *de2gui.UIState, line string) {
+	s.Logf(de2gui.LevelInfo, "> %s", line)
+
+	errors := s.Errors()
+	res, err := s.ExecLine(line)
+	switch {
+	case err != nil:
+		s.Logf(de2gui.LevelError, "%v", err)
+	case s.Errors() != errors:
+		// the panic has already been logged
+	case res == nil:
+	case res.Pass:
+		s.Logf(de2gui.LevelInfo, "pass: %s is %s", res.Target, res.Observed)
+	default:
+		s.Logf(de2gui.LevelWarning, "FAIL: expected %s to be %s, observed %s", res.Target, res.Expected, res.Observed)
+	}
+}
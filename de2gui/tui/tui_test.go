@@ -0,0 +1,107 @@
+package tui
+
+import (
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	"github.com/herclab/de2gui/de2gui"
+)
+
+func TestRenderWideBoard(t *testing.T) {
+	s := de2gui.NewHeadlessUIStateFor(de2gui.BoardProfile{Name: "wide", HEX: 2, LEDR: 32, SW: 20, KEY: 6})
+	s.SetSW(1 << 19)
+
+	text := Render(s, Options{NoColor: true})
+	if !strings.Contains(text, "h  g  f") {
+		t.Errorf("switch keys not shown:\n%s", text)
+	}
+	if !strings.Contains(text, "0x80000") {
+		t.Errorf("SW19 not shown:\n%s", text)
+	}
+}
+
+// smallBoard is a board with two of everything
+var smallBoard = de2gui.BoardProfile{Name: "small", HEX: 2, LEDR: 2, LEDG: 2, SW: 2, KEY: 2}
+
+func TestRenderDigits(t *testing.T) {
+	s := de2gui.NewHeadlessUIStateFor(smallBoard)
+	s.SetHEX(1, de2gui.HexSegments(1))
+	s.SetHEX(0, de2gui.HexSegments(2))
+
+	lines := strings.Split(Render(s, Options{NoColor: true}), "\n")
+	want := []string{
+		"        #",
+		"    #   ##",
+		"    #  ##",
+		"  HEX1 HEX0",
+	}
+
+	for i, line := range want {
+		if got := strings.TrimRight(lines[i], " "); got != line {
+			t.Errorf("line %d is %q, expected %q", i, got, line)
+		}
+	}
+
+	// in color, the segments are drawn as lines
+	text := Render(s, Options{})
+	if !strings.Contains(text, ansiRed+"_"+ansiReset+ansiRed+"|"+ansiReset) {
+		t.Errorf("the middle row of the 2 is not drawn in color:\n%q", text)
+	}
+}
+
+func TestKeys(t *testing.T) {
+	s := de2gui.NewHeadlessUIStateFor(smallBoard)
+	s.LogOutput = ioutil.Discard
+	s.OnTick = func(s *de2gui.UIState, final bool) { s.Tick++ }
+
+	term := newTerminal(s, ioutil.Discard, Options{})
+	press := func(keys string) {
+		for _, b := range []byte(keys) {
+			if term.key(b) {
+				t.Fatalf("key %q quit", b)
+			}
+		}
+	}
+
+	// the board has no SW2, KEY2 or KEY3
+	press("02op")
+	if s.SW() != 1 || s.KEY() != 0 {
+		t.Errorf("SW() = %d and KEY() = %d, expected 1 and 0", s.SW(), s.KEY())
+	}
+
+	press("UIOP")
+	if s.PressedKEYs() != 3 {
+		t.Errorf("PressedKEYs() = %d after holding every KEY, expected 3", s.PressedKEYs())
+	}
+
+	// KEYs released by a command are held again by the keyboard
+	press(":key 0 up\r")
+	<-term.done
+	term.running = false
+	press("UI")
+	if s.PressedKEYs() != 1 {
+		t.Errorf("PressedKEYs() = %d, expected 1", s.PressedKEYs())
+	}
+
+	press("tT")
+	if s.Tick != 101 {
+		t.Errorf("Tick = %d, expected 101", s.Tick)
+	}
+
+	// only escape and q work while a command runs
+	press(":tick 5\r")
+	if !term.running {
+		t.Fatalf("the command is not running")
+	}
+	press("1t")
+	<-term.done
+	term.running = false
+	if s.Tick != 106 || s.SW() != 1 {
+		t.Errorf("Tick = %d and SW() = %d, expected 106 and 1", s.Tick, s.SW())
+	}
+
+	if !term.key('q') {
+		t.Errorf("q did not quit")
+	}
+}
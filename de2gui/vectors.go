@@ -0,0 +1,203 @@
+package de2gui
+
+import (
+	"fmt"
+	"image/color"
+	"strconv"
+	"strings"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/canvas"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+
+	"github.com/herclab/de2gui/de2gui/script"
+	"github.com/herclab/de2gui/de2gui/vectors"
+)
+
+// DefaultVectorPeriod is the number of ticks between test vectors loaded
+// using the GUI, unless the user changes it.
+var DefaultVectorPeriod uint64 = 100
+
+// LoadVectors begins applying the test vectors in f, one every period ticks,
+// starting with the next tick. Immediately before each vector's inputs are
+// applied, and once more after the last vector, the outputs expected by the
+// previous vector are checked. Mismatches are written to VectorLog, and shown
+// in the GUI. The results of all checks are collected in VectorReport().
+//
+// Vectors are applied using futures, so calling ClearFutures() stops them.
+func (s *UIState) LoadVectors(f *vectors.File, period uint64) {
+	if period == 0 {
+		period = 1
+	}
+
+	s.vectorReport = &script.Report{Name: f.Name, Results: []script.Result{}}
+
+	start := s.Tick
+	for k := range f.Vectors {
+		k := k
+		when := start + uint64(k)*period
+		s.ScheduleFuture(when, func(s *UIState) { s.applyVector(f, k) })
+		s.ScheduleFuture(when+period, func(s *UIState) { s.checkVector(f, k) })
+	}
+
+	s.setVectorStatus(fmt.Sprintf("%d vectors loaded", len(f.Vectors)), nil)
+}
+
+// VectorReport returns the results of checking the outputs of the most
+// recently loaded test vectors, or nil if LoadVectors() has never been
+// called.
+func (s *UIState) VectorReport() *script.Report {
+	return s.vectorReport
+}
+
+// Internal function which changes all of the keys at once
+func (s *UIState) setKEY(val uint32) {
+	// cancel any pending releases
+	for i := range s.keyGen {
+		s.keyGen[i]++
+	}
+
+	s.key = val & bitmask(s.profile.KEY)
+	s.keyUpdate()
+}
+
+// Internal function which applies the inputs of the k-th vector in f
+func (s *UIState) applyVector(f *vectors.File, k int) {
+	v := f.Vectors[k]
+	for i, col := range f.Columns {
+		switch col {
+		case "SW":
+			s.SetSW(uint32(v.Values[i]))
+		case "KEY":
+			s.setKEY(uint32(v.Values[i]))
+		case "KEY_N":
+			s.setKEY(^uint32(v.Values[i]))
+		}
+	}
+}
+
+// Internal function which checks the outputs expected by the k-th vector in f
+func (s *UIState) checkVector(f *vectors.File, k int) {
+	v := f.Vectors[k]
+	failed := 0
+	for i, col := range f.Columns {
+		if col == "-" || vectors.IsInput(col) {
+			continue
+		}
+
+		c := script.Command{
+			Line: v.Line,
+			Name: "expect",
+			Args: []string{strings.ToLower(col), fmt.Sprintf("0x%x", v.Values[i])},
+		}
+
+		e := script.Expect{Target: c.Args[0], Value: v.Values[i], Mask: v.Masks[i]}
+		if strings.HasPrefix(col, "HEX") {
+			e.Index, _ = strconv.Atoi(col[3:])
+		}
+
+		r, err := s.check(c, e)
+		if err != nil {
+			s.logVector(LevelError, "%s:%d: %v\n", f.Name, v.Line, err)
+			failed++
+			continue
+		}
+
+		s.vectorReport.Results = append(s.vectorReport.Results, *r)
+		if !r.Pass {
+			s.logVector(LevelWarning, "%s:%d: vector %d at tick %d: %s expected %s, observed %s\n",
+				f.Name, v.Line, k, s.Tick, col, r.Expected, r.Observed)
+			failed++
+		}
+	}
+
+	status := fmt.Sprintf("vector %d/%d", k+1, len(f.Vectors))
+	total := s.vectorReport.Failed()
+	if total > 0 {
+		status += fmt.Sprintf(": %d mismatches", total)
+	}
+
+	if failed > 0 {
+		s.setVectorStatus(status+fmt.Sprintf(" (line %d)", v.Line), ColorRedActive)
+	} else if total > 0 {
+		s.setVectorStatus(status, ColorRedActive)
+	} else {
+		s.setVectorStatus(status, ColorGreenActive)
+	}
+}
+
+// Internal function which writes a message to VectorLog, or adds it to the
+// log if VectorLog is nil
+func (s *UIState) logVector(level Level, format string, args ...interface{}) {
+	if s.VectorLog == nil {
+		s.Logf(level, format, args...)
+		return
+	}
+	fmt.Fprintf(s.VectorLog, format, args...)
+}
+
+// Internal function which updates the vector status shown in the GUI. If c
+// is nil, the theme's foreground color is used.
+func (s *UIState) setVectorStatus(text string, c color.Color) {
+	if s.headless {
+		return
+	}
+
+	if c == nil {
+		c = theme.ForegroundColor()
+	}
+
+	s.vectorStatus.Text = text
+	s.vectorStatus.Color = c
+	canvas.Refresh(s.vectorStatus)
+}
+
+// Internal function which creates the test vector controls
+func (s *UIState) newVectorControls() fyne.CanvasObject {
+	return container.NewHBox(
+		widget.NewLabel("Vectors:"),
+		widget.NewButton("Load...", func() { s.openVectors() }),
+		widget.NewLabel("every"),
+		s.vectorPeriodEntry,
+		widget.NewLabel("ticks"),
+		s.vectorStatus,
+	)
+}
+
+// Internal function wired into the vector "Load..." button
+func (s *UIState) openVectors() {
+	w := s.window()
+	if w == nil {
+		return
+	}
+
+	period, err := strconv.ParseUint(s.vectorPeriodEntry.Text, 10, 64)
+	if err != nil || period == 0 {
+		dialog.ShowError(fmt.Errorf("invalid number of ticks per vector '%s'", s.vectorPeriodEntry.Text), w)
+		return
+	}
+
+	dialog.ShowFileOpen(func(r fyne.URIReadCloser, err error) {
+		if err != nil {
+			dialog.ShowError(err, w)
+			return
+		}
+
+		if r == nil {
+			// canceled
+			return
+		}
+		defer r.Close()
+
+		f, err := vectors.Parse(r.URI().Name(), r, nil)
+		if err != nil {
+			dialog.ShowError(err, w)
+			return
+		}
+
+		s.LoadVectors(f, period)
+	}, w)
+}
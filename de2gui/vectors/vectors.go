@@ -0,0 +1,242 @@
+// Package vectors reads test vector files in the format accepted by the
+// Verilog $readmemh system task, so that existing testbench materials can be
+// used with de2gui, see de2gui.UIState.LoadVectors().
+//
+// Each non-blank line of a vector file is one test vector, consisting of
+// whitespace-separated hexadecimal words. Both // and /* */ comments are
+// allowed, as are underscores within words. Address directives (@...) are
+// ignored. A digit written as x or z is a "don't care", and is not checked.
+//
+// The meaning of each word is given by a list of column names, one of SW,
+// KEY, KEY_N, LEDR, LEDG, or HEX0...HEX7. KEY_N is the same as KEY, except
+// that the value is active-low, as it is on the physical board. A column
+// named "-" is ignored. The columns can also be given within the file using
+// a comment of the form
+//
+//	// columns: SW KEY LEDR HEX1 HEX0
+//
+// which takes precedence over the columns passed to Parse().
+package vectors
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// DefaultColumns is the column layout used by Parse() if none is given.
+var DefaultColumns = []string{"SW", "KEY", "LEDR", "LEDG"}
+
+// Vector is a single line of a vector file.
+type Vector struct {
+	// Line is the line of the file the vector appeared on.
+	Line int
+
+	// Values contains the value of each column.
+	Values []uint64
+
+	// Masks contains a mask for each column, with the bits of don't care
+	// digits cleared.
+	Masks []uint64
+}
+
+// File is a parsed vector file.
+type File struct {
+	// Name is the name of the file, used in log messages.
+	Name string
+
+	// Columns contains the name of each column in the file.
+	Columns []string
+
+	Vectors []Vector
+}
+
+// IsInput returns true if the named column is an input to the design, rather
+// than an expected output.
+func IsInput(column string) bool {
+	return column == "SW" || column == "KEY" || column == "KEY_N"
+}
+
+// validColumn returns true if name is allowed as a column name
+func validColumn(name string) bool {
+	switch name {
+	case "SW", "KEY", "KEY_N", "LEDR", "LEDG", "-":
+		return true
+	}
+
+	if strings.HasPrefix(name, "HEX") {
+		_, err := strconv.Atoi(name[3:])
+		return err == nil
+	}
+
+	return false
+}
+
+// parseColumns validates and normalizes a list of column names
+func parseColumns(names []string) ([]string, error) {
+	if len(names) == 0 {
+		return nil, fmt.Errorf("no columns given")
+	}
+
+	cols := make([]string, len(names))
+	for i, v := range names {
+		cols[i] = strings.ToUpper(v)
+		if !validColumn(cols[i]) {
+			return nil, fmt.Errorf("unknown column '%s'", v)
+		}
+	}
+
+	return cols, nil
+}
+
+// parseWord parses a hexadecimal word, returning its value and don't care
+// mask.
+func parseWord(w string) (value, mask uint64, err error) {
+	w = strings.Replace(w, "_", "", -1)
+	if len(w) == 0 || len(w) > 16 {
+		return 0, 0, fmt.Errorf("invalid word '%s'", w)
+	}
+
+	for _, c := range strings.ToLower(w) {
+		value <<= 4
+		mask <<= 4
+
+		switch {
+		case c == 'x' || c == 'z':
+
+		case c >= '0' && c <= '9':
+			value |= uint64(c - '0')
+			mask |= 0xf
+
+		case c >= 'a' && c <= 'f':
+			value |= uint64(c-'a') + 10
+			mask |= 0xf
+
+		default:
+			return 0, 0, fmt.Errorf("invalid hexadecimal digit '%c'", c)
+		}
+	}
+
+	// digits above the ones given are always checked, since they are
+	// implicitly zero
+	mask |= ^uint64(0) << uint(4*len(w))
+
+	return value, mask, nil
+}
+
+// Parse reads a vector file from r. The name is used in error messages. If
+// columns is nil, DefaultColumns is used, unless the file specifies its own
+// columns.
+func Parse(name string, r io.Reader, columns []string) (*File, error) {
+	if columns == nil {
+		columns = DefaultColumns
+	}
+
+	cols, err := parseColumns(columns)
+	if err != nil {
+		return nil, err
+	}
+
+	f := &File{Name: name, Columns: cols}
+
+	scanner := bufio.NewScanner(r)
+	lineno := 0
+	inComment := false
+	for scanner.Scan() {
+		lineno++
+		line := scanner.Text()
+		errorf := func(format string, args ...interface{}) error {
+			return fmt.Errorf("%s:%d: %s", name, lineno, fmt.Sprintf(format, args...))
+		}
+
+		// strip block comments
+		text := ""
+		for len(line) > 0 {
+			if inComment {
+				end := strings.Index(line, "*/")
+				if end < 0 {
+					line = ""
+					break
+				}
+				line = line[end+2:]
+				inComment = false
+				continue
+			}
+
+			start := strings.Index(line, "/*")
+			if start < 0 {
+				text += line
+				break
+			}
+			text += line[:start] + " "
+			line = line[start+2:]
+			inComment = true
+		}
+
+		// line comments, which may specify the columns
+		if i := strings.Index(text, "//"); i >= 0 {
+			comment := strings.TrimSpace(text[i+2:])
+			text = text[:i]
+
+			if strings.HasPrefix(strings.ToLower(comment), "columns:") {
+				if len(f.Vectors) != 0 {
+					return nil, errorf("columns must be given before the first vector")
+				}
+
+				cols, err := parseColumns(strings.Fields(comment[len("columns:"):]))
+				if err != nil {
+					return nil, errorf("%v", err)
+				}
+				f.Columns = cols
+			}
+		}
+
+		words := strings.Fields(text)
+		if len(words) == 0 || strings.HasPrefix(words[0], "@") {
+			continue
+		}
+
+		if len(words) != len(f.Columns) {
+			return nil, errorf("expected %d words, got %d", len(f.Columns), len(words))
+		}
+
+		v := Vector{
+			Line:   lineno,
+			Values: make([]uint64, len(words)),
+			Masks:  make([]uint64, len(words)),
+		}
+
+		for i, w := range words {
+			v.Values[i], v.Masks[i], err = parseWord(w)
+			if err != nil {
+				return nil, errorf("%v", err)
+			}
+
+			if IsInput(f.Columns[i]) && v.Masks[i] != ^uint64(0) {
+				return nil, errorf("inputs cannot contain don't care digits")
+			}
+		}
+
+		f.Vectors = append(f.Vectors, v)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return f, nil
+}
+
+// ParseFile reads a vector file from path, see Parse().
+func ParseFile(path string, columns []string) (*File, error) {
+	fp, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer fp.Close()
+
+	return Parse(path, fp, columns)
+}
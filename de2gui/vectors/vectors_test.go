@@ -0,0 +1,85 @@
+package vectors
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	text := `// a vector file
+@0
+/* the columns are
+   given here */ // columns: sw key_n - LEDR HEX0
+0_3 e 0 0006 40
+1 f /* inline */ ff xxx3 7x
+`
+
+	f, err := Parse("test.hex", strings.NewReader(text), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(f.Columns, []string{"SW", "KEY_N", "-", "LEDR", "HEX0"}) {
+		t.Errorf("the columns are %v", f.Columns)
+	}
+
+	all := ^uint64(0)
+	want := []Vector{
+		{Line: 5, Values: []uint64{3, 0xe, 0, 6, 0x40}, Masks: []uint64{all, all, all, all, all}},
+		{Line: 6, Values: []uint64{1, 0xf, 0xff, 3, 0x70}, Masks: []uint64{all, all, all, all &^ 0xfff0, all &^ 0xf}},
+	}
+	if !reflect.DeepEqual(f.Vectors, want) {
+		t.Errorf("the vectors are\n%+v\nexpected\n%+v", f.Vectors, want)
+	}
+}
+
+func TestParseColumns(t *testing.T) {
+	f, err := Parse("test.hex", strings.NewReader("1 2 3 4\n"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(f.Columns, DefaultColumns) {
+		t.Errorf("the columns are %v, expected the default %v", f.Columns, DefaultColumns)
+	}
+
+	f, err = Parse("test.hex", strings.NewReader("1 2\n"), []string{"sw", "ledg"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(f.Columns, []string{"SW", "LEDG"}) {
+		t.Errorf("the columns are %v, expected those given", f.Columns)
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		text    string
+		columns []string
+		err     string
+	}{
+		{"1 2 3\n", nil, "test.hex:1: expected 4 words, got 3"},
+		{"1 2 3 4g\n", nil, "test.hex:1: invalid hexadecimal digit 'g'"},
+		{"1 2 3 11112222333344445\n", nil, "test.hex:1: invalid word '11112222333344445'"},
+		{"x 2 3 4\n", nil, "test.hex:1: inputs cannot contain don't care digits"},
+		{"1 2 3 4\n// columns: SW LEDR\n", nil, "test.hex:2: columns must be given before the first vector"},
+		{"// columns: SW LEDB\n", nil, "test.hex:1: unknown column 'LEDB'"},
+		{"// columns:\n", nil, "test.hex:1: no columns given"},
+		{"1\n", []string{"HEXA"}, "unknown column 'HEXA'"},
+	}
+
+	for _, test := range tests {
+		_, err := Parse("test.hex", strings.NewReader(test.text), test.columns)
+		if err == nil || err.Error() != test.err {
+			t.Errorf("%q: returned error %v, expected %q", test.text, err, test.err)
+		}
+	}
+}
+
+func TestIsInput(t *testing.T) {
+	for col, want := range map[string]bool{"SW": true, "KEY": true, "KEY_N": true, "LEDR": false, "HEX0": false, "-": false} {
+		if IsInput(col) != want {
+			t.Errorf("IsInput(%q) = %v, expected %v", col, !want, want)
+		}
+	}
+}
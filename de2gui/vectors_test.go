@@ -0,0 +1,57 @@
+package de2gui
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/herclab/de2gui/de2gui/vectors"
+)
+
+func TestLoadVectors(t *testing.T) {
+	s := NewHeadlessUIState()
+	s.OnTick = func(s *UIState, final bool) { s.Tick++ }
+	s.OnSW = func(s *UIState) { s.SetLEDR(s.SW() << 1) }
+	s.OnKEY = func(s *UIState) { s.SetLEDG(s.KEY()) }
+
+	var log bytes.Buffer
+	s.VectorLog = &log
+
+	f, err := vectors.Parse("test.hex", strings.NewReader(`// columns: SW KEY_N LEDR LEDG
+1 e 2 1
+3 f 6 x
+2 f 5 0
+`), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s.RunTicks(5)
+	s.LoadVectors(f, 10)
+
+	// the last vector is checked at the start of the tick after tick 35
+	s.RunTicks(30)
+	if r := s.VectorReport(); len(r.Results) != 4 {
+		t.Fatalf("checked %d outputs before the last vector was checked, expected 4", len(r.Results))
+	}
+
+	s.RunTicks(1)
+	r := s.VectorReport()
+	if r.Name != "test.hex" || len(r.Results) != 6 || r.Failed() != 1 {
+		t.Fatalf("VectorReport() = %+v, expected 6 results with 1 failure", r)
+	}
+
+	failed := r.Results[4]
+	if failed.Pass || failed.Line != 4 || failed.Tick != 35 || failed.Target != "ledr" {
+		t.Errorf("the failed result is %+v, expected LEDR on line 4 to fail at tick 35", failed)
+	}
+
+	want := "test.hex:4: vector 2 at tick 35: LEDR expected 0x00005, observed 0x00004\n"
+	if log.String() != want {
+		t.Errorf("VectorLog contains %q, expected %q", log.String(), want)
+	}
+
+	if s.KEY() != 0 || s.SW() != 2 {
+		t.Errorf("KEY() = %d and SW() = %d after the vectors, expected 0 and 2", s.KEY(), s.SW())
+	}
+}
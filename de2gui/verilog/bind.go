@@ -0,0 +1,132 @@
+package verilog
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Signal is a signal of a development board which a port can be bound to.
+type Signal struct {
+	// Name is the name of the signal in the board's pin list, such as
+	// "LEDR".
+	Name string
+
+	// Dir is the direction of the port the signal is connected to, so SW
+	// is an Input.
+	Dir Direction
+
+	// Width is the number of bits in the signal.
+	Width int
+}
+
+// BoardSignals returns the signals of a board with the given number of each
+// kind of peripheral, as in de2gui.BoardProfile. Every board is assumed to
+// have a 50 MHz CLOCK_50.
+func BoardSignals(hex, ledr, ledg, sw, key int) []Signal {
+	signals := []Signal{{Name: "CLOCK_50", Dir: Input, Width: 1}}
+
+	counts := []Signal{
+		{"SW", Input, sw}, {"KEY", Input, key},
+		{"LEDR", Output, ledr}, {"LEDG", Output, ledg},
+	}
+	for _, v := range counts {
+		if v.Width > 0 {
+			signals = append(signals, v)
+		}
+	}
+
+	for i := 0; i < hex; i++ {
+		signals = append(signals, Signal{Name: fmt.Sprintf("HEX%d", i), Dir: Output, Width: 7})
+	}
+
+	return signals
+}
+
+// DE2115 contains the signals of the Terasic DE2-115.
+var DE2115 = BoardSignals(8, 18, 9, 18, 4)
+
+// Binding connects a port of a module to a signal of the board.
+type Binding struct {
+	Port   Port
+	Signal Signal
+}
+
+// Problem is a port which may not be connected as its author intended.
+type Problem struct {
+	Port    Port
+	Message string
+}
+
+// String returns the problem as a message for the user.
+func (p Problem) String() string {
+	return fmt.Sprintf("line %d: %s: %s", p.Port.Line, p.Port.Name, p.Message)
+}
+
+// Bindings is the result of binding the ports of a module to a board.
+type Bindings struct {
+	// Bound contains the ports which match a board signal, in the order
+	// of the module's ports.
+	Bound []Binding
+
+	// Unrecognized contains the ports which do not match any signal. A
+	// harness must drive or ignore them itself.
+	Unrecognized []Port
+
+	// Problems contains width mismatches, ports whose width is unknown,
+	// and ports with the name of a signal but the wrong direction, which
+	// are not bound.
+	Problems []Problem
+}
+
+// Bind matches the ports of m to the board signals with the same name,
+// ignoring case. A port which is narrower or wider than its signal is still
+// bound, using the bits they have in common, but is reported as a problem.
+func Bind(m *Module, board []Signal) *Bindings {
+	b := &Bindings{}
+
+	for _, port := range m.Ports {
+		problem := func(format string, args ...interface{}) {
+			b.Problems = append(b.Problems, Problem{Port: port, Message: fmt.Sprintf(format, args...)})
+		}
+
+		var sig *Signal
+		for i := range board {
+			if strings.EqualFold(board[i].Name, port.Name) {
+				sig = &board[i]
+				break
+			}
+		}
+
+		if sig == nil {
+			b.Unrecognized = append(b.Unrecognized, port)
+			continue
+		}
+
+		if port.Dir != sig.Dir {
+			problem("should be an %s, not an %s", sig.Dir, port.Dir)
+			continue
+		}
+
+		switch {
+		case port.Width == 0:
+			problem("width could not be determined, expected %d bits", sig.Width)
+		case port.Width != sig.Width:
+			problem("is %d bits wide, but %s has %d", port.Width, sig.Name, sig.Width)
+		}
+
+		b.Bound = append(b.Bound, Binding{Port: port, Signal: *sig})
+	}
+
+	return b
+}
+
+// Signal returns the port bound to the named signal, or nil if there is
+// none.
+func (b *Bindings) Signal(name string) *Port {
+	for i := range b.Bound {
+		if b.Bound[i].Signal.Name == name {
+			return &b.Bound[i].Port
+		}
+	}
+	return nil
+}
@@ -0,0 +1,269 @@
+package verilog
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// exprParser evaluates constant integer expressions, such as the bounds of
+// a range, which may refer to parameters
+type exprParser struct {
+	tokens []token
+	pos    int
+	params map[string]int
+}
+
+// evaluate returns the value of the expression in tokens
+func evaluate(tokens []token, params map[string]int) (int, error) {
+	p := &exprParser{tokens: tokens, params: params}
+
+	v, err := p.ternary()
+	if err != nil {
+		return 0, err
+	}
+	if p.pos != len(p.tokens) {
+		return 0, fmt.Errorf("unexpected '%s' in expression", p.tokens[p.pos].text)
+	}
+	return v, nil
+}
+
+// peek returns the text of the next token, or "" at the end of the
+// expression
+func (p *exprParser) peek() string {
+	if p.pos >= len(p.tokens) {
+		return ""
+	}
+	return p.tokens[p.pos].text
+}
+
+func (p *exprParser) expect(text string) error {
+	if p.peek() != text {
+		return fmt.Errorf("expected '%s' in expression", text)
+	}
+	p.pos++
+	return nil
+}
+
+// ternary parses cond ? a : b, and comparisons, which are mostly found in
+// parameter defaults
+func (p *exprParser) ternary() (int, error) {
+	cond, err := p.comparison()
+	if err != nil || p.peek() != "?" {
+		return cond, err
+	}
+	p.pos++
+
+	a, err := p.ternary()
+	if err != nil {
+		return 0, err
+	}
+	if err := p.expect(":"); err != nil {
+		return 0, err
+	}
+	b, err := p.ternary()
+	if err != nil {
+		return 0, err
+	}
+
+	if cond != 0 {
+		return a, nil
+	}
+	return b, nil
+}
+
+// comparison parses a single comparison, such as N > 8. The operators are
+// made of two tokens, except for < and >.
+func (p *exprParser) comparison() (int, error) {
+	a, err := p.sum()
+	if err != nil {
+		return 0, err
+	}
+
+	op := p.peek()
+	switch op {
+	case "<", ">", "=", "!":
+	default:
+		return a, nil
+	}
+	p.pos++
+	if p.peek() == "=" {
+		op += "="
+		p.pos++
+	}
+
+	b, err := p.sum()
+	if err != nil {
+		return 0, err
+	}
+
+	result := false
+	switch op {
+	case "<":
+		result = a < b
+	case ">":
+		result = a > b
+	case "<=":
+		result = a <= b
+	case ">=":
+		result = a >= b
+	case "==":
+		result = a == b
+	case "!=":
+		result = a != b
+	default:
+		return 0, fmt.Errorf("unexpected '%s' in expression", op)
+	}
+
+	if result {
+		return 1, nil
+	}
+	return 0, nil
+}
+
+func (p *exprParser) sum() (int, error) {
+	v, err := p.product()
+	for err == nil && (p.peek() == "+" || p.peek() == "-") {
+		op := p.peek()
+		p.pos++
+
+		var w int
+		w, err = p.product()
+		if op == "+" {
+			v += w
+		} else {
+			v -= w
+		}
+	}
+	return v, err
+}
+
+func (p *exprParser) product() (int, error) {
+	v, err := p.unary()
+	for err == nil && (p.peek() == "*" || p.peek() == "/" || p.peek() == "%" || p.peek() == "**") {
+		op := p.peek()
+		p.pos++
+
+		var w int
+		w, err = p.unary()
+		if err != nil {
+			break
+		}
+
+		switch op {
+		case "*":
+			v *= w
+		case "**":
+			r := 1
+			for i := 0; i < w; i++ {
+				r *= v
+			}
+			v = r
+		default:
+			if w == 0 {
+				return 0, fmt.Errorf("division by zero in expression")
+			}
+			if op == "/" {
+				v /= w
+			} else {
+				v %= w
+			}
+		}
+	}
+	return v, err
+}
+
+func (p *exprParser) unary() (int, error) {
+	switch p.peek() {
+	case "-":
+		p.pos++
+		v, err := p.unary()
+		return -v, err
+
+	case "+":
+		p.pos++
+		return p.unary()
+	}
+
+	return p.primary()
+}
+
+func (p *exprParser) primary() (int, error) {
+	text := p.peek()
+	switch {
+	case text == "":
+		return 0, fmt.Errorf("unexpected end of expression")
+
+	case text == "(":
+		p.pos++
+		v, err := p.ternary()
+		if err != nil {
+			return 0, err
+		}
+		return v, p.expect(")")
+
+	case text == "$clog2":
+		p.pos++
+		if err := p.expect("("); err != nil {
+			return 0, err
+		}
+		v, err := p.ternary()
+		if err != nil {
+			return 0, err
+		}
+		if err := p.expect(")"); err != nil {
+			return 0, err
+		}
+
+		n := 0
+		for (1 << uint(n)) < v {
+			n++
+		}
+		return n, nil
+
+	case isDigit(text[0]) || text[0] == '\'':
+		p.pos++
+		return parseNumber(text)
+
+	case isIdentStart(text[0]):
+		p.pos++
+		v, ok := p.params[text]
+		if !ok {
+			return 0, fmt.Errorf("unknown parameter '%s'", text)
+		}
+		return v, nil
+	}
+
+	return 0, fmt.Errorf("unexpected '%s' in expression", text)
+}
+
+// parseNumber parses an integer literal, such as 18, 8'd10 or 'hff
+func parseNumber(text string) (int, error) {
+	text = strings.Replace(text, "_", "", -1)
+
+	tick := strings.IndexByte(text, '\'')
+	if tick < 0 {
+		v, err := strconv.ParseInt(text, 10, 32)
+		return int(v), err
+	}
+
+	digits := strings.TrimLeft(text[tick+1:], "sS")
+	base := 10
+	if len(digits) > 0 && isBase(digits[0]) {
+		switch digits[0] {
+		case 'b', 'B':
+			base = 2
+		case 'o', 'O':
+			base = 8
+		case 'h', 'H':
+			base = 16
+		}
+		digits = digits[1:]
+	}
+
+	v, err := strconv.ParseInt(digits, base, 32)
+	if err != nil {
+		return 0, fmt.Errorf("invalid number '%s'", text)
+	}
+	return int(v), nil
+}
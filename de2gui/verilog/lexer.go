@@ -0,0 +1,156 @@
+package verilog
+
+import (
+	"strings"
+)
+
+// token is a single lexical token of a Verilog source file
+type token struct {
+	text string
+	line int
+}
+
+// skippedDirectives contains the compiler directives whose arguments are
+// skipped, along with the rest of the line
+var skippedDirectives = map[string]bool{
+	"timescale": true, "define": true, "undef": true, "include": true,
+	"ifdef": true, "ifndef": true, "elsif": true, "else": true, "endif": true,
+	"default_nettype": true, "resetall": true, "celldefine": true,
+	"endcelldefine": true, "pragma": true, "line": true,
+}
+
+func isIdentStart(c byte) bool {
+	return c == '_' || c == '$' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+}
+
+func isIdentChar(c byte) bool {
+	return isIdentStart(c) || c >= '0' && c <= '9'
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+// isBase returns true if c is the base of a based number, as in 8'hff
+func isBase(c byte) bool {
+	return strings.IndexByte("bBoOdDhH", c) >= 0
+}
+
+// isBasedDigit returns true if c may appear in the digits of a based number
+func isBasedDigit(c byte) bool {
+	return isDigit(c) || c == '_' || c == '?' || strings.IndexByte("abcdefABCDEFxXzZ", c) >= 0
+}
+
+// tokenize splits Verilog source into tokens, discarding comments,
+// attributes and the directives in skippedDirectives. Other directives,
+// such as uses of macros, are kept as tokens.
+func tokenize(src string) []token {
+	tokens := []token{}
+	line := 1
+
+	for i := 0; i < len(src); {
+		c := src[i]
+		start := i
+
+		switch {
+		case c == '\n':
+			line++
+			i++
+
+		case c == ' ' || c == '\t' || c == '\r' || c == '\f':
+			i++
+
+		case strings.HasPrefix(src[i:], "//"):
+			for i < len(src) && src[i] != '\n' {
+				i++
+			}
+
+		case strings.HasPrefix(src[i:], "/*"):
+			end := strings.Index(src[i+2:], "*/")
+			if end < 0 {
+				end = len(src) - i - 2
+			}
+			line += strings.Count(src[i:i+2+end], "\n")
+			i += end + 4
+
+		// attributes, but not @(*)
+		case strings.HasPrefix(src[i:], "(*") && !strings.HasPrefix(src[i:], "(*)"):
+			end := strings.Index(src[i+2:], "*)")
+			if end < 0 {
+				end = len(src) - i - 2
+			}
+			line += strings.Count(src[i:i+2+end], "\n")
+			i += end + 4
+
+		case c == '`':
+			i++
+			for i < len(src) && isIdentChar(src[i]) {
+				i++
+			}
+
+			if skippedDirectives[src[start+1:i]] {
+				for i < len(src) && src[i] != '\n' {
+					i++
+				}
+			} else {
+				tokens = append(tokens, token{src[start:i], line})
+			}
+
+		case c == '"':
+			i++
+			for i < len(src) && src[i] != '"' && src[i] != '\n' {
+				if src[i] == '\\' {
+					i++
+				}
+				i++
+			}
+			i++
+			if i > len(src) {
+				i = len(src)
+			}
+			tokens = append(tokens, token{src[start:i], line})
+
+		case c == '\\':
+			for i < len(src) && src[i] != ' ' && src[i] != '\t' && src[i] != '\n' {
+				i++
+			}
+			tokens = append(tokens, token{src[start+1 : i], line})
+
+		case isIdentStart(c):
+			for i < len(src) && isIdentChar(src[i]) {
+				i++
+			}
+			tokens = append(tokens, token{src[start:i], line})
+
+		case isDigit(c) || c == '\'':
+			for i < len(src) && (isDigit(src[i]) || src[i] == '_') {
+				i++
+			}
+
+			// the base and digits of a based number, such as 8'hff
+			if i < len(src) && src[i] == '\'' {
+				i++
+				if i < len(src) && (src[i] == 's' || src[i] == 'S') {
+					i++
+				}
+				if i < len(src) && isBase(src[i]) {
+					i++
+				}
+				for i < len(src) && isBasedDigit(src[i]) {
+					i++
+				}
+			}
+			tokens = append(tokens, token{src[start:i], line})
+
+		case strings.HasPrefix(src[i:], "::"), strings.HasPrefix(src[i:], "**"):
+			i += 2
+			tokens = append(tokens, token{src[start:i], line})
+
+		default:
+			i++
+			tokens = append(tokens, token{src[start:i], line})
+		}
+	}
+
+	return tokens
+}
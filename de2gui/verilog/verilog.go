@@ -0,0 +1,509 @@
+// Package verilog reads the ports of a Verilog or SystemVerilog module, and
+// matches them to the signals of a development board using the names found
+// in the board's pin list: SW, KEY, LEDR, LEDG, HEX0...HEX7 and CLOCK_50.
+// This is the basis for generating the glue between a Verilated design and
+// de2gui.
+//
+// The parser only understands module headers and port declarations, in
+// either the ANSI style
+//
+//	module top #(parameter N = 18) (
+//		input  wire         CLOCK_50,
+//		input  wire [N-1:0] SW,
+//		output reg  [6:0]   HEX0, HEX1
+//	);
+//
+// or the older style, in which the ports are declared in the module body.
+// The bounds of ranges may be constant expressions using the module's
+// parameters, and $clog2(). Uses of macros are not expanded, so a port whose
+// range depends on one has an unknown width, as does a port with a
+// user-defined type.
+package verilog
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"strings"
+)
+
+// Direction is the direction of a port.
+type Direction int
+
+const (
+	Input Direction = iota
+	Output
+	Inout
+)
+
+// String returns the keyword which declares a port with the direction.
+func (d Direction) String() string {
+	switch d {
+	case Input:
+		return "input"
+	case Output:
+		return "output"
+	default:
+		return "inout"
+	}
+}
+
+// directions maps the keywords which declare ports to their direction
+var directions = map[string]Direction{"input": Input, "output": Output, "inout": Inout}
+
+// Port is a single port of a module.
+type Port struct {
+	Name string
+	Dir  Direction
+
+	// MSB and LSB are the bounds of the port's range, and are both 0 for a
+	// scalar port. For a port with several packed dimensions, they are the
+	// bounds of the first.
+	MSB int
+	LSB int
+
+	// Width is the number of bits in the port, or 0 if it is not known,
+	// for example because its range uses a macro.
+	Width int
+
+	// Line is the line the port was declared on.
+	Line int
+}
+
+// Module is the interface of a parsed module.
+type Module struct {
+	Name string
+
+	// Line is the line the module was declared on.
+	Line int
+
+	// Ports contains the ports of the module, in order.
+	Ports []Port
+
+	// Params contains the values of the module's parameters which could be
+	// evaluated, with their default values.
+	Params map[string]int
+}
+
+// Port returns the port with the given name, or nil if there is none.
+func (m *Module) Port(name string) *Port {
+	for i := range m.Ports {
+		if m.Ports[i].Name == name {
+			return &m.Ports[i]
+		}
+	}
+	return nil
+}
+
+// typeKeywords contains the data and net types which may appear in a port
+// declaration, and their width, or 0 if their width is given by a range
+var typeKeywords = map[string]int{
+	"wire": 0, "reg": 0, "logic": 0, "bit": 0, "var": 0, "tri": 0,
+	"wand": 0, "wor": 0, "tri0": 0, "tri1": 0, "uwire": 0, "supply0": 0,
+	"supply1": 0, "signed": 0, "unsigned": 0,
+	"byte": 8, "shortint": 16, "int": 32, "integer": 32, "longint": 64,
+}
+
+// parser holds the state of parsing a single source file
+type parser struct {
+	name   string
+	tokens []token
+	pos    int
+}
+
+// errorf returns an error at the line of the current token
+func (p *parser) errorf(format string, args ...interface{}) error {
+	line := 0
+	if p.pos < len(p.tokens) {
+		line = p.tokens[p.pos].line
+	} else if len(p.tokens) > 0 {
+		line = p.tokens[len(p.tokens)-1].line
+	}
+	return fmt.Errorf("%s:%d: %s", p.name, line, fmt.Sprintf(format, args...))
+}
+
+func (p *parser) peek() string {
+	if p.pos >= len(p.tokens) {
+		return ""
+	}
+	return p.tokens[p.pos].text
+}
+
+func (p *parser) expect(text string) error {
+	if p.peek() != text {
+		return p.errorf("expected '%s', got '%s'", text, p.peek())
+	}
+	p.pos++
+	return nil
+}
+
+// group returns the tokens up to the matching close of the bracket at the
+// current token, and moves past it
+func (p *parser) group() ([]token, error) {
+	start := p.pos + 1
+	depth := 0
+	for ; p.pos < len(p.tokens); p.pos++ {
+		switch p.tokens[p.pos].text {
+		case "(", "[", "{":
+			depth++
+		case ")", "]", "}":
+			depth--
+			if depth == 0 {
+				p.pos++
+				return p.tokens[start : p.pos-1], nil
+			}
+		}
+	}
+	return nil, p.errorf("unbalanced brackets")
+}
+
+// statement returns the tokens up to the next ';', and moves past it
+func (p *parser) statement() []token {
+	start := p.pos
+	for p.pos < len(p.tokens) && p.tokens[p.pos].text != ";" {
+		p.pos++
+	}
+	end := p.pos
+	if p.pos < len(p.tokens) {
+		p.pos++
+	}
+	return p.tokens[start:end]
+}
+
+// split splits tokens at the commas which are not within brackets
+func split(tokens []token) [][]token {
+	items := [][]token{}
+	depth := 0
+	start := 0
+	for i, t := range tokens {
+		switch t.text {
+		case "(", "[", "{":
+			depth++
+		case ")", "]", "}":
+			depth--
+		case ",":
+			if depth == 0 {
+				items = append(items, tokens[start:i])
+				start = i + 1
+			}
+		}
+	}
+	if start < len(tokens) {
+		items = append(items, tokens[start:])
+	}
+	return items
+}
+
+// parseParams evaluates parameter declarations, such as N = 8, M = N * 2,
+// adding them to params. Parameters whose values cannot be evaluated, such
+// as type parameters, are ignored.
+func parseParams(tokens []token, params map[string]int) {
+	for _, item := range split(tokens) {
+		eq := -1
+		for i, t := range item {
+			if t.text == "=" {
+				eq = i
+				break
+			}
+		}
+		if eq < 1 {
+			continue
+		}
+
+		if v, err := evaluate(item[eq+1:], params); err == nil {
+			params[item[eq-1].text] = v
+		}
+	}
+}
+
+// portDecl is the direction and type of a port declaration, which carries
+// over to the following ports in a list such as "input [3:0] a, b"
+type portDecl struct {
+	dir      Direction
+	msb, lsb int
+	width    int
+}
+
+// parseRange parses the bounds of a range, returning its width
+func parseRange(tokens []token, params map[string]int) (msb, lsb, width int, ok bool) {
+	colon := -1
+	for i, t := range tokens {
+		if t.text == ":" {
+			colon = i
+		}
+	}
+
+	// a single size, as in logic [8]
+	if colon < 0 {
+		n, err := evaluate(tokens, params)
+		if err != nil || n <= 0 {
+			return 0, 0, 0, false
+		}
+		return n - 1, 0, n, true
+	}
+
+	msb, err := evaluate(tokens[:colon], params)
+	if err != nil {
+		return 0, 0, 0, false
+	}
+	lsb, err = evaluate(tokens[colon+1:], params)
+	if err != nil {
+		return 0, 0, 0, false
+	}
+
+	width = msb - lsb + 1
+	if lsb > msb {
+		width = lsb - msb + 1
+	}
+	return msb, lsb, width, true
+}
+
+// parsePortItem parses a single item of a port declaration. If the item
+// does not start with a direction, the direction and type of prev are used.
+func (p *parser) parsePortItem(item []token, prev *portDecl, params map[string]int) (Port, error) {
+	if len(item) == 0 {
+		return Port{}, p.errorf("empty port declaration")
+	}
+
+	i := 0
+	if dir, ok := directions[item[0].text]; ok {
+		*prev = portDecl{dir: dir, width: 1}
+		i++
+
+		// the type, which is followed by the port name
+		for ; i < len(item)-1; i++ {
+			text := item[i].text
+			if w, ok := typeKeywords[text]; ok {
+				if w != 0 {
+					prev.width = w
+					prev.msb = w - 1
+				}
+				continue
+			}
+
+			if text == "[" {
+				sub := &parser{name: p.name, tokens: item, pos: i}
+				bounds, err := sub.group()
+				if err != nil {
+					return Port{}, err
+				}
+
+				msb, lsb, w, ok := parseRange(bounds, params)
+				switch {
+				case !ok || prev.width == 0:
+					prev.width = 0
+				case prev.width == 1 && prev.msb == 0:
+					prev.msb, prev.lsb, prev.width = msb, lsb, w
+				default:
+					// further packed dimensions
+					prev.width *= w
+				}
+
+				i = sub.pos - 1
+				continue
+			}
+
+			// the package of a type, as in pkg::state_t
+			if isIdentStart(text[0]) && i+1 < len(item) && item[i+1].text == "::" {
+				i++
+				continue
+			}
+
+			// a user-defined type, or interface port
+			if isIdentStart(text[0]) && i+1 < len(item) && isIdentStart(item[i+1].text[0]) {
+				prev.width = 0
+				continue
+			}
+
+			break
+		}
+	}
+
+	if i >= len(item) || !isIdentStart(item[i].text[0]) {
+		return Port{}, p.errorf("expected a port name")
+	}
+
+	return Port{
+		Name:  item[i].text,
+		Dir:   prev.dir,
+		MSB:   prev.msb,
+		LSB:   prev.lsb,
+		Width: prev.width,
+		Line:  item[i].line,
+	}, nil
+}
+
+// parseModule parses a module, starting after the module keyword
+func (p *parser) parseModule() (*Module, error) {
+	if p.peek() == "" || !isIdentStart(p.peek()[0]) {
+		return nil, p.errorf("expected a module name")
+	}
+
+	m := &Module{Name: p.peek(), Line: p.tokens[p.pos].line, Params: map[string]int{}}
+	p.pos++
+
+	// package imports
+	for p.peek() == "import" {
+		p.statement()
+	}
+
+	if p.peek() == "#" {
+		p.pos++
+		if p.peek() != "(" {
+			return nil, p.errorf("expected '('")
+		}
+		params, err := p.group()
+		if err != nil {
+			return nil, err
+		}
+		parseParams(params, m.Params)
+	}
+
+	// names of ports declared in the body, as in module top(a, b);
+	names := []string{}
+
+	if p.peek() == "(" {
+		list, err := p.group()
+		if err != nil {
+			return nil, err
+		}
+
+		items := split(list)
+		if len(items) > 0 && len(items[0]) > 0 {
+			if _, ansi := directions[items[0][0].text]; ansi {
+				decl := &portDecl{}
+				for _, item := range items {
+					port, err := p.parsePortItem(item, decl, m.Params)
+					if err != nil {
+						return nil, err
+					}
+					m.Ports = append(m.Ports, port)
+				}
+			} else {
+				for _, item := range items {
+					if len(item) != 1 {
+						return nil, p.errorf("unsupported port expression in module %s", m.Name)
+					}
+					names = append(names, item[0].text)
+				}
+			}
+		}
+	}
+
+	if err := p.expect(";"); err != nil {
+		return nil, err
+	}
+
+	// the body, which may declare parameters and ports
+	declared := map[string]Port{}
+	for {
+		switch p.peek() {
+		case "":
+			return nil, p.errorf("missing endmodule for module %s", m.Name)
+
+		case "endmodule":
+			p.pos++
+
+			for _, name := range names {
+				port, ok := declared[name]
+				if !ok {
+					return nil, p.errorf("port %s of module %s is not declared", name, m.Name)
+				}
+				m.Ports = append(m.Ports, port)
+			}
+			return m, nil
+
+		case "parameter", "localparam":
+			p.pos++
+			parseParams(p.statement(), m.Params)
+
+		case "input", "output", "inout":
+			decl := &portDecl{}
+			for _, item := range split(p.statement()) {
+				port, err := p.parsePortItem(item, decl, m.Params)
+				if err != nil {
+					return nil, err
+				}
+				declared[port.Name] = port
+			}
+
+		// functions and tasks have inputs and outputs of their own
+		case "function", "task":
+			end := "end" + p.peek()
+			for p.peek() != end && p.peek() != "" {
+				p.pos++
+			}
+			p.pos++
+
+		default:
+			p.pos++
+		}
+	}
+}
+
+// Parse reads the modules declared in r. The name is used in error
+// messages.
+func Parse(name string, r io.Reader) ([]*Module, error) {
+	src, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &parser{name: name, tokens: tokenize(string(src))}
+
+	modules := []*Module{}
+	for p.pos < len(p.tokens) {
+		switch p.peek() {
+		case "module", "macromodule":
+			p.pos++
+			m, err := p.parseModule()
+			if err != nil {
+				return nil, err
+			}
+			modules = append(modules, m)
+
+		default:
+			p.pos++
+		}
+	}
+
+	return modules, nil
+}
+
+// ParseFile reads the modules declared in the file at path, see Parse().
+func ParseFile(path string) ([]*Module, error) {
+	fp, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer fp.Close()
+
+	return Parse(path, fp)
+}
+
+// Top returns the module with the given name. If name is empty, and there
+// is only one module, it is returned.
+func Top(modules []*Module, name string) (*Module, error) {
+	if name == "" {
+		if len(modules) == 1 {
+			return modules[0], nil
+		}
+
+		names := make([]string, len(modules))
+		for i, m := range modules {
+			names[i] = m.Name
+		}
+		if len(names) == 0 {
+			return nil, fmt.Errorf("no modules found")
+		}
+		return nil, fmt.Errorf("several modules found (%s), the top module must be given", strings.Join(names, ", "))
+	}
+
+	for _, m := range modules {
+		if m.Name == name {
+			return m, nil
+		}
+	}
+	return nil, fmt.Errorf("module %s not found", name)
+}
@@ -0,0 +1,265 @@
+package verilog
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// parse parses Verilog source, failing the test on error
+func parse(t *testing.T, src string) []*Module {
+	t.Helper()
+
+	modules, err := Parse("test.sv", strings.NewReader(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return modules
+}
+
+func TestParseANSI(t *testing.T) {
+	modules := parse(t, "`timescale 1ns/1ps\n"+
+		"`define WIDTH 4\n"+
+		`// the top module
+module top import pkg::*; #(
+	parameter N = 18,
+	parameter int M = N / 2,
+	localparam D = $clog2(N + 1)
+) (
+	input  wire         CLOCK_50,   /* the clock */
+	input  wire [N-1:0] SW,
+	input  logic [0:3]  KEY,
+	output reg  [6:0]   HEX0, HEX1,
+	output logic [1:0][3:0] LEDR,
+	output int          count,
+	output [`+"`WIDTH"+`-1:0] LEDG,
+	output pkg::state_t state,
+	(* keep *) inout    data
+);
+	function automatic int f(input int x);
+		return x;
+	endfunction
+endmodule
+`)
+
+	if len(modules) != 1 {
+		t.Fatalf("parsed %d modules, expected 1", len(modules))
+	}
+	m := modules[0]
+
+	if m.Name != "top" || m.Line != 4 {
+		t.Errorf("parsed module %s on line %d, expected top on line 4", m.Name, m.Line)
+	}
+
+	params := map[string]int{"N": 18, "M": 9, "D": 5}
+	if !reflect.DeepEqual(m.Params, params) {
+		t.Errorf("the parameters are %v, expected %v", m.Params, params)
+	}
+
+	want := []Port{
+		{Name: "CLOCK_50", Dir: Input, Width: 1, Line: 9},
+		{Name: "SW", Dir: Input, MSB: 17, Width: 18, Line: 10},
+		{Name: "KEY", Dir: Input, MSB: 0, LSB: 3, Width: 4, Line: 11},
+		{Name: "HEX0", Dir: Output, MSB: 6, Width: 7, Line: 12},
+		{Name: "HEX1", Dir: Output, MSB: 6, Width: 7, Line: 12},
+		{Name: "LEDR", Dir: Output, MSB: 1, Width: 8, Line: 13},
+		{Name: "count", Dir: Output, MSB: 31, Width: 32, Line: 14},
+		{Name: "LEDG", Dir: Output, Width: 0, Line: 15},
+		{Name: "state", Dir: Output, Width: 0, Line: 16},
+		{Name: "data", Dir: Inout, Width: 1, Line: 17},
+	}
+	if !reflect.DeepEqual(m.Ports, want) {
+		t.Errorf("parsed the ports\n%+v\nexpected\n%+v", m.Ports, want)
+	}
+
+	if p := m.Port("HEX1"); p == nil || p.Name != "HEX1" {
+		t.Errorf("Port(HEX1) = %v", p)
+	}
+	if p := m.Port("HEX2"); p != nil {
+		t.Errorf("Port(HEX2) = %v, expected nil", p)
+	}
+}
+
+func TestParseNonANSI(t *testing.T) {
+	modules := parse(t, `module counter(CLOCK_50, KEY, LEDR);
+	parameter W = 4;
+	input CLOCK_50;
+	input [3:0] KEY;
+	output reg [W*2-1:0] LEDR;
+	reg [W-1:0] unused;
+endmodule
+
+module empty;
+endmodule
+`)
+
+	if len(modules) != 2 || modules[1].Name != "empty" || len(modules[1].Ports) != 0 {
+		t.Fatalf("parsed %+v, expected counter and empty", modules)
+	}
+
+	want := []Port{
+		{Name: "CLOCK_50", Dir: Input, Width: 1, Line: 3},
+		{Name: "KEY", Dir: Input, MSB: 3, Width: 4, Line: 4},
+		{Name: "LEDR", Dir: Output, MSB: 7, Width: 8, Line: 5},
+	}
+	if !reflect.DeepEqual(modules[0].Ports, want) {
+		t.Errorf("parsed the ports\n%+v\nexpected\n%+v", modules[0].Ports, want)
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		src string
+		err string
+	}{
+		{"module;", "test.sv:1: expected a module name"},
+		{"module top(input a);", "test.sv:1: missing endmodule for module top"},
+		{"module top(input a)\nendmodule", "test.sv:2: expected ';', got 'endmodule'"},
+		{"module top(input a, [1:0] b", "test.sv:1: unbalanced brackets"},
+		{"module top(a, b);\ninput a;\nendmodule", "test.sv:3: port b of module top is not declared"},
+		{"module top(a, b[1:0]);\nendmodule", "test.sv:1: unsupported port expression in module top"},
+		{"module top(input [1:0]);\nendmodule", "test.sv:1: expected a port name"},
+	}
+
+	for _, test := range tests {
+		_, err := Parse("test.sv", strings.NewReader(test.src))
+		if err == nil || err.Error() != test.err {
+			t.Errorf("%q: returned error %v, expected %q", test.src, err, test.err)
+		}
+	}
+}
+
+func TestEvaluate(t *testing.T) {
+	params := map[string]int{"N": 18, "ZERO": 0}
+
+	tests := []struct {
+		expr string
+		want int
+		err  bool
+	}{
+		{"N-1", 17, false},
+		{"2 + 3 * 4", 14, false},
+		{"(2 + 3) * 4", 20, false},
+		{"2 ** 3 - 1", 7, false},
+		{"N / 4 % 3", 1, false},
+		{"-N + +2", -16, false},
+		{"$clog2(N)", 5, false},
+		{"$clog2(16)", 4, false},
+		{"8'hff", 255, false},
+		{"'b1010_1010", 170, false},
+		{"4'sd7", 7, false},
+		{"N > 8 ? 1 : 0", 1, false},
+		{"N <= 8 ? 1 : 0", 0, false},
+		{"N == 18 ? (N != 0 ? 2 : 3) : 4", 2, false},
+		{"N / ZERO", 0, true},
+		{"M + 1", 0, true},
+		{"N +", 0, true},
+		{"(N", 0, true},
+		{"N N", 0, true},
+		{"8'hzz", 0, true},
+		{"N = 1", 0, true},
+	}
+
+	for _, test := range tests {
+		v, err := evaluate(tokenize(test.expr), params)
+		switch {
+		case test.err && err == nil:
+			t.Errorf("%q = %d, expected an error", test.expr, v)
+		case !test.err && err != nil:
+			t.Errorf("%q returned error %v", test.expr, err)
+		case !test.err && v != test.want:
+			t.Errorf("%q = %d, expected %d", test.expr, v, test.want)
+		}
+	}
+}
+
+func TestTop(t *testing.T) {
+	one := []*Module{{Name: "a"}}
+	two := []*Module{{Name: "a"}, {Name: "b"}}
+
+	if m, err := Top(one, ""); err != nil || m.Name != "a" {
+		t.Errorf("Top(a, \"\") = %v, %v", m, err)
+	}
+	if m, err := Top(two, "b"); err != nil || m.Name != "b" {
+		t.Errorf("Top(a b, b) = %v, %v", m, err)
+	}
+
+	errors := []struct {
+		modules []*Module
+		name    string
+		err     string
+	}{
+		{nil, "", "no modules found"},
+		{two, "", "several modules found (a, b), the top module must be given"},
+		{two, "c", "module c not found"},
+	}
+	for _, test := range errors {
+		if _, err := Top(test.modules, test.name); err == nil || err.Error() != test.err {
+			t.Errorf("Top(%q) returned error %v, expected %q", test.name, err, test.err)
+		}
+	}
+}
+
+func TestBind(t *testing.T) {
+	m := parse(t, `module top(
+	input CLOCK_50,
+	input [17:0] sw,
+	input [3:0] KEY,
+	output [15:0] LEDR,
+	input [8:0] LEDG,
+	output [6:0] HEX0,
+	output [`+"`W"+`:0] HEX1,
+	output [7:0] debug
+);
+endmodule
+`)[0]
+
+	b := Bind(m, DE2115)
+
+	bound := []string{}
+	for _, v := range b.Bound {
+		bound = append(bound, v.Port.Name+"="+v.Signal.Name)
+	}
+	if want := []string{"CLOCK_50=CLOCK_50", "sw=SW", "KEY=KEY", "LEDR=LEDR", "HEX0=HEX0", "HEX1=HEX1"}; !reflect.DeepEqual(bound, want) {
+		t.Errorf("bound %v, expected %v", bound, want)
+	}
+
+	if len(b.Unrecognized) != 1 || b.Unrecognized[0].Name != "debug" {
+		t.Errorf("the unrecognized ports are %+v, expected debug", b.Unrecognized)
+	}
+
+	problems := []string{}
+	for _, p := range b.Problems {
+		problems = append(problems, p.String())
+	}
+	want := []string{
+		"line 5: LEDR: is 16 bits wide, but LEDR has 18",
+		"line 6: LEDG: should be an output, not an input",
+		"line 8: HEX1: width could not be determined, expected 7 bits",
+	}
+	if !reflect.DeepEqual(problems, want) {
+		t.Errorf("found the problems\n%s\nexpected\n%s", strings.Join(problems, "\n"), strings.Join(want, "\n"))
+	}
+
+	if p := b.Signal("SW"); p == nil || p.Name != "sw" {
+		t.Errorf("Signal(SW) = %v, expected sw", p)
+	}
+	if p := b.Signal("LEDG"); p != nil {
+		t.Errorf("Signal(LEDG) = %v, expected nil", p)
+	}
+}
+
+func TestBoardSignals(t *testing.T) {
+	got := BoardSignals(2, 10, 0, 10, 4)
+	want := []Signal{
+		{"CLOCK_50", Input, 1},
+		{"SW", Input, 10},
+		{"KEY", Input, 4},
+		{"LEDR", Output, 10},
+		{"HEX0", Output, 7},
+		{"HEX1", Output, 7},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("BoardSignals() = %+v, expected %+v", got, want)
+	}
+}
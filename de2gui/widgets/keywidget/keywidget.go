@@ -0,0 +1,223 @@
+// Package keywidget implements a GUI widget that mimics the appearance of the
+// DE2-115 KEY push buttons.
+package keywidget
+
+import (
+	"image/color"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/canvas"
+	"fyne.io/fyne/v2/driver/desktop"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+)
+
+var keyHousingSize float32 = 40.0
+var keyCapRadius float32 = 12.0
+var keyPressDepth float32 = 3.0 // how far the cap moves when pressed
+var keyTextSize float32 = 11.0
+
+var keyHousingColor color.RGBA = color.RGBA{40, 40, 40, 255}
+var keyHousingStroke color.RGBA = color.RGBA{90, 90, 90, 255}
+var keyCapColor color.RGBA = color.RGBA{170, 170, 170, 255}
+var keyCapPressedColor color.RGBA = color.RGBA{110, 110, 110, 255}
+var keyCapShadowColor color.RGBA = color.RGBA{15, 15, 15, 255}
+
+type keyRenderer struct {
+	key     *KeyWidget
+	housing *canvas.Rectangle
+	shadow  *canvas.Circle
+	cap     *canvas.Circle
+	name    *canvas.Text
+	level   *canvas.Text
+	objects []fyne.CanvasObject
+}
+
+func (k *keyRenderer) MinSize() fyne.Size {
+	return fyne.NewSize(
+		keyHousingSize+theme.Padding()*2,
+		keyHousingSize+2*keyTextSize+theme.Padding()*4,
+	)
+}
+
+func (k *keyRenderer) Layout(size fyne.Size) {
+	left := (size.Width - keyHousingSize) / 2
+	top := theme.Padding()
+
+	k.housing.Move(fyne.NewPos(left, top))
+	k.housing.Resize(fyne.NewSize(keyHousingSize, keyHousingSize))
+
+	center := fyne.NewPos(left+keyHousingSize/2, top+keyHousingSize/2)
+	k.shadow.Move(fyne.NewPos(center.X-keyCapRadius, center.Y-keyCapRadius+keyPressDepth))
+	k.shadow.Resize(fyne.NewSize(keyCapRadius*2, keyCapRadius*2))
+
+	capPos := fyne.NewPos(center.X-keyCapRadius, center.Y-keyCapRadius)
+	capRadius := keyCapRadius
+	if k.key.pressed {
+		// the cap sinks into the housing and appears slightly smaller
+		capRadius -= 1
+		capPos = fyne.NewPos(center.X-capRadius, center.Y-capRadius+keyPressDepth)
+	}
+	k.cap.Move(capPos)
+	k.cap.Resize(fyne.NewSize(capRadius*2, capRadius*2))
+
+	textTop := top + keyHousingSize + theme.Padding()
+	k.name.Move(fyne.NewPos(0, textTop))
+	k.name.Resize(fyne.NewSize(size.Width, keyTextSize))
+	k.level.Move(fyne.NewPos(0, textTop+keyTextSize+theme.Padding()))
+	k.level.Resize(fyne.NewSize(size.Width, keyTextSize))
+}
+
+func (k *keyRenderer) ApplyTheme() {
+}
+
+func (k *keyRenderer) BackgroundColor() color.Color {
+	return theme.BackgroundColor()
+}
+
+func (k *keyRenderer) Refresh() {
+	k.name.Text = k.key.label
+	k.name.Color = theme.ForegroundColor()
+
+	// the KEYs are active-low on the board, so the signal reads 0 while
+	// the button is pushed
+	if k.key.pressed {
+		k.cap.FillColor = keyCapPressedColor
+		k.level.Text = "0"
+	} else {
+		k.cap.FillColor = keyCapColor
+		k.level.Text = "1"
+	}
+	k.level.Color = theme.ForegroundColor()
+
+	k.Layout(k.key.Size())
+	for _, v := range k.objects {
+		canvas.Refresh(v)
+	}
+}
+
+func (k *keyRenderer) Destroy() {
+}
+
+func (k *keyRenderer) Objects() []fyne.CanvasObject {
+	return k.objects
+}
+
+// KeyWidget represents a single push button. The button is drawn depressed
+// while it is pressed, see SetPressed(), which is independent of whether
+// the user is currently clicking on it. This allows the widget to show the
+// state of a KEY which has been pressed for some number of ticks, or by
+// some other means than the mouse.
+type KeyWidget struct {
+	widget.BaseWidget
+	label   string
+	pressed bool
+
+	// true while the mouse button is held down over the widget
+	down bool
+
+	// OnTapped is run when the widget is clicked.
+	OnTapped func()
+
+	// OnMouseDown is run when the mouse button is pressed over the
+	// widget.
+	OnMouseDown func()
+
+	// OnMouseUp is run when the mouse button is released after
+	// OnMouseDown was run, or if the mouse leaves the widget while the
+	// button is held down.
+	OnMouseUp func()
+}
+
+// CreateRenderer implements fyne.Widget
+func (k *KeyWidget) CreateRenderer() fyne.WidgetRenderer {
+	r := &keyRenderer{
+		key:     k,
+		housing: canvas.NewRectangle(keyHousingColor),
+		shadow:  canvas.NewCircle(keyCapShadowColor),
+		cap:     canvas.NewCircle(keyCapColor),
+		name:    canvas.NewText(k.label, theme.ForegroundColor()),
+		level:   canvas.NewText("1", theme.ForegroundColor()),
+	}
+
+	r.housing.StrokeColor = keyHousingStroke
+	r.housing.StrokeWidth = 1
+	r.name.Alignment = fyne.TextAlignCenter
+	r.name.TextSize = keyTextSize
+	r.name.TextStyle = fyne.TextStyle{Bold: true}
+	r.level.Alignment = fyne.TextAlignCenter
+	r.level.TextSize = keyTextSize
+	r.level.TextStyle = fyne.TextStyle{Monospace: true}
+
+	r.objects = []fyne.CanvasObject{r.housing, r.shadow, r.cap, r.name, r.level}
+	r.Refresh()
+	return r
+}
+
+// Tapped implements fyne.Tappable
+func (k *KeyWidget) Tapped(*fyne.PointEvent) {
+	if k.OnTapped != nil {
+		k.OnTapped()
+	}
+}
+
+// MouseDown implements desktop.Mouseable
+func (k *KeyWidget) MouseDown(*desktop.MouseEvent) {
+	k.down = true
+	if k.OnMouseDown != nil {
+		k.OnMouseDown()
+	}
+}
+
+// MouseUp implements desktop.Mouseable
+func (k *KeyWidget) MouseUp(*desktop.MouseEvent) {
+	k.mouseReleased()
+}
+
+// MouseIn implements desktop.Hoverable
+func (k *KeyWidget) MouseIn(*desktop.MouseEvent) {
+}
+
+// MouseMoved implements desktop.Hoverable
+func (k *KeyWidget) MouseMoved(*desktop.MouseEvent) {
+}
+
+// MouseOut implements desktop.Hoverable. The widget will not otherwise see
+// the mouse button being released once the mouse has left it.
+func (k *KeyWidget) MouseOut() {
+	k.mouseReleased()
+}
+
+func (k *KeyWidget) mouseReleased() {
+	if !k.down {
+		return
+	}
+
+	k.down = false
+	if k.OnMouseUp != nil {
+		k.OnMouseUp()
+	}
+}
+
+// Pressed returns true if the widget is currently drawn as pressed.
+func (k *KeyWidget) Pressed() bool {
+	return k.pressed
+}
+
+// SetPressed changes whether the widget is drawn as pressed, and causes it
+// to refresh if this has changed.
+func (k *KeyWidget) SetPressed(pressed bool) {
+	if k.pressed == pressed {
+		return
+	}
+
+	k.pressed = pressed
+	k.Refresh()
+}
+
+// NewKeyWidget creates a new, unpressed, KEY widget with the given label.
+func NewKeyWidget(label string) *KeyWidget {
+	k := &KeyWidget{label: label}
+	k.ExtendBaseWidget(k)
+	return k
+}
@@ -0,0 +1,56 @@
+package keywidget
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/herclab/de2gui/de2gui/internal/widgettest"
+)
+
+func TestSetPressed(t *testing.T) {
+	widgettest.NewApp()
+
+	k := NewKeyWidget("KEY0")
+	r := k.CreateRenderer().(*keyRenderer)
+	up := r.cap.Position()
+	if k.Pressed() || r.level.Text != "1" || r.name.Text != "KEY0" {
+		t.Errorf("a new KEY is pressed: %v, shows the level %q and is labeled %q", k.Pressed(), r.level.Text, r.name.Text)
+	}
+
+	// the KEY is active-low, and its cap sinks while pressed
+	k.SetPressed(true)
+	r.Refresh()
+	if !k.Pressed() || r.level.Text != "0" || r.cap.Position().Y <= up.Y || r.cap.FillColor != keyCapPressedColor {
+		t.Errorf("a pressed KEY shows the level %q, with its cap at %v, from %v", r.level.Text, r.cap.Position(), up)
+	}
+
+	k.SetPressed(false)
+	r.Refresh()
+	if k.Pressed() || r.level.Text != "1" || r.cap.Position() != up {
+		t.Errorf("a released KEY shows the level %q, with its cap at %v, expected %v", r.level.Text, r.cap.Position(), up)
+	}
+}
+
+func TestMouse(t *testing.T) {
+	widgettest.NewApp()
+
+	events := []string{}
+	k := NewKeyWidget("KEY1")
+	k.OnTapped = func() { events = append(events, "tapped") }
+	k.OnMouseDown = func() { events = append(events, "down") }
+	k.OnMouseUp = func() { events = append(events, "up") }
+
+	// the mouse leaving the widget releases the button once, and the
+	// widget's pressed state is left to the board
+	k.MouseDown(nil)
+	k.MouseOut()
+	k.MouseUp(nil)
+	k.MouseDown(nil)
+	k.MouseUp(nil)
+	k.Tapped(nil)
+
+	want := []string{"down", "up", "down", "up", "tapped"}
+	if !reflect.DeepEqual(events, want) || k.Pressed() {
+		t.Errorf("got the events %q, and Pressed() = %v, expected %q and false", events, k.Pressed(), want)
+	}
+}
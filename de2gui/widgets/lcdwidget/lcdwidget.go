@@ -0,0 +1,143 @@
+// Package lcdwidget implements a GUI widget that mimics the appearance of the
+// DE2-115 16x2 character LCD.
+package lcdwidget
+
+import (
+	"image/color"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/canvas"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+)
+
+// Columns and Rows are the size of the display, in characters.
+const Columns int = 16
+const Rows int = 2
+
+var lcdTextSize float32 = 16.0
+var lcdBezel float32 = 6.0
+
+var lcdBackgroundColor color.RGBA = color.RGBA{120, 160, 40, 255}
+var lcdBezelColor color.RGBA = color.RGBA{30, 30, 30, 255}
+var lcdTextColor color.RGBA = color.RGBA{20, 30, 10, 255}
+
+type lcdRenderer struct {
+	lcd     *LcdWidget
+	bezel   *canvas.Rectangle
+	screen  *canvas.Rectangle
+	lines   []*canvas.Text
+	objects []fyne.CanvasObject
+}
+
+// charSize returns the size of a single character
+func charSize() fyne.Size {
+	return fyne.MeasureText("M", lcdTextSize, fyne.TextStyle{Monospace: true})
+}
+
+func (l *lcdRenderer) MinSize() fyne.Size {
+	c := charSize()
+	return fyne.NewSize(
+		c.Width*float32(Columns)+lcdBezel*4,
+		c.Height*float32(Rows)+lcdBezel*4,
+	)
+}
+
+func (l *lcdRenderer) Layout(size fyne.Size) {
+	min := l.MinSize()
+	c := charSize()
+
+	l.bezel.Move(fyne.NewPos(0, 0))
+	l.bezel.Resize(min)
+	l.screen.Move(fyne.NewPos(lcdBezel, lcdBezel))
+	l.screen.Resize(fyne.NewSize(min.Width-lcdBezel*2, min.Height-lcdBezel*2))
+
+	for i, v := range l.lines {
+		v.Move(fyne.NewPos(lcdBezel*2, lcdBezel*2+c.Height*float32(i)))
+		v.Resize(fyne.NewSize(c.Width*float32(Columns), c.Height))
+	}
+}
+
+func (l *lcdRenderer) ApplyTheme() {
+}
+
+func (l *lcdRenderer) BackgroundColor() color.Color {
+	return theme.BackgroundColor()
+}
+
+func (l *lcdRenderer) Refresh() {
+	for i, v := range l.lines {
+		v.Text = l.lcd.text[i]
+	}
+
+	l.Layout(l.lcd.Size())
+	for _, v := range l.objects {
+		canvas.Refresh(v)
+	}
+}
+
+func (l *lcdRenderer) Destroy() {
+}
+
+func (l *lcdRenderer) Objects() []fyne.CanvasObject {
+	return l.objects
+}
+
+// LcdWidget represents a character LCD with Rows lines of Columns
+// characters each.
+type LcdWidget struct {
+	widget.BaseWidget
+	text [Rows]string
+}
+
+// CreateRenderer implements fyne.Widget
+func (l *LcdWidget) CreateRenderer() fyne.WidgetRenderer {
+	r := &lcdRenderer{
+		lcd:    l,
+		bezel:  canvas.NewRectangle(lcdBezelColor),
+		screen: canvas.NewRectangle(lcdBackgroundColor),
+	}
+
+	r.objects = []fyne.CanvasObject{r.bezel, r.screen}
+	for range l.text {
+		t := canvas.NewText("", lcdTextColor)
+		t.TextSize = lcdTextSize
+		t.TextStyle = fyne.TextStyle{Monospace: true}
+		r.lines = append(r.lines, t)
+		r.objects = append(r.objects, t)
+	}
+
+	r.Refresh()
+	return r
+}
+
+// SetLine changes the text of the i-th line, the top line being 0. Text
+// past the end of the line is not shown. Lines which do not exist are
+// ignored.
+func (l *LcdWidget) SetLine(i int, text string) {
+	if i < 0 || i >= Rows {
+		return
+	}
+
+	if r := []rune(text); len(r) > Columns {
+		text = string(r[:Columns])
+	}
+
+	l.text[i] = text
+	l.Refresh()
+}
+
+// Line returns the text shown on the i-th line.
+func (l *LcdWidget) Line(i int) string {
+	if i < 0 || i >= Rows {
+		return ""
+	}
+	return l.text[i]
+}
+
+// NewLcdWidget creates a new, blank, LCD widget.
+func NewLcdWidget() *LcdWidget {
+	l := &LcdWidget{}
+	l.ExtendBaseWidget(l)
+	return l
+}
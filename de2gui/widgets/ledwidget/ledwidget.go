@@ -20,7 +20,7 @@ type ledRenderer struct {
 }
 
 func (l *ledRenderer) MinSize() fyne.Size {
-	return fyne.NewSize(float32(l.led.count)*ledBoxSize+float32(theme.Padding())*2, ledBoxSize+float32(theme.Padding())*2)
+	return fyne.NewSize(float32(l.led.count)*l.led.pitch+float32(theme.Padding())*2, ledBoxSize+float32(theme.Padding())*2)
 }
 
 func (l *ledRenderer) Layout(size fyne.Size) {
@@ -56,6 +56,7 @@ type LedWidget struct {
 	count    int
 	onColor  color.RGBA
 	offColor color.RGBA
+	pitch    float32
 }
 
 // Mask returns a uint32 with all of the bits corresponding to an LED set to 1.
@@ -100,7 +101,7 @@ func (l *LedWidget) CreateRenderer() fyne.WidgetRenderer {
 
 		// top-left corner of circle's bounding box
 		led.Move(fyne.Position{
-			theme.Padding() + float32(i)*ledBoxSize + ledRadius,
+			theme.Padding() + float32(i)*l.pitch + ledRadius,
 			theme.Padding() + ledRadius,
 		})
 
@@ -121,7 +122,15 @@ func NewLedWidget(count int, onColor, offColor color.RGBA) *LedWidget {
 		count:    count,
 		onColor:  onColor,
 		offColor: offColor,
+		pitch:    ledBoxSize,
 	}
 	l.ExtendBaseWidget(l)
 	return l
 }
+
+// SetPitch changes the distance between the LEDs, for example so that they
+// line up with a row of switches. It must be called before the widget is
+// shown.
+func (l *LedWidget) SetPitch(pitch float32) {
+	l.pitch = pitch
+}
@@ -0,0 +1,260 @@
+// Package switchwidget implements a GUI widget that mimics the appearance of
+// the DE2-115 slide switches.
+package switchwidget
+
+import (
+	"image/color"
+	"strconv"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/canvas"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+)
+
+var switchBoxWidth float32 = 22.0 // padding "box" around each switch
+var switchSlotWidth float32 = 12.0
+var switchSlotHeight float32 = 34.0
+var switchKnobHeight float32 = 14.0
+var switchTextSize float32 = 10.0
+
+var switchSlotColor color.RGBA = color.RGBA{30, 30, 30, 255}
+var switchSlotStroke color.RGBA = color.RGBA{90, 90, 90, 255}
+var switchKnobColor color.RGBA = color.RGBA{200, 200, 200, 255}
+
+type switchRenderer struct {
+	sw      *SwitchWidget
+	slots   []*canvas.Rectangle
+	knobs   []*canvas.Rectangle
+	labels  []*canvas.Text
+	objects []fyne.CanvasObject
+}
+
+func (r *switchRenderer) MinSize() fyne.Size {
+	return fyne.NewSize(
+		float32(r.sw.count)*r.sw.pitch+theme.Padding()*2,
+		switchSlotHeight+switchTextSize+theme.Padding()*3,
+	)
+}
+
+func (r *switchRenderer) Layout(size fyne.Size) {
+	for i := 0; i < r.sw.count; i++ {
+		left := r.sw.switchLeft(i)
+		top := theme.Padding()
+
+		r.slots[i].Move(fyne.NewPos(left+(r.sw.pitch-switchSlotWidth)/2, top))
+		r.slots[i].Resize(fyne.NewSize(switchSlotWidth, switchSlotHeight))
+
+		// the knob sits at the top of the slot when the switch is on,
+		// as it does on the board
+		knobTop := top + switchSlotHeight - switchKnobHeight - 2
+		if r.sw.Bit(i) {
+			knobTop = top + 2
+		}
+		r.knobs[i].Move(fyne.NewPos(left+(r.sw.pitch-switchSlotWidth)/2+2, knobTop))
+		r.knobs[i].Resize(fyne.NewSize(switchSlotWidth-4, switchKnobHeight))
+
+		r.labels[i].Move(fyne.NewPos(left, top+switchSlotHeight+theme.Padding()))
+		r.labels[i].Resize(fyne.NewSize(r.sw.pitch, switchTextSize))
+	}
+}
+
+func (r *switchRenderer) ApplyTheme() {
+}
+
+func (r *switchRenderer) BackgroundColor() color.Color {
+	return theme.BackgroundColor()
+}
+
+func (r *switchRenderer) Refresh() {
+	for _, v := range r.labels {
+		v.Color = theme.ForegroundColor()
+	}
+
+	r.Layout(r.sw.Size())
+	for _, v := range r.objects {
+		canvas.Refresh(v)
+	}
+}
+
+func (r *switchRenderer) Destroy() {
+}
+
+func (r *switchRenderer) Objects() []fyne.CanvasObject {
+	return r.objects
+}
+
+// SwitchWidget represents a horizontal bank of up to 32 slide switches. The
+// rightmost switch corresponds to the least significant bit of the state, and
+// each switch is labeled with its bit number.
+//
+// Switches can be toggled by clicking on them, or turned on and off with the
+// scroll wheel. Dragging across several switches sets all of them to the
+// opposite of the state of the switch where the drag started.
+type SwitchWidget struct {
+	widget.BaseWidget
+	state uint32
+	count int
+	pitch float32
+
+	// state of an in-progress drag
+	dragging  bool
+	dragValue bool
+	dragLastX float32
+
+	// OnChanged is run with the new state whenever the user changes any
+	// of the switches. It is not run by Update().
+	OnChanged func(uint32)
+}
+
+// switchLeft returns the x coordinate of the left edge of the box around
+// the i-th switch
+func (s *SwitchWidget) switchLeft(i int) float32 {
+	return theme.Padding() + float32(s.count-1-i)*s.pitch
+}
+
+// switchAt returns the index of the switch at the given x coordinate, or -1
+// if there is none
+func (s *SwitchWidget) switchAt(x float32) int {
+	col := int((x - theme.Padding()) / s.pitch)
+	if x < theme.Padding() || col >= s.count {
+		return -1
+	}
+	return s.count - 1 - col
+}
+
+// Mask returns a uint32 with all of the bits corresponding to a switch set
+// to 1.
+func (s *SwitchWidget) Mask() uint32 {
+	mask := uint32(0)
+	for i := 0; i < s.count; i++ {
+		mask = (mask << 1) | 1
+	}
+	return mask
+}
+
+// State returns the current state of the switches in this widget.
+func (s *SwitchWidget) State() uint32 {
+	return s.state
+}
+
+// Bit returns true if the i-th switch is on.
+func (s *SwitchWidget) Bit(i int) bool {
+	return s.state&(1<<uint(i)) != 0
+}
+
+// Update changes the state of the switches in this widget, and triggers the
+// graphical widget to refresh. OnChanged is not run.
+func (s *SwitchWidget) Update(newstate uint32) {
+	s.state = newstate & s.Mask()
+	s.Refresh()
+}
+
+// setBit changes the i-th switch as if the user had done so, running
+// OnChanged if the state changed
+func (s *SwitchWidget) setBit(i int, on bool) {
+	if i < 0 || i >= s.count || s.Bit(i) == on {
+		return
+	}
+
+	s.state ^= 1 << uint(i)
+	s.Refresh()
+
+	if s.OnChanged != nil {
+		s.OnChanged(s.state)
+	}
+}
+
+// Tapped implements fyne.Tappable
+func (s *SwitchWidget) Tapped(ev *fyne.PointEvent) {
+	i := s.switchAt(ev.Position.X)
+	if i >= 0 {
+		s.setBit(i, !s.Bit(i))
+	}
+}
+
+// Dragged implements fyne.Draggable
+func (s *SwitchWidget) Dragged(ev *fyne.DragEvent) {
+	x := ev.Position.X
+	if !s.dragging {
+		start := s.switchAt(x - ev.Dragged.DX)
+		if start < 0 {
+			return
+		}
+		s.dragging = true
+		s.dragValue = !s.Bit(start)
+		s.dragLastX = x - ev.Dragged.DX
+	}
+
+	// set every switch between the previous and current position, so
+	// that fast movements do not skip any
+	from, to := s.dragLastX, x
+	if from > to {
+		from, to = to, from
+	}
+	for px := from; px < to+s.pitch; px += s.pitch {
+		if px > to {
+			px = to
+		}
+		s.setBit(s.switchAt(px), s.dragValue)
+	}
+
+	s.dragLastX = x
+}
+
+// DragEnd implements fyne.Draggable
+func (s *SwitchWidget) DragEnd() {
+	s.dragging = false
+}
+
+// Scrolled implements fyne.Scrollable. Scrolling up turns the switch under
+// the pointer on, and scrolling down turns it off.
+func (s *SwitchWidget) Scrolled(ev *fyne.ScrollEvent) {
+	i := s.switchAt(ev.Position.X)
+	switch {
+	case ev.Scrolled.DY > 0:
+		s.setBit(i, true)
+	case ev.Scrolled.DY < 0:
+		s.setBit(i, false)
+	}
+}
+
+// CreateRenderer implements fyne.Widget
+func (s *SwitchWidget) CreateRenderer() fyne.WidgetRenderer {
+	r := &switchRenderer{sw: s}
+
+	for i := 0; i < s.count; i++ {
+		slot := canvas.NewRectangle(switchSlotColor)
+		slot.StrokeColor = switchSlotStroke
+		slot.StrokeWidth = 1
+
+		knob := canvas.NewRectangle(switchKnobColor)
+
+		label := canvas.NewText(strconv.Itoa(i), theme.ForegroundColor())
+		label.Alignment = fyne.TextAlignCenter
+		label.TextSize = switchTextSize
+
+		r.slots = append(r.slots, slot)
+		r.knobs = append(r.knobs, knob)
+		r.labels = append(r.labels, label)
+		r.objects = append(r.objects, slot, knob, label)
+	}
+
+	r.Layout(s.Size())
+	return r
+}
+
+// NewSwitchWidget creates a new switch widget with the given number of
+// switches, all of which are off.
+func NewSwitchWidget(count int) *SwitchWidget {
+	s := &SwitchWidget{count: count, pitch: switchBoxWidth}
+	s.ExtendBaseWidget(s)
+	return s
+}
+
+// SetPitch changes the distance between the switches, for example so that
+// they line up with a row of LEDs. It must be called before the widget is
+// shown.
+func (s *SwitchWidget) SetPitch(pitch float32) {
+	s.pitch = pitch
+}
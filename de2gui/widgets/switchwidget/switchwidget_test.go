@@ -0,0 +1,123 @@
+package switchwidget
+
+import (
+	"testing"
+
+	"fyne.io/fyne/v2"
+
+	"github.com/herclab/de2gui/de2gui/internal/widgettest"
+)
+
+// at returns the center of the i-th switch
+func at(s *SwitchWidget, i int) fyne.Position {
+	return fyne.NewPos(s.switchLeft(i)+s.pitch/2, switchSlotHeight/2)
+}
+
+// newSwitches returns a widget with the given number of switches, and a
+// pointer to the states passed to OnChanged
+func newSwitches(count int) (*SwitchWidget, *[]uint32) {
+	widgettest.NewApp()
+
+	changes := []uint32{}
+	s := NewSwitchWidget(count)
+	s.OnChanged = func(state uint32) { changes = append(changes, state) }
+	return s, &changes
+}
+
+func TestUpdate(t *testing.T) {
+	s, changes := newSwitches(4)
+	if s.Mask() != 0xf {
+		t.Errorf("Mask() = %#x, expected 0xf", s.Mask())
+	}
+
+	// switches which do not exist are ignored, and OnChanged is not run
+	s.Update(0x35)
+	if s.State() != 0x5 || !s.Bit(2) || s.Bit(1) || len(*changes) != 0 {
+		t.Errorf("State() = %#x and OnChanged got %#x, expected 0x5 and nothing", s.State(), *changes)
+	}
+
+	// the knob of a switch which is on is at the top of its slot
+	r := s.CreateRenderer().(*switchRenderer)
+	if r.knobs[2].Position().Y >= r.knobs[1].Position().Y {
+		t.Errorf("the knob of SW2 is at %v, and of SW1 at %v, expected SW2 higher", r.knobs[2].Position(), r.knobs[1].Position())
+	}
+	if r.labels[0].Text != "0" || r.labels[0].Position().X <= r.labels[3].Position().X {
+		t.Errorf("SW0 is labeled %q at %v, expected 0 to the right of SW3", r.labels[0].Text, r.labels[0].Position())
+	}
+}
+
+func TestTapped(t *testing.T) {
+	s, changes := newSwitches(4)
+
+	s.Tapped(&fyne.PointEvent{Position: at(s, 0)})
+	s.Tapped(&fyne.PointEvent{Position: at(s, 3)})
+	s.Tapped(&fyne.PointEvent{Position: at(s, 0)})
+
+	// outside the switches
+	s.Tapped(&fyne.PointEvent{Position: fyne.NewPos(0, 0)})
+	s.Tapped(&fyne.PointEvent{Position: fyne.NewPos(s.switchLeft(0)+s.pitch+1, 0)})
+
+	want := []uint32{0x1, 0x9, 0x8}
+	if s.State() != 0x8 || len(*changes) != len(want) {
+		t.Fatalf("State() = %#x and OnChanged got %#x, expected 0x8 and %#x", s.State(), *changes, want)
+	}
+	for i := range want {
+		if (*changes)[i] != want[i] {
+			t.Errorf("OnChanged got %#x, expected %#x", *changes, want)
+			break
+		}
+	}
+}
+
+func TestDragged(t *testing.T) {
+	s, changes := newSwitches(8)
+	s.Update(0x10)
+
+	// dragging from SW5 to SW1 in one movement turns them all on,
+	// since SW5 was off, without running OnChanged for SW4 which was
+	// already on
+	from, to := at(s, 5), at(s, 1)
+	s.Dragged(&fyne.DragEvent{PointEvent: fyne.PointEvent{Position: to}, Dragged: fyne.NewDelta(to.X-from.X, 0)})
+	s.DragEnd()
+	if s.State() != 0x3e || len(*changes) != 4 {
+		t.Errorf("State() = %#x and OnChanged ran %d times, expected 0x3e and 4", s.State(), len(*changes))
+	}
+
+	// dragging back from SW1, which is on, turns the switches off
+	// again
+	from, to = at(s, 1), at(s, 3)
+	s.Dragged(&fyne.DragEvent{PointEvent: fyne.PointEvent{Position: to}, Dragged: fyne.NewDelta(to.X-from.X, 0)})
+	s.DragEnd()
+	if s.State() != 0x30 {
+		t.Errorf("State() = %#x, expected 0x30", s.State())
+	}
+}
+
+func TestScrolled(t *testing.T) {
+	s, changes := newSwitches(4)
+
+	s.Scrolled(&fyne.ScrollEvent{PointEvent: fyne.PointEvent{Position: at(s, 2)}, Scrolled: fyne.NewDelta(0, 1)})
+	s.Scrolled(&fyne.ScrollEvent{PointEvent: fyne.PointEvent{Position: at(s, 2)}, Scrolled: fyne.NewDelta(0, 1)})
+	if s.State() != 0x4 || len(*changes) != 1 {
+		t.Errorf("State() = %#x and OnChanged ran %d times after scrolling up, expected 0x4 and once", s.State(), len(*changes))
+	}
+
+	s.Scrolled(&fyne.ScrollEvent{PointEvent: fyne.PointEvent{Position: at(s, 2)}, Scrolled: fyne.NewDelta(0, -1)})
+	if s.State() != 0 || len(*changes) != 2 {
+		t.Errorf("State() = %#x and OnChanged ran %d times after scrolling down, expected 0 and twice", s.State(), len(*changes))
+	}
+}
+
+func TestSetPitch(t *testing.T) {
+	s, _ := newSwitches(18)
+	s.SetPitch(24)
+
+	for _, i := range []int{0, 9, 17} {
+		if got := s.switchAt(at(s, i).X); got != i {
+			t.Errorf("switchAt() the center of SW%d = %d", i, got)
+		}
+	}
+	if got := s.switchAt(s.switchLeft(0) + 24 + 1); got != -1 {
+		t.Errorf("switchAt() to the right of SW0 = %d, expected -1", got)
+	}
+}
@@ -5,4 +5,5 @@ go 1.13
 require (
 	fyne.io/fyne/v2 v2.0.0-rc5
 	github.com/alecthomas/kong v0.2.11
+	golang.org/x/sys v0.0.0-20200720211630-cb9d2d5c5666
 )